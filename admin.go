@@ -0,0 +1,251 @@
+package routerx
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed admin/index.html
+var embeddedAdmin embed.FS
+
+var adminTemplate = template.Must(template.ParseFS(embeddedAdmin, "admin/index.html"))
+
+// AdminConfig configures an Admin.
+type AdminConfig struct {
+	// Title of the page. Defaults to "routerx dashboard".
+	Title string
+
+	// Middlewares guard the dashboard and its API, e.g. with an admin
+	// check. They run after the router's middlewares. At least one is
+	// required, since the dashboard reveals the internals of the service.
+	Middlewares []Middleware
+
+	// Metrics, when set, adds the live request counts, error rates, and
+	// latencies per route, as collected by Metrics.Middleware.
+	Metrics *Metrics
+
+	// RecentErrors is the number of failed requests the dashboard keeps.
+	// Defaults to 100.
+	RecentErrors int
+
+	// ErrorStatus is the lowest status recorded as an error. Defaults to
+	// 500.
+	ErrorStatus int
+}
+
+// Admin is an embedded dashboard for operators and developers showing the
+// route tree with the middleware chain of every route, the live metrics, and
+// the recent errors, see Router.Admin. The page is self-contained and loads
+// no assets from the internet.
+type Admin struct {
+	config AdminConfig
+
+	mutex  sync.Mutex
+	errors []AdminError
+	next   int
+}
+
+// AdminError is a failed request recorded by Admin.Middleware.
+type AdminError struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Route     string    `json:"route"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	LatencyMS float64   `json:"latency_ms"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// AdminRouteMetrics are the metrics of a route on the dashboard.
+type AdminRouteMetrics struct {
+	Method    string  `json:"method"`
+	Route     string  `json:"route"`
+	Requests  uint64  `json:"requests"`
+	Errors    uint64  `json:"errors"`
+	InFlight  int64   `json:"in_flight"`
+	AverageMS float64 `json:"average_ms"`
+}
+
+// NewAdmin creates an Admin from config. It panics without guarding
+// middlewares.
+func NewAdmin(config AdminConfig) *Admin {
+	if len(config.Middlewares) == 0 {
+		panic("routerx: NewAdmin needs middlewares restricting it to administrators")
+	}
+	if config.Title == "" {
+		config.Title = "routerx dashboard"
+	}
+	if config.RecentErrors <= 0 {
+		config.RecentErrors = 100
+	}
+	if config.ErrorStatus <= 0 {
+		config.ErrorStatus = http.StatusInternalServerError
+	}
+	return &Admin{config: config}
+}
+
+// Middleware returns a Middleware recording failed requests for the
+// dashboard. Use it on the router to cover all routes.
+func (admin *Admin) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			clock := GetClock(request)
+			start := clock.Now()
+			writer := &statusWriter{ResponseWriter: responseWriter}
+			defer func() {
+				if status := writer.status(); status >= admin.config.ErrorStatus {
+					admin.record(AdminError{
+						Time:      start,
+						Method:    request.Method,
+						Route:     metricsRoute(request.Pattern),
+						Path:      request.URL.Path,
+						Status:    status,
+						LatencyMS: float64(clock.Now().Sub(start).Microseconds()) / 1000,
+						RequestID: request.Header.Get("X-Request-ID"),
+					})
+				}
+			}()
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// record adds a failed request to the ring of recent errors.
+func (admin *Admin) record(failure AdminError) {
+	admin.mutex.Lock()
+	defer admin.mutex.Unlock()
+	if len(admin.errors) < admin.config.RecentErrors {
+		admin.errors = append(admin.errors, failure)
+		return
+	}
+	admin.errors[admin.next] = failure
+	admin.next = (admin.next + 1) % len(admin.errors)
+}
+
+// RecentErrors returns the recorded failed requests, newest first.
+func (admin *Admin) RecentErrors() []AdminError {
+	admin.mutex.Lock()
+	defer admin.mutex.Unlock()
+	recent := make([]AdminError, 0, len(admin.errors))
+	recent = append(recent, admin.errors[admin.next:]...)
+	recent = append(recent, admin.errors[:admin.next]...)
+	slices.Reverse(recent)
+	return recent
+}
+
+// Admin serves the dashboard of admin at prefix, with its JSON API below
+// prefix/api/. The routes are guarded by the configured middlewares in
+// addition to the router's and are left out of the OpenAPI document.
+//
+// Example:
+//
+//	metrics := routerx.NewMetrics(routerx.MetricsConfig{})
+//	admin := routerx.NewAdmin(routerx.AdminConfig{
+//	    Middlewares: []routerx.Middleware{routerx.BasicAuth(routerx.BasicAuthUsers(operators))},
+//	    Metrics:     metrics,
+//	})
+//	router := routerx.New(routerx.WithMiddleware(admin.Middleware(), metrics.Middleware()))
+//	router.Admin("/_routerx/admin", admin)
+func (router *Router) Admin(prefix string, admin *Admin) {
+	prefix = strings.TrimSuffix(cleanPath(prefix), "/")
+	var page bytes.Buffer
+	err := adminTemplate.Execute(&page, map[string]any{
+		"Title":   admin.config.Title,
+		"APIPath": prefix + "/api",
+	})
+	if err != nil {
+		panic("routerx: render admin page: " + err.Error())
+	}
+
+	middlewares := append(copyMiddlewares(router.middlewares), admin.config.Middlewares...)
+	pagePath := prefix
+	if pagePath == "" {
+		pagePath = "/{$}"
+	}
+	endpoints := []struct {
+		path    string
+		handler http.HandlerFunc
+	}{
+		{pagePath, func(responseWriter http.ResponseWriter, request *http.Request) {
+			responseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+			responseWriter.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+			_, _ = responseWriter.Write(page.Bytes())
+		}},
+		{prefix + "/api/routes", func(responseWriter http.ResponseWriter, request *http.Request) {
+			report := router.Diagnose()
+			writeJSON(responseWriter, http.StatusOK, map[string]any{
+				"routes":   report.Routes,
+				"features": report.Features,
+				"problems": report.Problems,
+				"runtime":  report.Runtime,
+			})
+		}},
+		{prefix + "/api/metrics", func(responseWriter http.ResponseWriter, request *http.Request) {
+			metrics := []AdminRouteMetrics{}
+			if admin.config.Metrics != nil {
+				metrics = admin.config.Metrics.routeMetrics()
+			}
+			writeJSON(responseWriter, http.StatusOK, metrics)
+		}},
+		{prefix + "/api/errors", func(responseWriter http.ResponseWriter, request *http.Request) {
+			writeJSON(responseWriter, http.StatusOK, admin.RecentErrors())
+		}},
+	}
+	for _, endpoint := range endpoints {
+		router.register("GET", endpoint.path, http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			responseWriter.Header().Set("Cache-Control", "no-store")
+			endpoint.handler(responseWriter, request)
+		}), middlewares, nil).undocumented = true
+	}
+}
+
+// routeMetrics summarizes the collected metrics per method and route.
+func (metrics *Metrics) routeMetrics() []AdminRouteMetrics {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	byRoute := make(map[metricsKey]*AdminRouteMetrics)
+	summary := func(key metricsKey) *AdminRouteMetrics {
+		key.device, key.status = "", ""
+		route := byRoute[key]
+		if route == nil {
+			route = &AdminRouteMetrics{Method: key.method, Route: key.route}
+			byRoute[key] = route
+		}
+		return route
+	}
+	for key, count := range metrics.requests {
+		route := summary(key)
+		route.Requests += count
+		if strings.HasPrefix(key.status, "5") {
+			route.Errors += count
+		}
+	}
+	for key, inFlight := range metrics.inFlight {
+		summary(key).InFlight += inFlight
+	}
+	sums := make(map[*AdminRouteMetrics][2]float64)
+	for key, durations := range metrics.durations {
+		route := summary(key)
+		sum := sums[route]
+		sums[route] = [2]float64{sum[0] + durations.sum, sum[1] + float64(durations.count)}
+	}
+	for route, sum := range sums {
+		if sum[1] > 0 {
+			route.AverageMS = sum[0] / sum[1] * 1000
+		}
+	}
+	routes := make([]AdminRouteMetrics, 0, len(byRoute))
+	for _, route := range byRoute {
+		routes = append(routes, *route)
+	}
+	slices.SortFunc(routes, func(left, right AdminRouteMetrics) int {
+		return strings.Compare(left.Route+" "+left.Method, right.Route+" "+right.Method)
+	})
+	return routes
+}
@@ -0,0 +1,263 @@
+// Package async implements the asynchronous request pattern for long-running
+// operations on top of routerx: the client starts an operation, immediately
+// receives 202 Accepted with a Location header pointing at an automatically
+// registered status route, and polls that route for progress and the result.
+package async
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/Mark-Bazylev/routerx"
+)
+
+// Status is the lifecycle state of a Job.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Job is the persisted state of an asynchronous operation.
+type Job struct {
+	ID        string    `json:"id"`
+	Status    Status    `json:"status"`
+	Progress  float64   `json:"progress"`
+	Message   string    `json:"message,omitempty"`
+	Result    any       `json:"result,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ErrNotFound is returned by a Store when a job does not exist.
+var ErrNotFound = errors.New("async: job not found")
+
+// Store persists jobs. Implementations must be safe for concurrent use and
+// must return ErrNotFound from Get for unknown IDs.
+type Store interface {
+	Save(ctx context.Context, job Job) error
+	Get(ctx context.Context, id string) (Job, error)
+}
+
+// Work is the long-running part of an operation. It runs in the background
+// after the client has received 202 Accepted, and its result is stored on the
+// Job once it returns.
+type Work func(ctx context.Context, progress *Progress) (any, error)
+
+// Handler validates a request and returns the Work to run for it. Returning
+// an error rejects the request before any job is created, with the status of
+// an error implementing StatusCode() int, such as *routerx.HTTPError, or 400
+// Bad Request otherwise. The message of errors with a 5xx status is not
+// revealed to the client.
+type Handler func(request *http.Request) (Work, error)
+
+// Progress lets Work report how far it has come.
+type Progress struct {
+	store Store
+	clock routerx.Clock
+	job   Job
+}
+
+// Update records the completed fraction (0 to 1) and a human-readable
+// message on the job.
+func (progress *Progress) Update(ctx context.Context, fraction float64, message string) error {
+	progress.job.Progress = min(max(fraction, 0), 1)
+	progress.job.Message = message
+	progress.job.UpdatedAt = progress.clock.Now()
+	return progress.store.Save(ctx, progress.job)
+}
+
+// Registrar is implemented by *routerx.Router and *routerx.RouteGroup.
+type Registrar interface {
+	Get(path string, handler http.HandlerFunc)
+	Post(path string, handler http.HandlerFunc)
+}
+
+// Config configures a Manager.
+type Config struct {
+	// MaxRunning is the number of jobs that may run at once. Requests
+	// starting a job beyond it are answered with 503 Service Unavailable.
+	// Defaults to 32.
+	MaxRunning int
+
+	// Clock tells the time for the job timestamps. Defaults to the clock of
+	// the router serving the request starting the job, see routerx.GetClock.
+	Clock routerx.Clock
+
+	// Random is read for the job IDs. Defaults to the source of the router
+	// serving the request starting the job, see routerx.GetRandom.
+	Random io.Reader
+}
+
+// Manager starts jobs and serves their status.
+type Manager struct {
+	store   Store
+	config  Config
+	running chan struct{}
+}
+
+// New creates a Manager persisting jobs in store.
+func New(store Store, configs ...Config) *Manager {
+	var config Config
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.MaxRunning <= 0 {
+		config.MaxRunning = 32
+	}
+	return &Manager{store: store, config: config, running: make(chan struct{}, config.MaxRunning)}
+}
+
+// Register registers "POST path", which starts a job with handler, and
+// "GET path/jobs/{id}", which reports the job status.
+//
+// Example:
+//
+//	jobs := async.New(async.NewMemoryStore())
+//	jobs.Register(router.Group("/api"), "/reports", func(request *http.Request) (async.Work, error) {
+//	    return func(ctx context.Context, progress *async.Progress) (any, error) {
+//	        return buildReport(ctx, progress)
+//	    }, nil
+//	})
+//	// POST /api/reports          -> 202, Location: reports/jobs/3f2a... (/api/reports/jobs/3f2a...)
+//	// GET  /api/reports/jobs/{id} -> {"status": "running", "progress": 0.4, ...}
+func (manager *Manager) Register(registrar Registrar, path string, handler Handler) {
+	path = "/" + strings.Trim(path, "/")
+	registrar.Post(path, manager.start(handler))
+	registrar.Get(strings.TrimSuffix(path, "/")+"/jobs/{id}", manager.status)
+}
+
+func (manager *Manager) start(handler Handler) http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		work, err := handler(request)
+		if err != nil {
+			writeHandlerError(responseWriter, err)
+			return
+		}
+		select {
+		case manager.running <- struct{}{}:
+		default:
+			responseWriter.Header().Set("Retry-After", "1")
+			writeJSON(responseWriter, http.StatusServiceUnavailable, map[string]string{"error": "too many running jobs"})
+			return
+		}
+		clock, random := manager.config.Clock, manager.config.Random
+		if clock == nil {
+			clock = routerx.GetClock(request)
+		}
+		if random == nil {
+			random = routerx.GetRandom(request)
+		}
+		now := clock.Now()
+		job := Job{ID: newID(random), Status: StatusPending, CreatedAt: now, UpdatedAt: now}
+		if err := manager.store.Save(request.Context(), job); err != nil {
+			<-manager.running
+			writeJSON(responseWriter, http.StatusInternalServerError, map[string]string{"error": "failed to create job"})
+			return
+		}
+
+		go manager.run(context.WithoutCancel(request.Context()), clock, job, work)
+
+		responseWriter.Header().Set("Location", statusLocation(request.URL.Path, job.ID))
+		writeJSON(responseWriter, http.StatusAccepted, job)
+	}
+}
+
+// statusLocation returns the Location of the status route of a job started
+// at requestPath. It is relative to the request URL, so that it stays right
+// when a prefix was stripped from the path, as with Mount.
+func statusLocation(requestPath string, id string) string {
+	if strings.HasSuffix(requestPath, "/") {
+		return "jobs/" + id
+	}
+	return path.Base(requestPath) + "/jobs/" + id
+}
+
+// writeHandlerError answers an error returned by a Handler.
+func writeHandlerError(responseWriter http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	var coded interface{ StatusCode() int }
+	if errors.As(err, &coded) && coded.StatusCode() >= 400 && coded.StatusCode() <= 599 {
+		status = coded.StatusCode()
+	}
+	message := err.Error()
+	if status >= http.StatusInternalServerError {
+		message = http.StatusText(status)
+	}
+	writeJSON(responseWriter, status, map[string]string{"error": message})
+}
+
+func (manager *Manager) run(ctx context.Context, clock routerx.Clock, job Job, work Work) {
+	defer func() { <-manager.running }()
+	job.Status = StatusRunning
+	job.UpdatedAt = clock.Now()
+	if err := manager.store.Save(ctx, job); err != nil {
+		log.Printf("async: save job %s: %v", job.ID, err)
+	}
+
+	progress := &Progress{store: manager.store, clock: clock, job: job}
+	result, err := runWork(ctx, work, progress, job.ID)
+
+	job = progress.job
+	job.UpdatedAt = clock.Now()
+	if err != nil {
+		job.Status, job.Error = StatusFailed, err.Error()
+	} else {
+		job.Status, job.Result, job.Progress = StatusSucceeded, result, 1
+	}
+	if err := manager.store.Save(ctx, job); err != nil {
+		log.Printf("async: save job %s: %v", job.ID, err)
+	}
+}
+
+// runWork calls work, turning a panic into an error so that a failing job
+// does not crash the server.
+func runWork(ctx context.Context, work Work, progress *Progress, id string) (result any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("async: panic in job %s: %v\n%s", id, recovered, debug.Stack())
+			result, err = nil, errors.New("internal error")
+		}
+	}()
+	return work(ctx, progress)
+}
+
+func (manager *Manager) status(responseWriter http.ResponseWriter, request *http.Request) {
+	job, err := manager.store.Get(request.Context(), request.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeJSON(responseWriter, http.StatusNotFound, map[string]string{"error": "job not found"})
+	case err != nil:
+		writeJSON(responseWriter, http.StatusInternalServerError, map[string]string{"error": "failed to load job"})
+	default:
+		if job.Status == StatusPending || job.Status == StatusRunning {
+			responseWriter.Header().Set("Retry-After", "1")
+		}
+		writeJSON(responseWriter, http.StatusOK, job)
+	}
+}
+
+func newID(random io.Reader) string {
+	buffer := make([]byte, 16)
+	_, _ = io.ReadFull(random, buffer)
+	return hex.EncodeToString(buffer)
+}
+
+func writeJSON(responseWriter http.ResponseWriter, statusCode int, data any) {
+	responseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	responseWriter.WriteHeader(statusCode)
+	_ = json.NewEncoder(responseWriter).Encode(data)
+}
@@ -0,0 +1,119 @@
+package async
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Mark-Bazylev/routerx"
+)
+
+// MemoryStoreConfig configures a MemoryStore.
+type MemoryStoreConfig struct {
+	// TTL is how long finished jobs are kept after their last update.
+	// Defaults to 1 hour.
+	TTL time.Duration
+
+	// MaxJobs caps the number of jobs kept. Once it is reached, the finished
+	// jobs updated longest ago are dropped first. Defaults to 10000.
+	MaxJobs int
+
+	// Clock tells the time for expiring jobs. Defaults to
+	// routerx.SystemClock.
+	Clock routerx.Clock
+}
+
+// MemoryStore is a Store keeping jobs in process memory. Jobs are lost on
+// restart and are not shared between instances, which makes it suitable for
+// development and single-instance deployments. Finished jobs are dropped
+// after the configured TTL, or earlier when the store is full.
+type MemoryStore struct {
+	config MemoryStoreConfig
+
+	mutex     sync.RWMutex
+	jobs      map[string]Job
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore(configs ...MemoryStoreConfig) *MemoryStore {
+	var config MemoryStoreConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.TTL <= 0 {
+		config.TTL = time.Hour
+	}
+	if config.MaxJobs <= 0 {
+		config.MaxJobs = 10000
+	}
+	if config.Clock == nil {
+		config.Clock = routerx.SystemClock
+	}
+	return &MemoryStore{config: config, jobs: make(map[string]Job)}
+}
+
+// Save stores job, replacing any previous state with the same ID.
+func (store *MemoryStore) Save(_ context.Context, job Job) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	now := store.config.Clock.Now()
+	if _, found := store.jobs[job.ID]; !found && len(store.jobs) >= store.config.MaxJobs || now.Sub(store.lastSweep) > time.Minute {
+		store.sweep(now)
+	}
+	store.jobs[job.ID] = job
+	return nil
+}
+
+// sweep drops the expired finished jobs and, while the store is full, the
+// finished jobs updated longest ago.
+func (store *MemoryStore) sweep(now time.Time) {
+	store.lastSweep = now
+	var oldest []Job
+	for id, job := range store.jobs {
+		if !finished(job) {
+			continue
+		}
+		if now.Sub(job.UpdatedAt) > store.config.TTL {
+			delete(store.jobs, id)
+			continue
+		}
+		oldest = append(oldest, job)
+	}
+	if len(store.jobs) < store.config.MaxJobs {
+		return
+	}
+	slices.SortFunc(oldest, func(left, right Job) int {
+		return left.UpdatedAt.Compare(right.UpdatedAt)
+	})
+	for _, job := range oldest {
+		if len(store.jobs) < store.config.MaxJobs {
+			return
+		}
+		delete(store.jobs, job.ID)
+	}
+}
+
+func finished(job Job) bool {
+	return job.Status == StatusSucceeded || job.Status == StatusFailed
+}
+
+// Get returns the job with the given ID or ErrNotFound.
+func (store *MemoryStore) Get(_ context.Context, id string) (Job, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	job, found := store.jobs[id]
+	if !found {
+		return Job{}, ErrNotFound
+	}
+	return job, nil
+}
+
+// Delete removes the job with the given ID.
+func (store *MemoryStore) Delete(_ context.Context, id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.jobs, id)
+	return nil
+}
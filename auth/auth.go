@@ -0,0 +1,113 @@
+// Package auth provides security requirements for routerx routes. Each
+// requirement both guards the route and documents it in the OpenAPI
+// document, see routerx.PathBuilder.Security:
+//
+//	router.Path("/users").
+//	    Security(auth.Bearer("users:read")).
+//	    Get(listUsers)
+//
+// The requirements authorize the principal attached to the request by an
+// authentication middleware with routerx.SetPrincipal, such as routerx.JWT;
+// they do not verify credentials themselves. Each requirement only accepts
+// principals authenticated with its scheme, see routerx.Principal.Scheme, so
+// a route requiring a bearer token is not satisfied by a basic login.
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Mark-Bazylev/routerx"
+)
+
+// Requirement is a routerx.SecurityRequirement for one security scheme.
+type Requirement struct {
+	name      string
+	object    map[string]any
+	scopes    []string
+	challenge string
+
+	// scheme is the routerx.Principal.Scheme the principal must carry.
+	scheme string
+}
+
+// Bearer requires a bearer token granting all of scopes, documented as the
+// HTTP bearer scheme "bearerAuth".
+func Bearer(scopes ...string) Requirement {
+	return Requirement{
+		name:      "bearerAuth",
+		object:    map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+		scopes:    scopes,
+		challenge: "Bearer",
+		scheme:    routerx.SchemeBearer,
+	}
+}
+
+// APIKey requires an API key sent in the named header granting all of
+// scopes, documented as the API key scheme "apiKeyAuth".
+func APIKey(header string, scopes ...string) Requirement {
+	return Requirement{
+		name:   "apiKeyAuth",
+		object: map[string]any{"type": "apiKey", "in": "header", "name": header},
+		scopes: scopes,
+		scheme: routerx.SchemeAPIKey,
+	}
+}
+
+// Basic requires HTTP basic authentication, documented as the HTTP basic
+// scheme "basicAuth".
+func Basic(realm string) Requirement {
+	return Requirement{
+		name:      "basicAuth",
+		object:    map[string]any{"type": "http", "scheme": "basic"},
+		challenge: `Basic realm="` + realm + `", charset="UTF-8"`,
+		scheme:    routerx.SchemeBasic,
+	}
+}
+
+// Named returns a copy of the requirement documented under name, for APIs
+// with several schemes of the same type.
+func (requirement Requirement) Named(name string) Requirement {
+	requirement.name = name
+	return requirement
+}
+
+// Scheme returns the name and the OpenAPI security scheme object.
+func (requirement Requirement) Scheme() (string, map[string]any) {
+	return requirement.name, requirement.object
+}
+
+// Scopes returns the required scopes.
+func (requirement Requirement) Scopes() []string {
+	return requirement.scopes
+}
+
+// Middleware returns a routerx.Middleware answering 401 Unauthorized for
+// requests without a principal authenticated with the requirement's scheme
+// and 403 Forbidden for principals lacking one of the scopes.
+func (requirement Requirement) Middleware() routerx.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			principal := routerx.GetPrincipal(request)
+			if principal == nil || principal.Scheme != requirement.scheme {
+				if requirement.challenge != "" {
+					responseWriter.Header().Set("WWW-Authenticate", requirement.challenge)
+				}
+				routerx.JSON(responseWriter, http.StatusUnauthorized, map[string]string{"error": http.StatusText(http.StatusUnauthorized)})
+				return
+			}
+			for _, scope := range requirement.scopes {
+				if principal.HasScope(scope) {
+					continue
+				}
+				if requirement.challenge == "Bearer" {
+					responseWriter.Header().Set("WWW-Authenticate",
+						`Bearer error="insufficient_scope", scope="`+strings.Join(requirement.scopes, " ")+`"`)
+				}
+				routerx.JSON(responseWriter, http.StatusForbidden, map[string]string{"error": "missing scope " + scope})
+				return
+			}
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
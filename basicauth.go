@@ -0,0 +1,122 @@
+package routerx
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+)
+
+// BasicAuthConfig configures the BasicAuth middleware.
+type BasicAuthConfig struct {
+	// Realm is announced in the WWW-Authenticate challenge. Defaults to
+	// "Restricted".
+	Realm string
+}
+
+// BasicAuth returns a Middleware that authenticates requests with HTTP basic
+// authentication. validate reports whether a username and password are
+// valid, e.g. with BasicAuthUsers; authenticated requests carry a Principal
+// with the username as its ID. Other requests are answered with 401
+// Unauthorized and a challenge through the router's error handler, see
+// HandleError. Only use basic authentication over HTTPS.
+//
+// Example:
+//
+//	admin := router.Group("/admin").Use(routerx.BasicAuth(routerx.BasicAuthUsers(map[string]string{
+//	    "ops": os.Getenv("ADMIN_PASSWORD"),
+//	})))
+func BasicAuth(validate func(username string, password string) bool, configs ...BasicAuthConfig) Middleware {
+	var config BasicAuthConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.Realm == "" {
+		config.Realm = "Restricted"
+	}
+	challenge := "Basic realm=" + strconv.Quote(config.Realm) + `, charset="UTF-8"`
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			username, password, ok := request.BasicAuth()
+			if !ok || !validate(username, password) {
+				responseWriter.Header().Set("WWW-Authenticate", challenge)
+				HandleError(responseWriter, request, NewHTTPError(http.StatusUnauthorized, "invalid credentials"))
+				return
+			}
+			next.ServeHTTP(responseWriter, SetPrincipal(request, &Principal{ID: username, Scheme: SchemeBasic}))
+		})
+	}
+}
+
+// BasicAuthUsers returns a validator for BasicAuth accepting the given
+// usernames and passwords. Passwords are compared in constant time.
+func BasicAuthUsers(users map[string]string) func(username string, password string) bool {
+	return func(username string, password string) bool {
+		expected, found := users[username]
+		// Compare against a value even for unknown users so that the
+		// response time does not reveal which usernames exist.
+		return SecureCompare(password, expected) && found
+	}
+}
+
+// APIKey returns a Middleware that authenticates requests with an API key
+// sent in the header name or, failing that, the query parameter name.
+// validate returns the principal a key belongs to, e.g. with StaticAPIKeys,
+// which is attached to the request with SetPrincipal. Requests without a
+// valid key are answered with 401 Unauthorized through the router's error
+// handler, see HandleError.
+//
+// Example:
+//
+//	internal := router.Group("/internal").Use(routerx.APIKey("X-API-Key", routerx.StaticAPIKeys(map[string]string{
+//	    os.Getenv("BILLING_API_KEY"): "billing",
+//	})))
+func APIKey(name string, validate func(key string) (*Principal, bool)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			key := request.Header.Get(name)
+			if key == "" {
+				key = request.URL.Query().Get(name)
+			}
+			if key == "" {
+				HandleError(responseWriter, request, NewHTTPError(http.StatusUnauthorized, "missing API key"))
+				return
+			}
+			principal, ok := validate(key)
+			if !ok {
+				HandleError(responseWriter, request, NewHTTPError(http.StatusUnauthorized, "invalid API key"))
+				return
+			}
+			if principal != nil {
+				authenticated := *principal
+				authenticated.Scheme = SchemeAPIKey
+				request = SetPrincipal(request, &authenticated)
+			}
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
+
+// StaticAPIKeys returns a validator for APIKey accepting the keys of keys,
+// whose values are the IDs of the principals the keys belong to. Every key
+// is compared in constant time.
+func StaticAPIKeys(keys map[string]string) func(key string) (*Principal, bool) {
+	return func(key string) (*Principal, bool) {
+		var principal *Principal
+		for expected, id := range keys {
+			if SecureCompare(key, expected) {
+				principal = &Principal{ID: id}
+			}
+		}
+		return principal, principal != nil
+	}
+}
+
+// SecureCompare reports whether given equals expected in time independent
+// of their contents and lengths, for comparing passwords, API keys, and
+// other secrets.
+func SecureCompare(given string, expected string) bool {
+	givenHash := sha256.Sum256([]byte(given))
+	expectedHash := sha256.Sum256([]byte(expected))
+	return subtle.ConstantTimeCompare(givenHash[:], expectedHash[:]) == 1
+}
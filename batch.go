@@ -0,0 +1,178 @@
+package routerx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// BatchConfig limits the work done by a single batch request.
+type BatchConfig struct {
+	// MaxItems rejects payloads with more items with 413. Defaults to 100.
+	MaxItems int
+
+	// Concurrency is the number of items processed at the same time.
+	// Defaults to 4.
+	Concurrency int
+
+	// MaxBodyBytes rejects larger request bodies with 413. Defaults to
+	// 10 MiB.
+	MaxBodyBytes int64
+}
+
+// BatchResult is the outcome of a single item of a batch request.
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Status int    `json:"status"`
+	Data   any    `json:"data,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResponse is the body written by handlers created with BatchHandler.
+type BatchResponse struct {
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// BatchHandler returns a handler for bulk operations. The request body must be
+// a JSON array of items, or an object with an "items" array. Every item is
+// decoded into T and passed to process with bounded concurrency; the response
+// lists the per-item outcome in request order. An error returned by process
+// is reported with status 500 unless it implements StatusCode() int. Once
+// the request context is done, no further items are started, and the
+// remaining ones are reported with status 503 without being processed.
+//
+// Example:
+//
+//	router.Batch("/users", "batchCreate", routerx.BatchHandler(routerx.BatchConfig{MaxItems: 500},
+//	    func(ctx context.Context, user User) (any, error) {
+//	        return store.CreateUser(ctx, user)
+//	    }))
+func BatchHandler[T any](config BatchConfig, process func(ctx context.Context, item T) (any, error)) http.HandlerFunc {
+	if config.MaxItems <= 0 {
+		config.MaxItems = 100
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 4
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 10 << 20
+	}
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		content, err := io.ReadAll(http.MaxBytesReader(responseWriter, request.Body, config.MaxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(responseWriter, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			writeError(responseWriter, http.StatusBadRequest, "failed to read request body")
+			return
+		}
+		items, err := decodeBatchItems(content)
+		if err != nil {
+			writeError(responseWriter, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(items) > config.MaxItems {
+			writeError(responseWriter, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("batch contains %d items, the maximum is %d", len(items), config.MaxItems))
+			return
+		}
+
+		ctx := request.Context()
+		results := make([]BatchResult, len(items))
+		limit := make(chan struct{}, config.Concurrency)
+		var wait sync.WaitGroup
+		for index, raw := range items {
+			select {
+			case limit <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				for remaining := index; remaining < len(items); remaining++ {
+					results[remaining] = BatchResult{Index: remaining, Status: http.StatusServiceUnavailable, Error: "not processed: " + ctx.Err().Error()}
+				}
+				break
+			}
+			wait.Add(1)
+			go func() {
+				defer func() {
+					<-limit
+					wait.Done()
+				}()
+				results[index] = runBatchItem(ctx, index, raw, process)
+			}()
+		}
+		wait.Wait()
+
+		response := BatchResponse{Results: results}
+		for _, result := range results {
+			if result.Error == "" {
+				response.Succeeded++
+			} else {
+				response.Failed++
+			}
+		}
+		writeJSON(responseWriter, http.StatusOK, response)
+	}
+}
+
+func runBatchItem[T any](ctx context.Context, index int, raw json.RawMessage, process func(ctx context.Context, item T) (any, error)) BatchResult {
+	var item T
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return BatchResult{Index: index, Status: http.StatusBadRequest, Error: "invalid item: " + err.Error()}
+	}
+	data, err := process(ctx, item)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var coded interface{ StatusCode() int }
+		if errors.As(err, &coded) {
+			status = coded.StatusCode()
+		}
+		return BatchResult{Index: index, Status: status, Error: err.Error()}
+	}
+	return BatchResult{Index: index, Status: http.StatusOK, Data: data}
+}
+
+// decodeBatchItems accepts either [...] or {"items": [...]}.
+func decodeBatchItems(content []byte) ([]json.RawMessage, error) {
+	content = bytes.TrimSpace(content)
+	var items []json.RawMessage
+	var err error
+	if bytes.HasPrefix(content, []byte("{")) {
+		var wrapper struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		err = json.Unmarshal(content, &wrapper)
+		items = wrapper.Items
+	} else {
+		err = json.Unmarshal(content, &items)
+	}
+	if err != nil {
+		return nil, errors.New("batch payload must be a JSON array of items")
+	}
+	return items, nil
+}
+
+// Batch registers handler for POST requests to the bulk variant of path,
+// i.e. "POST {path}:{action}".
+//
+// Example:
+//
+//	router.Batch("/users", "batchDelete", deleteUsers) // POST /users:batchDelete
+func (router *Router) Batch(path string, action string, handler http.HandlerFunc) {
+	router.Post(cleanPath(path)+":"+action, handler)
+}
+
+// Batch registers handler for POST requests to the bulk variant of path
+// under the group's prefix. See Router.Batch.
+func (group *RouteGroup) Batch(path string, action string, handler http.HandlerFunc) {
+	group.Post(cleanPath(path)+":"+action, handler)
+}
@@ -0,0 +1,238 @@
+package routerx
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// BindError describes a request value that could not be bound. Its status
+// code is 400 Bad Request.
+type BindError struct {
+	// Source is "path", "query", "form", or "body".
+	Source string
+
+	// Field is the parameter or form field name. It is empty for body
+	// errors.
+	Field string
+
+	// Value is the rejected input. It is empty for body errors.
+	Value string
+
+	Err error
+}
+
+func (err *BindError) Error() string {
+	detail := err.Err
+	var numberError *strconv.NumError
+	if errors.As(err.Err, &numberError) {
+		detail = numberError.Err
+	}
+	switch err.Source {
+	case "body":
+		return "invalid request body: " + detail.Error()
+	case "path":
+		return fmt.Sprintf("invalid path parameter %q: %v", err.Field, detail)
+	case "query":
+		return fmt.Sprintf("invalid query parameter %q: %v", err.Field, detail)
+	}
+	return fmt.Sprintf("invalid form field %q: %v", err.Field, detail)
+}
+
+// StatusCode returns 400 Bad Request.
+func (err *BindError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// Unwrap returns the underlying parse error.
+func (err *BindError) Unwrap() error {
+	return err.Err
+}
+
+// defaultMultipartMemory is the memory limit for multipart forms parsed by
+// Bind; larger files are stored in temporary files.
+const defaultMultipartMemory = 32 << 20
+
+// Bind populates destination, a pointer to a struct, from the request:
+//
+//   - a JSON body is decoded with encoding/json, honouring json tags
+//   - fields tagged `path:"name"` receive path parameters
+//   - fields tagged `query:"name"` receive query parameters
+//   - fields tagged `form:"name"` receive url-encoded or multipart form
+//     fields; *multipart.FileHeader and []*multipart.FileHeader fields
+//     receive uploaded files
+//
+// Tagged fields may be strings, booleans, numbers, pointers to those, types
+// implementing encoding.TextUnmarshaler such as time.Time, or slices of those
+// to receive repeated values. Path, query, and form values are applied after
+// the body and take precedence. Invalid input is reported as a *BindError.
+// The bound value is then checked with Validate, and validation failures are
+// reported as a *ValidationError.
+//
+// Example:
+//
+//	var input struct {
+//	    ID     int      `path:"id"`
+//	    Tags   []string `query:"tag"`
+//	    Name   string   `json:"name"`
+//	}
+//	if err := routerx.Bind(request, &input); err != nil {
+//	    return err
+//	}
+func Bind(request *http.Request, destination any) error {
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	hasBody := request.Body != nil && request.Body != http.NoBody
+	if hasBody && isJSONContentType(request.Header.Get("Content-Type")) {
+		if err := json.NewDecoder(request.Body).Decode(destination); err != nil && !errors.Is(err, io.EOF) {
+			return &BindError{Source: "body", Err: err}
+		}
+	}
+	if hasBody && mediaType == "multipart/form-data" {
+		if err := request.ParseMultipartForm(defaultMultipartMemory); err != nil {
+			return &BindError{Source: "body", Err: err}
+		}
+	} else if hasBody && mediaType == "application/x-www-form-urlencoded" {
+		if err := request.ParseForm(); err != nil {
+			return &BindError{Source: "body", Err: err}
+		}
+	}
+
+	query := request.URL.Query()
+	target := reflect.ValueOf(destination)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Struct {
+		return Validate(destination)
+	}
+	target = target.Elem()
+	for index := 0; index < target.NumField(); index++ {
+		field := target.Type().Field(index)
+		if !field.IsExported() {
+			continue
+		}
+		var source, name string
+		var values []string
+		if name = field.Tag.Get("path"); name != "" {
+			source = "path"
+			if value := request.PathValue(name); value != "" {
+				values = []string{value}
+			}
+		} else if name = field.Tag.Get("query"); name != "" {
+			source = "query"
+			values = query[name]
+		} else if name = field.Tag.Get("form"); name != "" {
+			source = "form"
+			if bindFiles(target.Field(index), request.MultipartForm, name) {
+				continue
+			}
+			values = request.PostForm[name]
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if err := setFieldValues(target.Field(index), values); err != nil {
+			return &BindError{Source: source, Field: name, Value: strings.Join(values, ","), Err: err}
+		}
+	}
+	return Validate(destination)
+}
+
+var (
+	fileHeaderType      = reflect.TypeFor[*multipart.FileHeader]()
+	fileHeaderSliceType = reflect.TypeFor[[]*multipart.FileHeader]()
+)
+
+// bindFiles sets field to the uploaded files named name when it holds
+// *multipart.FileHeader values, and reports whether it did.
+func bindFiles(field reflect.Value, form *multipart.Form, name string) bool {
+	if field.Type() != fileHeaderType && field.Type() != fileHeaderSliceType {
+		return false
+	}
+	if form == nil || len(form.File[name]) == 0 {
+		return true
+	}
+	if field.Type() == fileHeaderType {
+		field.Set(reflect.ValueOf(form.File[name][0]))
+	} else {
+		field.Set(reflect.ValueOf(form.File[name]))
+	}
+	return true
+}
+
+// setFieldValues parses values into field, which may be a slice to receive
+// repeated values.
+func setFieldValues(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && !implementsTextUnmarshaler(field) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for index, value := range values {
+			if err := setFieldValue(slice.Index(index), value); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return setFieldValue(field, values[0])
+}
+
+func setFieldValue(field reflect.Value, value string) error {
+	if implementsTextUnmarshaler(field) {
+		if field.Kind() == reflect.Pointer && field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		target := field
+		if field.Kind() != reflect.Pointer {
+			target = field.Addr()
+		}
+		return target.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(strings.TrimSpace(value), 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+	case reflect.Pointer:
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setFieldValue(field.Elem(), value)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// textUnmarshalerType is the reflect.Type of encoding.TextUnmarshaler.
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+func implementsTextUnmarshaler(field reflect.Value) bool {
+	return field.Type().Implements(textUnmarshalerType) || reflect.PointerTo(field.Type()).Implements(textUnmarshalerType)
+}
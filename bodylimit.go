@@ -0,0 +1,138 @@
+package routerx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type bodyLimitContextKey struct{}
+
+// BodyLimit returns a Middleware that limits request bodies to maxBytes with
+// http.MaxBytesReader. Requests whose Content-Length or body exceeds the
+// limit are answered with 413 Request Entity Too Large and a JSON error,
+// whatever response the handler writes after its read failed. A BodyLimit
+// closer to the handler overrides one applied further out, so routes such
+// as file uploads can raise the router's limit, and a non-positive maxBytes
+// removes it.
+//
+// Example:
+//
+//	router := routerx.New().Use(routerx.BodyLimit(1 << 20))
+//	router.Path("/uploads").Use(routerx.BodyLimit(100 << 20)).Post(upload)
+func BodyLimit(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			if limiter, ok := request.Context().Value(bodyLimitContextKey{}).(*bodyLimiter); ok {
+				limiter.limit = maxBytes
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			if request.Body == nil || request.Body == http.NoBody {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			limiter := &bodyLimiter{
+				source:         request.Body,
+				responseWriter: responseWriter,
+				limit:          maxBytes,
+				contentLength:  request.ContentLength,
+			}
+			request = request.WithContext(context.WithValue(request.Context(), bodyLimitContextKey{}, limiter))
+			request.Body = limiter
+			writer := &bodyLimitWriter{ResponseWriter: responseWriter, limiter: limiter}
+			next.ServeHTTP(writer, request)
+			writer.reject()
+		})
+	}
+}
+
+// bodyLimiter wraps the body with http.MaxBytesReader on the first read, so
+// that the limit of the innermost BodyLimit applies.
+type bodyLimiter struct {
+	source         io.ReadCloser
+	responseWriter http.ResponseWriter
+	limit          int64
+	reader         io.ReadCloser
+	contentLength  int64
+	exceeded       bool
+}
+
+func (limiter *bodyLimiter) Read(data []byte) (int, error) {
+	if limiter.reader == nil {
+		limiter.reader = limiter.source
+		if limiter.limit > 0 {
+			if limiter.contentLength > limiter.limit {
+				limiter.exceeded = true
+				return 0, &http.MaxBytesError{Limit: limiter.limit}
+			}
+			limiter.reader = http.MaxBytesReader(limiter.responseWriter, limiter.source, limiter.limit)
+		}
+	}
+	count, err := limiter.reader.Read(data)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		limiter.exceeded = true
+	}
+	return count, err
+}
+
+func (limiter *bodyLimiter) Close() error {
+	return limiter.source.Close()
+}
+
+// bodyLimitWriter replaces the response with 413 once the body exceeded the
+// limit.
+type bodyLimitWriter struct {
+	http.ResponseWriter
+	limiter  *bodyLimiter
+	rejected bool
+	started  bool
+}
+
+func (writer *bodyLimitWriter) WriteHeader(statusCode int) {
+	if writer.reject() || writer.started {
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		writer.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	writer.started = true
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (writer *bodyLimitWriter) Write(data []byte) (int, error) {
+	if writer.reject() {
+		return len(data), nil
+	}
+	writer.started = true
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *bodyLimitWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// reject writes the 413 response the first time it is called after the
+// limit was exceeded, and reports whether the handler's response is
+// discarded.
+func (writer *bodyLimitWriter) reject() bool {
+	if writer.rejected {
+		return true
+	}
+	if writer.started || !writer.limiter.exceeded {
+		return false
+	}
+	writer.rejected = true
+	header := writer.ResponseWriter.Header()
+	for _, name := range []string{"Content-Type", "Content-Length", "Content-Encoding", "ETag", "Last-Modified"} {
+		header.Del(name)
+	}
+	header.Set("Connection", "close")
+	writeError(writer.ResponseWriter, http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("request body exceeds %d bytes", writer.limiter.limit))
+	return true
+}
@@ -0,0 +1,164 @@
+package routerx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// KeyCase selects a naming convention for JSON object keys.
+type KeyCase int
+
+const (
+	// KeepCase leaves keys untouched.
+	KeepCase KeyCase = iota
+	// SnakeCase converts keys to snake_case.
+	SnakeCase
+	// CamelCase converts keys to camelCase.
+	CamelCase
+)
+
+// convert returns key in the naming convention of keyCase.
+func (keyCase KeyCase) convert(key string) string {
+	switch keyCase {
+	case SnakeCase:
+		return toSnakeCase(key)
+	case CamelCase:
+		return toCamelCase(key)
+	}
+	return key
+}
+
+// toSnakeCase converts camelCase, PascalCase, and kebab-case keys to
+// snake_case. Runs of capitals are treated as one word, so "userID" becomes
+// "user_id".
+func toSnakeCase(key string) string {
+	runes := []rune(key)
+	var builder strings.Builder
+	builder.Grow(len(key) + 4)
+	for index, current := range runes {
+		if current == '-' || current == ' ' {
+			builder.WriteByte('_')
+			continue
+		}
+		if unicode.IsUpper(current) {
+			if index > 0 && runes[index-1] != '_' && runes[index-1] != '-' {
+				previousLower := unicode.IsLower(runes[index-1]) || unicode.IsDigit(runes[index-1])
+				nextLower := index+1 < len(runes) && unicode.IsLower(runes[index+1])
+				if previousLower || (unicode.IsUpper(runes[index-1]) && nextLower) {
+					builder.WriteByte('_')
+				}
+			}
+			builder.WriteRune(unicode.ToLower(current))
+			continue
+		}
+		builder.WriteRune(current)
+	}
+	return builder.String()
+}
+
+// toCamelCase converts snake_case and kebab-case keys to camelCase. Keys that
+// contain no separators are returned with their first letter lowered.
+func toCamelCase(key string) string {
+	var builder strings.Builder
+	builder.Grow(len(key))
+	upperNext := false
+	for index, current := range key {
+		switch {
+		case current == '_' || current == '-' || current == ' ':
+			upperNext = builder.Len() > 0
+		case upperNext:
+			builder.WriteRune(unicode.ToUpper(current))
+			upperNext = false
+		case index == 0:
+			builder.WriteRune(unicode.ToLower(current))
+		default:
+			builder.WriteRune(current)
+		}
+	}
+	return builder.String()
+}
+
+// convertKeys rewrites every object key in a decoded JSON value with convert,
+// descending into nested objects and arrays.
+func convertKeys(value any, convert func(string) string) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		converted := make(map[string]any, len(typed))
+		for key, child := range typed {
+			converted[convert(key)] = convertKeys(child, convert)
+		}
+		return converted
+	case []any:
+		for index, child := range typed {
+			typed[index] = convertKeys(child, convert)
+		}
+		return typed
+	}
+	return value
+}
+
+// CaseConfig configures the ConvertCase middleware.
+type CaseConfig struct {
+	// Wire is the key convention used by clients, e.g. CamelCase.
+	Wire KeyCase
+
+	// Server is the key convention of the json tags on the Go structs, e.g.
+	// SnakeCase.
+	Server KeyCase
+
+	// MaxBodyBytes limits the request bodies buffered for conversion.
+	// Larger bodies are rejected with 413. Defaults to 10 MiB.
+	MaxBodyBytes int64
+}
+
+// ConvertCase returns a Middleware that converts JSON object keys between the
+// convention clients use on the wire and the one handlers expect. Request
+// bodies are rewritten to config.Server before the handler runs and response
+// bodies are rewritten to config.Wire afterwards, so Go structs can keep a
+// single set of json tags while serving clients with different conventions.
+//
+// Example:
+//
+//	mobile := router.Group("/mobile").Use(routerx.ConvertCase(routerx.CaseConfig{
+//	    Wire:   routerx.CamelCase,
+//	    Server: routerx.SnakeCase,
+//	}))
+func ConvertCase(config CaseConfig) Middleware {
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 10 << 20
+	}
+	transformResponse := Transform(TransformConfig{Case: config.Wire})
+	return func(next http.Handler) http.Handler {
+		convertResponse := transformResponse(next)
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			if config.Server != KeepCase && request.Body != nil && isJSONContentType(request.Header.Get("Content-Type")) {
+				body, err := io.ReadAll(http.MaxBytesReader(responseWriter, request.Body, config.MaxBodyBytes))
+				if err != nil {
+					var tooLarge *http.MaxBytesError
+					if errors.As(err, &tooLarge) {
+						writeError(responseWriter, http.StatusRequestEntityTooLarge, "request body too large")
+						return
+					}
+					writeError(responseWriter, http.StatusBadRequest, "failed to read request body")
+					return
+				}
+				var document any
+				decoder := json.NewDecoder(bytes.NewReader(body))
+				decoder.UseNumber()
+				if err := decoder.Decode(&document); err == nil {
+					if converted, err := json.Marshal(convertKeys(document, config.Server.convert)); err == nil {
+						body = converted
+					}
+				}
+				request.Body = io.NopCloser(bytes.NewReader(body))
+				request.ContentLength = int64(len(body))
+			}
+			convertResponse.ServeHTTP(responseWriter, request)
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package routerx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ClientHintsConfig configures the AcceptClientHints middleware.
+type ClientHintsConfig struct {
+	// Hints lists the client hints the browser is asked to send on
+	// subsequent requests, e.g. "Sec-CH-UA-Mobile", "Sec-CH-DPR", or
+	// "Sec-CH-Viewport-Width". Low-entropy hints such as Sec-CH-UA and
+	// Save-Data are sent without asking.
+	Hints []string
+
+	// Critical lists the hints without which the response would be wrong.
+	// Browsers that support Critical-CH retry the request once with them.
+	Critical []string
+}
+
+// AcceptClientHints returns a Middleware that advertises the configured
+// client hints with Accept-CH and Critical-CH, and keeps the Vary header
+// correct: every hint read through GetClientHints while handling a request
+// is added to Vary before the response headers are written, so caches keep
+// the variants of responses that adapt to device characteristics apart.
+//
+// Example:
+//
+//	images := router.Group("/images").Use(routerx.AcceptClientHints(routerx.ClientHintsConfig{
+//	    Hints: []string{"Sec-CH-DPR", "Sec-CH-Viewport-Width"},
+//	}))
+//	images.Get("/{name}", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    hints := routerx.GetClientHints(request)
+//	    serveImage(responseWriter, request.PathValue("name"), hints.DPR(), hints.SaveData())
+//	})
+func AcceptClientHints(config ClientHintsConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			header := responseWriter.Header()
+			if len(config.Hints) > 0 {
+				header.Set("Accept-CH", strings.Join(config.Hints, ", "))
+			}
+			if len(config.Critical) > 0 {
+				header.Set("Critical-CH", strings.Join(config.Critical, ", "))
+			}
+			usage := &clientHintUsage{}
+			request = request.WithContext(context.WithValue(request.Context(), clientHintsContextKey{}, usage))
+			next.ServeHTTP(&clientHintsWriter{ResponseWriter: responseWriter, usage: usage}, request)
+		})
+	}
+}
+
+type clientHintsContextKey struct{}
+
+// clientHintUsage collects the hint headers read while handling a request.
+type clientHintUsage struct {
+	mutex   sync.Mutex
+	headers []string
+}
+
+func (usage *clientHintUsage) add(name string) {
+	usage.mutex.Lock()
+	defer usage.mutex.Unlock()
+	for _, existing := range usage.headers {
+		if existing == name {
+			return
+		}
+	}
+	usage.headers = append(usage.headers, name)
+}
+
+// clientHintsWriter adds the read hints to Vary when the response headers are
+// written.
+type clientHintsWriter struct {
+	http.ResponseWriter
+	usage       *clientHintUsage
+	wroteHeader bool
+}
+
+func (writer *clientHintsWriter) WriteHeader(statusCode int) {
+	if !writer.wroteHeader {
+		writer.wroteHeader = true
+		writer.usage.mutex.Lock()
+		addVary(writer.Header(), writer.usage.headers...)
+		writer.usage.mutex.Unlock()
+	}
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (writer *clientHintsWriter) Write(data []byte) (int, error) {
+	if !writer.wroteHeader {
+		writer.WriteHeader(http.StatusOK)
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *clientHintsWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// UABrand is a brand and significant version from the Sec-CH-UA hint.
+type UABrand struct {
+	Brand   string
+	Version string
+}
+
+// ClientHints gives typed access to the client hints of a request. Values
+// the client did not send are reported as zero values. Under
+// AcceptClientHints every accessor records its header for the Vary header.
+type ClientHints struct {
+	request *http.Request
+	usage   *clientHintUsage
+}
+
+// GetClientHints returns the client hints of request.
+func GetClientHints(request *http.Request) ClientHints {
+	usage, _ := request.Context().Value(clientHintsContextKey{}).(*clientHintUsage)
+	return ClientHints{request: request, usage: usage}
+}
+
+// value returns the first present header of names, recording all of them as
+// read.
+func (hints ClientHints) value(names ...string) string {
+	for _, name := range names {
+		if hints.usage != nil {
+			hints.usage.add(name)
+		}
+	}
+	for _, name := range names {
+		if value := hints.request.Header.Get(name); value != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// Brands returns the user agent brands from Sec-CH-UA.
+func (hints ClientHints) Brands() []UABrand {
+	var brands []UABrand
+	for _, member := range strings.Split(hints.value("Sec-CH-UA"), ",") {
+		brand, parameters, _ := strings.Cut(strings.TrimSpace(member), ";")
+		if brand = unquoteHint(brand); brand == "" {
+			continue
+		}
+		entry := UABrand{Brand: brand}
+		for _, parameter := range strings.Split(parameters, ";") {
+			if version, found := strings.CutPrefix(strings.TrimSpace(parameter), "v="); found {
+				entry.Version = unquoteHint(version)
+			}
+		}
+		brands = append(brands, entry)
+	}
+	return brands
+}
+
+// Mobile reports whether Sec-CH-UA-Mobile marks the client as mobile.
+func (hints ClientHints) Mobile() bool {
+	return hints.value("Sec-CH-UA-Mobile") == "?1"
+}
+
+// Platform returns the operating system from Sec-CH-UA-Platform, e.g.
+// "Android" or "Windows".
+func (hints ClientHints) Platform() string {
+	return unquoteHint(hints.value("Sec-CH-UA-Platform"))
+}
+
+// DPR returns the device pixel ratio from Sec-CH-DPR or DPR, or 0.
+func (hints ClientHints) DPR() float64 {
+	return parseHintNumber(hints.value("Sec-CH-DPR", "DPR"))
+}
+
+// ViewportWidth returns the layout viewport width in CSS pixels from
+// Sec-CH-Viewport-Width or Viewport-Width, or 0.
+func (hints ClientHints) ViewportWidth() int {
+	return int(parseHintNumber(hints.value("Sec-CH-Viewport-Width", "Viewport-Width")))
+}
+
+// Width returns the intended display width of an image resource in
+// physical pixels from Sec-CH-Width or Width, or 0.
+func (hints ClientHints) Width() int {
+	return int(parseHintNumber(hints.value("Sec-CH-Width", "Width")))
+}
+
+// DeviceMemory returns the approximate device memory in GiB from
+// Sec-CH-Device-Memory or Device-Memory, or 0.
+func (hints ClientHints) DeviceMemory() float64 {
+	return parseHintNumber(hints.value("Sec-CH-Device-Memory", "Device-Memory"))
+}
+
+// SaveData reports whether the client asked for reduced data usage with
+// Save-Data: on.
+func (hints ClientHints) SaveData() bool {
+	return strings.EqualFold(hints.value("Save-Data"), "on")
+}
+
+func unquoteHint(value string) string {
+	value = strings.TrimSpace(value)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+	return value
+}
+
+func parseHintNumber(value string) float64 {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0
+	}
+	return number
+}
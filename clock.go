@@ -0,0 +1,223 @@
+package routerx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	mathrand "math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock tells the time to the router and the built-in middlewares, such as
+// rate limiting, sessions, JWT expiry, queueing, and latency measurement.
+// Tests install a FakeClock with WithClock to control time without sleeping.
+type Clock interface {
+	Now() time.Time
+
+	// After returns a channel receiving the time once duration elapsed.
+	After(duration time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(duration time.Duration) <-chan time.Time {
+	return time.After(duration)
+}
+
+// SystemClock is the Clock used by default, reading the system time.
+var SystemClock Clock = systemClock{}
+
+type clockContextKey struct{}
+
+type randomContextKey struct{}
+
+// WithClock makes the router and the middlewares of its routes read the time
+// from clock, see GetClock.
+//
+// Example:
+//
+//	clock := routerx.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+//	router := routerx.New(routerx.WithClock(clock))
+//	router.Use(routerx.RateLimit(1, time.Minute))
+//	// ...the second request is limited...
+//	clock.Advance(time.Minute)
+//	// ...and allowed again, without sleeping.
+func WithClock(clock Clock) Option {
+	return func(router *Router) {
+		router.clock = clock
+	}
+}
+
+// WithRandom makes the router and the middlewares of its routes read random
+// bytes, e.g. for request IDs, session IDs, and log sampling, from random,
+// see GetRandom. Use it with NewSeededRandom in tests only: the default is
+// crypto/rand, which session and request IDs rely on to be unguessable.
+func WithRandom(random io.Reader) Option {
+	return func(router *Router) {
+		router.random = random
+	}
+}
+
+// timeSource returns the clock of the router outside of requests, e.g. for
+// the delays of a shutdown.
+func (router *Router) timeSource() Clock {
+	if router.clock != nil {
+		return router.clock
+	}
+	return SystemClock
+}
+
+// SetClock returns a shallow copy of the request whose middlewares and
+// handler read the time from clock, for testing a middleware without a
+// router.
+func SetClock(request *http.Request, clock Clock) *http.Request {
+	return request.WithContext(context.WithValue(request.Context(), clockContextKey{}, clock))
+}
+
+// GetClock returns the clock of the router serving the request, see
+// WithClock, or SystemClock.
+func GetClock(request *http.Request) Clock {
+	return clockFrom(request.Context())
+}
+
+// SetRandom returns a shallow copy of the request whose middlewares and
+// handler read random bytes from random, for testing a middleware without a
+// router.
+func SetRandom(request *http.Request, random io.Reader) *http.Request {
+	return request.WithContext(context.WithValue(request.Context(), randomContextKey{}, random))
+}
+
+// GetRandom returns the source of random bytes of the router serving the
+// request, see WithRandom, or crypto/rand.Reader.
+func GetRandom(request *http.Request) io.Reader {
+	return randomFrom(request.Context())
+}
+
+func clockFrom(ctx context.Context) Clock {
+	if clock, ok := ctx.Value(clockContextKey{}).(Clock); ok {
+		return clock
+	}
+	return SystemClock
+}
+
+func randomFrom(ctx context.Context) io.Reader {
+	if random, ok := ctx.Value(randomContextKey{}).(io.Reader); ok {
+		return random
+	}
+	return rand.Reader
+}
+
+// randomFloat returns a number in [0, 1) read from random.
+func randomFloat(random io.Reader) float64 {
+	var buffer [8]byte
+	if _, err := io.ReadFull(random, buffer[:]); err != nil {
+		return 0
+	}
+	return float64(binary.BigEndian.Uint64(buffer[:])>>11) / (1 << 53)
+}
+
+// randomText returns 26 base32 characters read from random, as rand.Text
+// does.
+func randomText(random io.Reader) string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	var buffer [26]byte
+	_, _ = io.ReadFull(random, buffer[:])
+	for index := range buffer {
+		buffer[index] = alphabet[buffer[index]%32]
+	}
+	return string(buffer[:])
+}
+
+// NewSeededRandom returns a deterministic source of random bytes for
+// WithRandom: the same seed yields the same request IDs, session IDs, and
+// sampling decisions on every run. It is safe for concurrent use.
+func NewSeededRandom(seed uint64) io.Reader {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], seed)
+	return &seededRandom{source: mathrand.NewChaCha8(key)}
+}
+
+type seededRandom struct {
+	mutex  sync.Mutex
+	source *mathrand.ChaCha8
+}
+
+func (random *seededRandom) Read(data []byte) (int, error) {
+	random.mutex.Lock()
+	defer random.mutex.Unlock()
+	return random.source.Read(data)
+}
+
+// FakeClock is a Clock for tests whose time only moves with Advance and Set.
+// It is safe for concurrent use.
+type FakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	due     time.Time
+	channel chan time.Time
+}
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the time of the clock.
+func (clock *FakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+// After returns a channel receiving the time once the clock was advanced by
+// duration.
+func (clock *FakeClock) After(duration time.Duration) <-chan time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	channel := make(chan time.Time, 1)
+	if duration <= 0 {
+		channel <- clock.now
+		return channel
+	}
+	clock.waiters = append(clock.waiters, fakeWaiter{due: clock.now.Add(duration), channel: channel})
+	return channel
+}
+
+// Advance moves the clock forward by duration, firing the channels returned
+// by After that became due.
+func (clock *FakeClock) Advance(duration time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.set(clock.now.Add(duration))
+}
+
+// Set moves the clock to now, firing the channels returned by After that
+// became due.
+func (clock *FakeClock) Set(now time.Time) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.set(now)
+}
+
+func (clock *FakeClock) set(now time.Time) {
+	clock.now = now
+	clock.waiters = slices.DeleteFunc(clock.waiters, func(waiter fakeWaiter) bool {
+		if now.Before(waiter.due) {
+			return false
+		}
+		waiter.channel <- now
+		return true
+	})
+}
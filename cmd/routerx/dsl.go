@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// routeFile is a parsed route definition file.
+type routeFile struct {
+	packageName  string
+	functionName string
+	root         *routeBlock
+}
+
+// routeBlock is the root of a file or a group ... end block.
+type routeBlock struct {
+	prefix      string
+	middlewares []string
+	entries     []any // *routeLine or *routeBlock
+}
+
+// routeLine is a single METHOD PATH HANDLER line.
+type routeLine struct {
+	method      string
+	path        string
+	handler     string
+	name        string
+	middlewares []string
+}
+
+var dslMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// parseRouteFile parses the route definition language:
+//
+//	# Comments start with a hash.
+//	package api
+//	func RegisterRoutes
+//
+//	GET /health healthCheck
+//	group /api/v1 use=requireAuth,audit
+//	    GET  /users       users.List
+//	    GET  /users/{id}  users.Show    name=UserShow
+//	    PUT  /users/{id}  users.Update  name=UserShow use=requireAdmin
+//	end
+//
+// Handlers and middlewares are Go expressions resolved in the package of the
+// generated file.
+func parseRouteFile(name string, reader io.Reader) (*routeFile, error) {
+	file := &routeFile{functionName: "RegisterRoutes", root: &routeBlock{}}
+	stack := []*routeBlock{file.root}
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		fail := func(format string, arguments ...any) error {
+			return fmt.Errorf("%s:%d: %s", name, lineNumber, fmt.Sprintf(format, arguments...))
+		}
+		current := stack[len(stack)-1]
+
+		switch keyword := fields[0]; {
+		case keyword == "package":
+			if len(fields) != 2 || !token.IsIdentifier(fields[1]) {
+				return nil, fail("expected: package NAME")
+			}
+			file.packageName = fields[1]
+		case keyword == "func":
+			if len(fields) != 2 || !token.IsIdentifier(fields[1]) {
+				return nil, fail("expected: func NAME")
+			}
+			file.functionName = fields[1]
+		case keyword == "group":
+			if len(fields) < 2 || !strings.HasPrefix(fields[1], "/") {
+				return nil, fail("expected: group /PREFIX [use=MIDDLEWARE,...]")
+			}
+			block := &routeBlock{prefix: fields[1]}
+			for _, option := range fields[2:] {
+				value, found := strings.CutPrefix(option, "use=")
+				if !found {
+					return nil, fail("unknown group option %q", option)
+				}
+				middlewares, err := parseExpressions(value)
+				if err != nil {
+					return nil, fail("%v", err)
+				}
+				block.middlewares = append(block.middlewares, middlewares...)
+			}
+			current.entries = append(current.entries, block)
+			stack = append(stack, block)
+		case keyword == "end":
+			if len(stack) == 1 {
+				return nil, fail("end without group")
+			}
+			stack = stack[:len(stack)-1]
+		case slices.Contains(dslMethods, keyword):
+			if len(fields) < 3 || !strings.HasPrefix(fields[1], "/") {
+				return nil, fail("expected: METHOD /PATH HANDLER [name=NAME] [use=MIDDLEWARE,...]")
+			}
+			if _, err := parser.ParseExpr(fields[2]); err != nil {
+				return nil, fail("invalid handler %q", fields[2])
+			}
+			route := &routeLine{method: keyword, path: fields[1], handler: fields[2]}
+			for _, option := range fields[3:] {
+				key, value, _ := strings.Cut(option, "=")
+				switch key {
+				case "name":
+					if !token.IsIdentifier(value) {
+						return nil, fail("invalid route name %q", value)
+					}
+					route.name = value
+				case "use":
+					middlewares, err := parseExpressions(value)
+					if err != nil {
+						return nil, fail("%v", err)
+					}
+					route.middlewares = append(route.middlewares, middlewares...)
+				default:
+					return nil, fail("unknown route option %q", option)
+				}
+			}
+			current.entries = append(current.entries, route)
+		default:
+			return nil, fail("unknown keyword %q", keyword)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("%s: group %s is missing its end", name, stack[len(stack)-1].prefix)
+	}
+	if file.packageName == "" {
+		return nil, fmt.Errorf("%s: missing package declaration", name)
+	}
+	return file, nil
+}
+
+// parseExpressions splits a comma separated list of Go expressions.
+func parseExpressions(list string) ([]string, error) {
+	var expressions []string
+	for _, expression := range strings.Split(list, ",") {
+		if _, err := parser.ParseExpr(expression); err != nil {
+			return nil, fmt.Errorf("invalid expression %q", expression)
+		}
+		expressions = append(expressions, expression)
+	}
+	return expressions, nil
+}
+
+// generate renders the registration function for file.
+func (file *routeFile) generate(source string) ([]byte, error) {
+	var output bytes.Buffer
+	fmt.Fprintf(&output, "// Code generated by routerx gen from %s; DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&output, "package %s\n\n", file.packageName)
+	output.WriteString("import \"github.com/Mark-Bazylev/routerx\"\n\n")
+	fmt.Fprintf(&output, "// %s registers the routes declared in %s.\n", file.functionName, source)
+	fmt.Fprintf(&output, "func %s(router *routerx.Router) {\n", file.functionName)
+	groups := 0
+	file.root.generate(&output, "router", &groups)
+	output.WriteString("}\n")
+	return format.Source(output.Bytes())
+}
+
+func (block *routeBlock) generate(output *bytes.Buffer, receiver string, groups *int) {
+	for _, entry := range block.entries {
+		switch entry := entry.(type) {
+		case *routeLine:
+			fmt.Fprintf(output, "%s.Path(%s)", receiver, strconv.Quote(entry.path))
+			if entry.name != "" {
+				fmt.Fprintf(output, ".Name(%s)", strconv.Quote(entry.name))
+			}
+			if len(entry.middlewares) > 0 {
+				fmt.Fprintf(output, ".Use(%s)", strings.Join(entry.middlewares, ", "))
+			}
+			fmt.Fprintf(output, ".%s(%s)\n", methodFunction(entry.method), entry.handler)
+		case *routeBlock:
+			if len(entry.entries) == 0 {
+				continue
+			}
+			*groups++
+			group := "group" + strconv.Itoa(*groups)
+			fmt.Fprintf(output, "%s := %s.Group(%s)", group, receiver, strconv.Quote(entry.prefix))
+			if len(entry.middlewares) > 0 {
+				fmt.Fprintf(output, ".Use(%s)", strings.Join(entry.middlewares, ", "))
+			}
+			output.WriteString("\n")
+			entry.generate(output, group, groups)
+		}
+	}
+}
+
+// methodFunction returns the PathBuilder method registering method, e.g.
+// "Get" for GET.
+func methodFunction(method string) string {
+	return method[:1] + strings.ToLower(method[1:])
+}
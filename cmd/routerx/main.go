@@ -0,0 +1,73 @@
+// Command routerx contains development tools for routerx applications.
+//
+// routerx gen reads a route definition file and writes the Go function that
+// registers the declared routes, keeping the route table of large APIs
+// declarative and easy to review in diffs:
+//
+//	//go:generate go run github.com/Mark-Bazylev/routerx/cmd/routerx gen -o routes_gen.go routes.routes
+//
+// A route definition file looks like this:
+//
+//	package api
+//	func RegisterRoutes
+//
+//	GET /health healthCheck
+//	group /api/v1 use=requireAuth
+//	    GET  /users       users.List
+//	    POST /users       users.Create
+//	    GET  /users/{id}  users.Show    name=UserShow
+//	    PUT  /users/{id}  users.Update  name=UserShow use=requireAdmin
+//	end
+//
+// Handlers and middlewares are Go expressions resolved in the package of the
+// generated file, which declares func RegisterRoutes(router *routerx.Router).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "gen" {
+		fmt.Fprintln(os.Stderr, "usage: routerx gen [-o output.go] routes.routes")
+		os.Exit(2)
+	}
+	if err := gen(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "routerx gen:", err)
+		os.Exit(1)
+	}
+}
+
+func gen(arguments []string) error {
+	flags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	output := flags.String("o", "", "output file; defaults to the input name with a _gen.go suffix")
+	if err := flags.Parse(arguments); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected exactly one route definition file")
+	}
+	input := flags.Arg(0)
+	if *output == "" {
+		*output = strings.TrimSuffix(input, filepath.Ext(input)) + "_gen.go"
+	}
+
+	file, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	routes, err := parseRouteFile(input, file)
+	if err != nil {
+		return err
+	}
+	source, err := routes.generate(filepath.Base(input))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*output, source, 0o644)
+}
@@ -0,0 +1,332 @@
+package routerx
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Compressor compresses response bodies with one content coding. The
+// standard library only implements gzip and deflate, which Compress
+// provides; Brotli or Zstandard are added with RegisterCompressor, typically
+// as thin wrappers around a Brotli or Zstandard package.
+type Compressor interface {
+	// Encoding returns the content coding token, e.g. "br".
+	Encoding() string
+
+	// NewWriter returns a writer compressing into destination at level,
+	// which follows compress/flate: from flate.BestSpeed to
+	// flate.BestCompression, or flate.DefaultCompression. Writers should
+	// implement Flush() error to support streaming responses.
+	NewWriter(destination io.Writer, level int) (io.WriteCloser, error)
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Encoding() string { return "gzip" }
+
+func (gzipCompressor) NewWriter(destination io.Writer, level int) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(destination, level)
+}
+
+type deflateCompressor struct{}
+
+func (deflateCompressor) Encoding() string { return "deflate" }
+
+func (deflateCompressor) NewWriter(destination io.Writer, level int) (io.WriteCloser, error) {
+	return flate.NewWriter(destination, level)
+}
+
+var (
+	compressorsMutex sync.RWMutex
+	compressors      = []Compressor{gzipCompressor{}, deflateCompressor{}}
+)
+
+// RegisterCompressor adds a content coding to Compress, preferred over the
+// built-in gzip and deflate when the client accepts it. Registering an
+// encoding again replaces it.
+//
+// Example:
+//
+//	routerx.RegisterCompressor(brotliCompressor{})
+//	router := routerx.New().Use(routerx.Compress(5))
+func RegisterCompressor(compressor Compressor) {
+	compressorsMutex.Lock()
+	defer compressorsMutex.Unlock()
+	registered := []Compressor{compressor}
+	for _, existing := range compressors {
+		if existing.Encoding() != compressor.Encoding() {
+			registered = append(registered, existing)
+		}
+	}
+	compressors = registered
+}
+
+// defaultCompressibleTypes are the media types compressed when Compress is
+// given none.
+var defaultCompressibleTypes = []string{
+	"text/*",
+	"application/json",
+	"application/*+json",
+	"application/javascript",
+	"application/xml",
+	"application/*+xml",
+	"application/x-ndjson",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// incompressibleTypes are never compressed, since they are compressed
+// already.
+var incompressibleTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/*", "audio/*", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/pdf",
+}
+
+// compressMinSize is the body size below which responses are sent
+// uncompressed, since the coding overhead outweighs the savings.
+const compressMinSize = 1024
+
+// Compress returns a Middleware that compresses responses of the given
+// content types, or of common text formats when none are given, with the
+// best coding the client accepts in Accept-Encoding: a coding added with
+// RegisterCompressor, gzip, or deflate. level follows compress/flate.
+// Content types may use wildcards such as "text/*". Responses that already
+// have a Content-Encoding, media that is compressed already such as images
+// and archives, partial content, and bodies under 1 KiB are sent as they
+// are. Accept-Encoding is added to Vary, strong ETags are weakened on
+// compressed responses, and the wrapped ResponseWriter keeps supporting
+// flushing, which compresses what was written so far, and hijacking.
+//
+// Example:
+//
+//	router := routerx.New().Use(routerx.Compress(flate.DefaultCompression))
+//	assets.Use(routerx.Compress(flate.BestCompression, "text/css", "application/javascript"))
+func Compress(level int, contentTypes ...string) Middleware {
+	if len(contentTypes) == 0 {
+		contentTypes = defaultCompressibleTypes
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			compressor := negotiateCompressor(request.Header.Get("Accept-Encoding"))
+			if compressor == nil || request.Method == http.MethodHead {
+				addVary(responseWriter.Header(), "Accept-Encoding")
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			writer := &compressWriter{
+				ResponseWriter: responseWriter,
+				compressor:     compressor,
+				level:          level,
+				contentTypes:   contentTypes,
+			}
+			defer writer.close()
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// negotiateCompressor returns the registered compressor with the highest
+// quality in an Accept-Encoding header, preferring the registration order on
+// ties, or nil.
+func negotiateCompressor(acceptEncoding string) Compressor {
+	qualities := make(map[string]float64)
+	for _, member := range strings.Split(acceptEncoding, ",") {
+		coding, parameters, _ := strings.Cut(strings.TrimSpace(member), ";")
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(parameters), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		qualities[strings.ToLower(strings.TrimSpace(coding))] = quality
+	}
+
+	compressorsMutex.RLock()
+	defer compressorsMutex.RUnlock()
+	var best Compressor
+	bestQuality := 0.0
+	for _, compressor := range compressors {
+		quality, found := qualities[compressor.Encoding()]
+		if !found {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = compressor, quality
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it knows whether to
+// compress it, then streams it through the compressor or unchanged.
+type compressWriter struct {
+	http.ResponseWriter
+	compressor   Compressor
+	level        int
+	contentTypes []string
+
+	statusCode int
+	buffer     []byte
+	decided    bool
+	encoder    io.WriteCloser
+}
+
+func (writer *compressWriter) WriteHeader(statusCode int) {
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		writer.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if writer.statusCode != 0 {
+		return
+	}
+	writer.statusCode = statusCode
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified ||
+		statusCode == http.StatusPartialContent || statusCode == http.StatusSwitchingProtocols {
+		writer.decide(false)
+	}
+}
+
+func (writer *compressWriter) Write(data []byte) (int, error) {
+	if writer.statusCode == 0 {
+		writer.statusCode = http.StatusOK
+	}
+	if !writer.decided {
+		writer.buffer = append(writer.buffer, data...)
+		if len(writer.buffer) < compressMinSize {
+			return len(data), nil
+		}
+		if err := writer.decide(writer.compressible()); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if writer.encoder != nil {
+		return writer.encoder.Write(data)
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+// compressible reports whether the response qualifies for compression,
+// sniffing its content type from the buffered body if unset.
+func (writer *compressWriter) compressible() bool {
+	header := writer.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" && len(writer.buffer) > 0 {
+		contentType = http.DetectContentType(writer.buffer)
+		header.Set("Content-Type", contentType)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return !matchesMediaType(incompressibleTypes, mediaType) && matchesMediaType(writer.contentTypes, mediaType)
+}
+
+// decide writes the response headers, compressed or not, and the buffered
+// body.
+func (writer *compressWriter) decide(compress bool) error {
+	writer.decided = true
+	header := writer.Header()
+	addVary(header, "Accept-Encoding")
+	if compress {
+		encoder, err := writer.compressor.NewWriter(writer.ResponseWriter, writer.level)
+		if err != nil {
+			compress = false
+		} else {
+			writer.encoder = encoder
+			header.Set("Content-Encoding", writer.compressor.Encoding())
+			header.Del("Content-Length")
+			if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				header.Set("ETag", "W/"+etag)
+			}
+		}
+	}
+	if writer.statusCode == 0 {
+		writer.statusCode = http.StatusOK
+	}
+	writer.ResponseWriter.WriteHeader(writer.statusCode)
+	buffered := writer.buffer
+	writer.buffer = nil
+	if len(buffered) == 0 {
+		return nil
+	}
+	var err error
+	if writer.encoder != nil {
+		_, err = writer.encoder.Write(buffered)
+	} else {
+		_, err = writer.ResponseWriter.Write(buffered)
+	}
+	return err
+}
+
+// Flush sends what was written so far, compressing it if the response
+// qualifies regardless of its size, since streamed responses are rarely
+// small in total.
+func (writer *compressWriter) Flush() {
+	if !writer.decided {
+		if err := writer.decide(writer.compressible()); err != nil {
+			return
+		}
+	}
+	if flusher, ok := writer.encoder.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	_ = http.NewResponseController(writer.ResponseWriter).Flush()
+}
+
+// Hijack lets protocols such as WebSocket take over the connection.
+func (writer *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	writer.decided = true
+	return http.NewResponseController(writer.ResponseWriter).Hijack()
+}
+
+func (writer *compressWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// close finishes the response once the handler returned.
+func (writer *compressWriter) close() {
+	if !writer.decided {
+		if writer.statusCode == 0 && len(writer.buffer) == 0 {
+			return
+		}
+		_ = writer.decide(false)
+	}
+	if writer.encoder != nil {
+		_ = writer.encoder.Close()
+	}
+}
+
+// matchesMediaType reports whether mediaType matches one of patterns, which
+// may use "*" for the subtype or a "*+suffix" structured syntax suffix.
+func matchesMediaType(patterns []string, mediaType string) bool {
+	mainType, subtype, _ := strings.Cut(mediaType, "/")
+	for _, pattern := range patterns {
+		patternType, patternSubtype, _ := strings.Cut(strings.ToLower(pattern), "/")
+		if patternType != mainType && patternType != "*" {
+			continue
+		}
+		if patternSubtype == "*" || patternSubtype == subtype {
+			return true
+		}
+		if suffix, found := strings.CutPrefix(patternSubtype, "*"); found && strings.HasSuffix(subtype, suffix) {
+			return true
+		}
+	}
+	return false
+}
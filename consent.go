@@ -0,0 +1,91 @@
+package routerx
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ConsentChecker reports whether a principal has accepted a given version of
+// the terms of service or another consent document. Implementations usually
+// look the answer up in a database and must be safe for concurrent use.
+type ConsentChecker interface {
+	Accepted(ctx context.Context, principal *Principal, version string) (bool, error)
+}
+
+// ConsentCheckerFunc adapts an ordinary function to the ConsentChecker
+// interface.
+type ConsentCheckerFunc func(ctx context.Context, principal *Principal, version string) (bool, error)
+
+// Accepted calls checkerFunc(ctx, principal, version).
+func (checkerFunc ConsentCheckerFunc) Accepted(ctx context.Context, principal *Principal, version string) (bool, error) {
+	return checkerFunc(ctx, principal, version)
+}
+
+// ConsentConfig configures the Consent middleware.
+type ConsentConfig struct {
+	// Checker decides whether the current principal accepted Version.
+	Checker ConsentChecker
+
+	// Version is the currently required consent version, e.g. "2024-06".
+	Version string
+
+	// AcceptURL points clients to the route where the consent can be
+	// accepted. It is returned in the response body and in a Link header,
+	// and requests to its path are never blocked.
+	AcceptURL string
+
+	// StatusCode is used for blocked requests. It defaults to
+	// http.StatusForbidden; http.StatusUnavailableForLegalReasons (451) is a
+	// common alternative.
+	StatusCode int
+}
+
+// Consent returns a Middleware that blocks authenticated principals who have
+// not accepted the required consent version. Anonymous requests are passed
+// through untouched so that the middleware composes with any authentication
+// scheme that sets the principal with SetPrincipal.
+//
+// Example:
+//
+//	app := router.Group("/app").Use(routerx.Consent(routerx.ConsentConfig{
+//	    Checker:   termsStore,
+//	    Version:   "2024-06",
+//	    AcceptURL: "/terms/accept",
+//	}))
+func Consent(config ConsentConfig) Middleware {
+	statusCode := config.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusForbidden
+	}
+	acceptPath := ""
+	if parsed, err := url.Parse(config.AcceptURL); err == nil {
+		acceptPath = parsed.Path
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			principal := GetPrincipal(request)
+			if principal == nil || (acceptPath != "" && request.URL.Path == acceptPath) {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			accepted, err := config.Checker.Accepted(request.Context(), principal, config.Version)
+			if err != nil {
+				writeError(responseWriter, http.StatusInternalServerError, "consent check failed")
+				return
+			}
+			if accepted {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			if config.AcceptURL != "" {
+				responseWriter.Header().Add("Link", "<"+config.AcceptURL+`>; rel="terms-of-service"`)
+			}
+			writeJSON(responseWriter, statusCode, map[string]string{
+				"error":           "consent required",
+				"consent_version": config.Version,
+				"accept_url":      config.AcceptURL,
+			})
+		})
+	}
+}
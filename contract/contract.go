@@ -0,0 +1,646 @@
+// Package contract verifies consumer-driven contracts against a routerx
+// router, or any http.Handler, without starting a server: the interactions of
+// Pact files, version 2 or 3, are replayed in process and their responses
+// compared with the expectations of the consumers.
+//
+//	func TestContracts(t *testing.T) {
+//	    pacts, err := contract.Load("testdata/pacts")
+//	    if err != nil {
+//	        t.Fatal(err)
+//	    }
+//	    report := contract.Verify(newRouter(), pacts, contract.Config{
+//	        States: map[string]contract.StateFunc{
+//	            "user 42 exists": func(ctx context.Context, params map[string]any) error {
+//	                return store.Put(ctx, User{ID: 42, Name: "Ann"})
+//	            },
+//	        },
+//	    })
+//	    if err := report.Err(); err != nil {
+//	        t.Fatal(err)
+//	    }
+//	}
+//
+// As in Pact, responses may contain more than the consumers expect: object
+// keys and headers the contract does not mention are ignored, while arrays
+// must have the expected length unless a type matcher relaxes them.
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Pact is a contract between a consumer and a provider.
+type Pact struct {
+	Consumer     Pacticipant   `json:"consumer"`
+	Provider     Pacticipant   `json:"provider"`
+	Interactions []Interaction `json:"interactions"`
+
+	// File is the file the contract was loaded from.
+	File string `json:"-"`
+}
+
+// Pacticipant names a party of a Pact.
+type Pacticipant struct {
+	Name string `json:"name"`
+}
+
+// Interaction is a request of the consumer and the response it expects.
+type Interaction struct {
+	Description string `json:"description"`
+
+	// ProviderState is the state of version 2 contracts.
+	ProviderState string `json:"providerState,omitempty"`
+
+	// ProviderStates are the states of version 3 contracts.
+	ProviderStates []ProviderState `json:"providerStates,omitempty"`
+
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// ProviderState is a state the provider must be in before an interaction,
+// such as "user 42 exists".
+type ProviderState struct {
+	Name   string         `json:"name"`
+	Params map[string]any `json:"params,omitempty"`
+}
+
+// Request is the request of an Interaction. Query is a string in version 2
+// contracts and an object of string arrays in version 3.
+type Request struct {
+	Method  string          `json:"method"`
+	Path    string          `json:"path"`
+	Query   any             `json:"query,omitempty"`
+	Headers map[string]any  `json:"headers,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+// Response is the response the consumer expects.
+type Response struct {
+	Status  int             `json:"status"`
+	Headers map[string]any  `json:"headers,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+
+	// MatchingRules relax the comparison, e.g. to the type of a value or a
+	// regular expression, in the version 2 or 3 layout.
+	MatchingRules map[string]any `json:"matchingRules,omitempty"`
+}
+
+// StateFunc puts the provider in a provider state, e.g. by seeding a test
+// database.
+type StateFunc func(ctx context.Context, params map[string]any) error
+
+// Config configures Verify.
+type Config struct {
+	// States sets up the provider states named by the interactions.
+	// Interactions naming an unknown state fail.
+	States map[string]StateFunc
+
+	// Context is the context of the replayed requests and of the state
+	// functions. Defaults to context.Background().
+	Context context.Context
+}
+
+// Report is the outcome of Verify.
+type Report struct {
+	Results []Result
+}
+
+// Result is the outcome of an interaction.
+type Result struct {
+	Consumer    string
+	Provider    string
+	Description string
+	File        string
+	Mismatches  []Mismatch
+}
+
+// Mismatch is a difference between the expected and the actual response.
+type Mismatch struct {
+	// Path locates the difference: "status", "header.<name>", or a JSON
+	// path into the body such as "$.items[0].id".
+	Path    string
+	Message string
+}
+
+// Load reads the Pact files at paths; directories are searched for .json
+// files.
+func Load(paths ...string) ([]*Pact, error) {
+	var pacts []*Pact
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		files := []string{path}
+		if info.IsDir() {
+			if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+				return nil, err
+			}
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			pact := &Pact{File: file}
+			if err := json.Unmarshal(data, pact); err != nil {
+				return nil, fmt.Errorf("contract: %s: %w", file, err)
+			}
+			pacts = append(pacts, pact)
+		}
+	}
+	return pacts, nil
+}
+
+// Verify replays every interaction of pacts against handler, after setting
+// up its provider states, and reports how the responses differ from the
+// expectations.
+func Verify(handler http.Handler, pacts []*Pact, config Config) Report {
+	if config.Context == nil {
+		config.Context = context.Background()
+	}
+	var report Report
+	for _, pact := range pacts {
+		for _, interaction := range pact.Interactions {
+			result := Result{
+				Consumer:    pact.Consumer.Name,
+				Provider:    pact.Provider.Name,
+				Description: interaction.Description,
+				File:        pact.File,
+			}
+			result.Mismatches = verifyInteraction(handler, interaction, config)
+			report.Results = append(report.Results, result)
+		}
+	}
+	return report
+}
+
+// OK reports whether every interaction was verified.
+func (report Report) OK() bool {
+	return report.Err() == nil
+}
+
+// Err returns an error listing the failed interactions and their
+// mismatches, or nil.
+func (report Report) Err() error {
+	var failures []string
+	for _, result := range report.Results {
+		if len(result.Mismatches) == 0 {
+			continue
+		}
+		lines := []string{fmt.Sprintf("%s → %s: %s", result.Consumer, result.Provider, result.Description)}
+		for _, mismatch := range result.Mismatches {
+			lines = append(lines, fmt.Sprintf("    %s: %s", mismatch.Path, mismatch.Message))
+		}
+		failures = append(failures, strings.Join(lines, "\n"))
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return errors.New("contract: " + strconv.Itoa(len(failures)) + " interactions failed:\n" + strings.Join(failures, "\n"))
+}
+
+func verifyInteraction(handler http.Handler, interaction Interaction, config Config) []Mismatch {
+	states := interaction.ProviderStates
+	if interaction.ProviderState != "" {
+		states = append(states, ProviderState{Name: interaction.ProviderState})
+	}
+	for _, state := range states {
+		setup, found := config.States[state.Name]
+		if !found {
+			return []Mismatch{{Path: "state", Message: fmt.Sprintf("no setup for provider state %q", state.Name)}}
+		}
+		if err := setup(config.Context, state.Params); err != nil {
+			return []Mismatch{{Path: "state", Message: fmt.Sprintf("provider state %q: %v", state.Name, err)}}
+		}
+	}
+
+	request := buildRequest(config.Context, interaction.Request)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	expected := interaction.Response
+	rules := parseRules(expected.MatchingRules)
+	var mismatches []Mismatch
+	if expected.Status != 0 && recorder.Code != expected.Status {
+		mismatches = append(mismatches, Mismatch{Path: "status", Message: fmt.Sprintf("expected %d, got %d", expected.Status, recorder.Code)})
+	}
+	for name, value := range expected.Headers {
+		want := headerValue(value)
+		got := strings.Join(recorder.Header().Values(name), ", ")
+		path := "header." + name
+		if matchers := rules.find("header", name); len(matchers) > 0 {
+			if message := applyMatchers(matchers, want, got); message != "" {
+				mismatches = append(mismatches, Mismatch{Path: path, Message: message})
+			}
+			continue
+		}
+		if !headerMatches(name, want, got) {
+			mismatches = append(mismatches, Mismatch{Path: path, Message: fmt.Sprintf("expected %q, got %q", want, got)})
+		}
+	}
+	if len(expected.Body) > 0 && string(expected.Body) != "null" {
+		var want, got any
+		if err := json.Unmarshal(expected.Body, &want); err != nil {
+			return append(mismatches, Mismatch{Path: "$", Message: "invalid expected body: " + err.Error()})
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+			if text, isText := want.(string); isText && text == recorder.Body.String() {
+				return mismatches
+			}
+			return append(mismatches, Mismatch{Path: "$", Message: "response body is not the expected JSON: " + err.Error()})
+		}
+		mismatches = compareBody(mismatches, rules, "$", want, got)
+	}
+	return mismatches
+}
+
+// buildRequest builds the request of an interaction.
+func buildRequest(ctx context.Context, expected Request) *http.Request {
+	target := expected.Path
+	switch query := expected.Query.(type) {
+	case string:
+		if query != "" {
+			target += "?" + query
+		}
+	case map[string]any:
+		values := url.Values{}
+		for name, value := range query {
+			switch typed := value.(type) {
+			case []any:
+				for _, item := range typed {
+					values.Add(name, fmt.Sprint(item))
+				}
+			default:
+				values.Add(name, fmt.Sprint(typed))
+			}
+		}
+		target += "?" + values.Encode()
+	}
+
+	var body string
+	if len(expected.Body) > 0 && string(expected.Body) != "null" {
+		body = string(expected.Body)
+	}
+	header := http.Header{}
+	for name, value := range expected.Headers {
+		header.Set(name, headerValue(value))
+	}
+	if body != "" && !strings.Contains(header.Get("Content-Type"), "json") {
+		var text string
+		if json.Unmarshal(expected.Body, &text) == nil {
+			body = text
+		}
+	}
+	if body != "" && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json")
+	}
+	method := expected.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	request := httptest.NewRequestWithContext(ctx, strings.ToUpper(method), target, strings.NewReader(body))
+	request.Header = header
+	return request
+}
+
+// compareBody appends the differences between the expected and the actual
+// JSON value at path.
+func compareBody(mismatches []Mismatch, rules matchingRules, path string, want any, got any) []Mismatch {
+	matchers := rules.find("body", path)
+	if len(matchers) > 0 {
+		if wantArray, isArray := want.([]any); isArray && typeMatching(matchers) {
+			gotArray, ok := got.([]any)
+			if !ok {
+				return append(mismatches, Mismatch{Path: path, Message: fmt.Sprintf("expected an array, got %s", jsonType(got))})
+			}
+			if message := arrayBounds(matchers, len(gotArray)); message != "" {
+				mismatches = append(mismatches, Mismatch{Path: path, Message: message})
+			}
+			if len(wantArray) > 0 {
+				for index, item := range gotArray {
+					mismatches = compareTyped(mismatches, rules, path+"["+strconv.Itoa(index)+"]", wantArray[0], item)
+				}
+			}
+			return mismatches
+		}
+		if _, isObject := want.(map[string]any); !isObject {
+			if message := applyMatchers(matchers, want, got); message != "" {
+				mismatches = append(mismatches, Mismatch{Path: path, Message: message})
+			}
+			return mismatches
+		}
+	}
+
+	switch typed := want.(type) {
+	case map[string]any:
+		object, ok := got.(map[string]any)
+		if !ok {
+			return append(mismatches, Mismatch{Path: path, Message: fmt.Sprintf("expected an object, got %s", jsonType(got))})
+		}
+		for _, key := range sortedKeys(typed) {
+			actual, found := object[key]
+			if !found {
+				mismatches = append(mismatches, Mismatch{Path: path + "." + key, Message: "missing"})
+				continue
+			}
+			mismatches = compareBody(mismatches, rules, path+"."+key, typed[key], actual)
+		}
+	case []any:
+		array, ok := got.([]any)
+		if !ok {
+			return append(mismatches, Mismatch{Path: path, Message: fmt.Sprintf("expected an array, got %s", jsonType(got))})
+		}
+		if len(array) != len(typed) {
+			return append(mismatches, Mismatch{Path: path, Message: fmt.Sprintf("expected %d items, got %d", len(typed), len(array))})
+		}
+		for index := range typed {
+			mismatches = compareBody(mismatches, rules, path+"["+strconv.Itoa(index)+"]", typed[index], array[index])
+		}
+	default:
+		if !reflect.DeepEqual(want, got) {
+			mismatches = append(mismatches, Mismatch{Path: path, Message: fmt.Sprintf("expected %s, got %s", encode(want), encode(got))})
+		}
+	}
+	return mismatches
+}
+
+// compareTyped compares values by type below a type matcher, where only
+// the shape matters, unless a more specific rule applies.
+func compareTyped(mismatches []Mismatch, rules matchingRules, path string, want any, got any) []Mismatch {
+	if len(rules.find("body", path)) > 0 {
+		return compareBody(mismatches, rules, path, want, got)
+	}
+	if jsonType(want) != jsonType(got) {
+		return append(mismatches, Mismatch{Path: path, Message: fmt.Sprintf("expected %s, got %s", jsonType(want), jsonType(got))})
+	}
+	switch typed := want.(type) {
+	case map[string]any:
+		object := got.(map[string]any)
+		for _, key := range sortedKeys(typed) {
+			actual, found := object[key]
+			if !found {
+				mismatches = append(mismatches, Mismatch{Path: path + "." + key, Message: "missing"})
+				continue
+			}
+			mismatches = compareTyped(mismatches, rules, path+"."+key, typed[key], actual)
+		}
+	case []any:
+		if len(typed) > 0 {
+			for index, item := range got.([]any) {
+				mismatches = compareTyped(mismatches, rules, path+"["+strconv.Itoa(index)+"]", typed[0], item)
+			}
+		}
+	}
+	return mismatches
+}
+
+// matcher is a matching rule of a contract.
+type matcher struct {
+	Match string `json:"match"`
+	Regex string `json:"regex"`
+	Min   *int   `json:"min"`
+	Max   *int   `json:"max"`
+}
+
+// matchingRules holds the rules of a response by category, "body" or
+// "header", and path pattern.
+type matchingRules map[string][]pathRule
+
+type pathRule struct {
+	path     string
+	pattern  *regexp.Regexp
+	matchers []matcher
+}
+
+// parseRules normalizes version 2 rules, keyed by "$.body.x" or
+// "$.headers.Name", and version 3 rules, keyed by category and then path.
+func parseRules(raw map[string]any) matchingRules {
+	rules := make(matchingRules)
+	add := func(category string, path string, value any) {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return
+		}
+		var matchers []matcher
+		var wrapped struct {
+			Matchers []matcher `json:"matchers"`
+		}
+		if json.Unmarshal(data, &wrapped) == nil && len(wrapped.Matchers) > 0 {
+			matchers = wrapped.Matchers
+		} else {
+			var single matcher
+			if json.Unmarshal(data, &single) != nil {
+				return
+			}
+			matchers = []matcher{single}
+		}
+		rules[category] = append(rules[category], pathRule{path: path, pattern: rulePattern(category, path), matchers: matchers})
+	}
+	for key, value := range raw {
+		switch {
+		case key == "$.body" || strings.HasPrefix(key, "$.body.") || strings.HasPrefix(key, "$.body["):
+			add("body", "$"+strings.TrimPrefix(key, "$.body"), value)
+		case strings.HasPrefix(key, "$.headers."):
+			add("header", strings.TrimPrefix(key, "$.headers."), value)
+		case key == "body" || key == "header":
+			paths, _ := value.(map[string]any)
+			for path, rule := range paths {
+				add(key, path, rule)
+			}
+		}
+	}
+	// Specific paths take precedence over wildcards.
+	for _, categoryRules := range rules {
+		slices.SortFunc(categoryRules, func(left, right pathRule) int {
+			if wildcards := strings.Count(left.path, "*") - strings.Count(right.path, "*"); wildcards != 0 {
+				return wildcards
+			}
+			return len(right.path) - len(left.path)
+		})
+	}
+	return rules
+}
+
+// rulePattern compiles a rule path, where "[*]" matches any index and "*"
+// any key.
+func rulePattern(category string, path string) *regexp.Regexp {
+	if category == "header" {
+		return regexp.MustCompile("(?i)^" + regexp.QuoteMeta(path) + "$")
+	}
+	if !strings.HasPrefix(path, "$") {
+		path = "$." + path
+	}
+	expression := regexp.QuoteMeta(path)
+	expression = strings.ReplaceAll(expression, `\[\*\]`, `\[\d+\]`)
+	expression = strings.ReplaceAll(expression, `\.\*`, `\.[^.\[]+`)
+	return regexp.MustCompile("^" + expression + "$")
+}
+
+// find returns the matchers of the first rule whose pattern matches path.
+func (rules matchingRules) find(category string, path string) []matcher {
+	for _, rule := range rules[category] {
+		if rule.pattern.MatchString(path) {
+			return rule.matchers
+		}
+	}
+	return nil
+}
+
+// applyMatchers checks a value against matchers and returns why it does not
+// match, or "".
+func applyMatchers(matchers []matcher, want any, got any) string {
+	for _, rule := range matchers {
+		switch {
+		case rule.Regex != "" || rule.Match == "regex":
+			expression, err := regexp.Compile("^(?:" + rule.Regex + ")$")
+			if err != nil {
+				return "invalid regex " + rule.Regex
+			}
+			text, isString := got.(string)
+			if !isString {
+				text = encode(got)
+			}
+			if !expression.MatchString(text) {
+				return fmt.Sprintf("%s does not match %s", encode(got), rule.Regex)
+			}
+		case rule.Match == "type":
+			if jsonType(want) != jsonType(got) {
+				return fmt.Sprintf("expected %s, got %s", jsonType(want), jsonType(got))
+			}
+		case rule.Match == "integer":
+			if number, ok := got.(float64); !ok || number != float64(int64(number)) {
+				return fmt.Sprintf("expected an integer, got %s", encode(got))
+			}
+		case rule.Match == "decimal" || rule.Match == "number":
+			if _, ok := got.(float64); !ok {
+				return fmt.Sprintf("expected a number, got %s", encode(got))
+			}
+		case rule.Match == "include":
+			if !strings.Contains(fmt.Sprint(got), fmt.Sprint(want)) {
+				return fmt.Sprintf("%s does not include %s", encode(got), encode(want))
+			}
+		case rule.Match == "equality" || rule.Match == "":
+			if !reflect.DeepEqual(want, got) {
+				return fmt.Sprintf("expected %s, got %s", encode(want), encode(got))
+			}
+		default:
+			return "unsupported matcher " + rule.Match
+		}
+	}
+	return ""
+}
+
+// typeMatching reports whether matchers compare by type, which also
+// applies min and max to arrays.
+func typeMatching(matchers []matcher) bool {
+	return slices.ContainsFunc(matchers, func(rule matcher) bool {
+		return rule.Match == "type" || rule.Min != nil || rule.Max != nil
+	})
+}
+
+// arrayBounds checks the length of an array against min and max.
+func arrayBounds(matchers []matcher, length int) string {
+	for _, rule := range matchers {
+		if rule.Min != nil && length < *rule.Min {
+			return fmt.Sprintf("expected at least %d items, got %d", *rule.Min, length)
+		}
+		if rule.Max != nil && length > *rule.Max {
+			return fmt.Sprintf("expected at most %d items, got %d", *rule.Max, length)
+		}
+	}
+	return ""
+}
+
+// headerValue returns the value of a contract header, a string or, in
+// newer contracts, an array of strings.
+func headerValue(value any) string {
+	if values, ok := value.([]any); ok {
+		parts := make([]string, len(values))
+		for index, part := range values {
+			parts[index] = fmt.Sprint(part)
+		}
+		return strings.Join(parts, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
+// headerMatches compares header values. Content types match when the media
+// types are equal and the actual value has the expected parameters, so that
+// "application/json" matches "application/json; charset=utf-8".
+func headerMatches(name string, want string, got string) bool {
+	if strings.EqualFold(name, "Content-Type") {
+		wantType, wantParams, wantErr := mime.ParseMediaType(want)
+		gotType, gotParams, gotErr := mime.ParseMediaType(got)
+		if wantErr == nil && gotErr == nil {
+			if wantType != gotType {
+				return false
+			}
+			for key, value := range wantParams {
+				if !strings.EqualFold(gotParams[key], value) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return normalizeHeader(got) == normalizeHeader(want)
+}
+
+// normalizeHeader removes the optional whitespace of a header value.
+func normalizeHeader(value string) string {
+	parts := strings.Split(value, ",")
+	for index, part := range parts {
+		parts[index] = strings.Join(strings.Fields(part), "")
+	}
+	return strings.Join(parts, ",")
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func encode(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
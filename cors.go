@@ -0,0 +1,236 @@
+package routerx
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to make cross-origin
+	// requests. "*" allows any origin, and a single "*" inside an entry
+	// matches any sequence of characters, e.g. "https://*.example.com".
+	AllowedOrigins []string
+
+	// AllowOriginFunc decides about origins not listed in AllowedOrigins.
+	AllowOriginFunc func(origin string, request *http.Request) bool
+
+	// AllowedMethods lists the methods allowed in preflight requests.
+	// Defaults to GET, HEAD, POST, PUT, PATCH, and DELETE.
+	AllowedMethods []string
+
+	// AllowedHeaders lists the request headers allowed in preflight
+	// requests. "*" allows any header. Defaults to Accept, Accept-Language,
+	// Authorization, Content-Language, and Content-Type.
+	AllowedHeaders []string
+
+	// ExposedHeaders lists the response headers readable by scripts.
+	ExposedHeaders []string
+
+	// AllowCredentials allows cookies and HTTP authentication. It requires
+	// AllowedOrigins to list the trusted origins explicitly, or
+	// AllowOriginFunc to decide about them, since credentials must never be
+	// shared with any origin. The allowed origin is echoed instead of
+	// answering "*".
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response. Zero
+	// leaves the browser default.
+	MaxAge time.Duration
+}
+
+// CORS returns a Middleware implementing Cross-Origin Resource Sharing. It
+// answers preflight requests (OPTIONS with Access-Control-Request-Method)
+// itself with 204 No Content and adds the CORS response headers to actual
+// requests from allowed origins. Requests from other origins are passed on
+// without CORS headers, so browsers block them.
+//
+// A middleware only runs for matched routes, so a preflight for a path that
+// has no OPTIONS route never reaches it. Prefer Router.CORS and
+// RouteGroup.CORS, which also register the preflight routes.
+//
+// CORS panics when AllowCredentials is combined with the "*" origin or with
+// neither AllowedOrigins nor AllowOriginFunc.
+func CORS(config CORSConfig) Middleware {
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = []string{
+			http.MethodGet, http.MethodHead, http.MethodPost,
+			http.MethodPut, http.MethodPatch, http.MethodDelete,
+		}
+	}
+	if len(config.AllowedHeaders) == 0 {
+		config.AllowedHeaders = []string{"Accept", "Accept-Language", "Authorization", "Content-Language", "Content-Type"}
+	}
+	anyOrigin := slices.Contains(config.AllowedOrigins, "*")
+	if config.AllowCredentials {
+		if anyOrigin {
+			panic("routerx: CORS AllowCredentials cannot be combined with the \"*\" origin; list the trusted origins or use AllowOriginFunc")
+		}
+		if len(config.AllowedOrigins) == 0 && config.AllowOriginFunc == nil {
+			panic("routerx: CORS AllowCredentials requires AllowedOrigins or AllowOriginFunc")
+		}
+	}
+	anyHeader := slices.Contains(config.AllowedHeaders, "*")
+	allowedMethods := strings.Join(config.AllowedMethods, ", ")
+	exposedHeaders := strings.Join(config.ExposedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			header := responseWriter.Header()
+			origin := request.Header.Get("Origin")
+			preflight := request.Method == http.MethodOptions && request.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				addVary(header, "Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers")
+			} else {
+				addVary(header, "Origin")
+			}
+			if origin == "" || !config.allowsOrigin(origin, anyOrigin, request) {
+				if preflight {
+					responseWriter.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+
+			if anyOrigin {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if config.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if exposedHeaders != "" {
+					header.Set("Access-Control-Expose-Headers", exposedHeaders)
+				}
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+
+			method := request.Header.Get("Access-Control-Request-Method")
+			requestedHeaders := parseHeaderList(request.Header.Get("Access-Control-Request-Headers"))
+			if !slices.Contains(config.AllowedMethods, method) || !config.allowsHeaders(requestedHeaders, anyHeader) {
+				header.Del("Access-Control-Allow-Origin")
+				header.Del("Access-Control-Allow-Credentials")
+				responseWriter.WriteHeader(http.StatusNoContent)
+				return
+			}
+			header.Set("Access-Control-Allow-Methods", allowedMethods)
+			if len(requestedHeaders) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+			}
+			if config.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(config.MaxAge.Seconds())))
+			}
+			responseWriter.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// allowsOrigin reports whether origin may make cross-origin requests.
+func (config CORSConfig) allowsOrigin(origin string, anyOrigin bool, request *http.Request) bool {
+	if anyOrigin {
+		return true
+	}
+	for _, allowed := range config.AllowedOrigins {
+		prefix, suffix, wildcard := strings.Cut(allowed, "*")
+		if !wildcard && strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if wildcard && len(origin) >= len(prefix)+len(suffix) &&
+			strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+			strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+			return true
+		}
+	}
+	return config.AllowOriginFunc != nil && config.AllowOriginFunc(origin, request)
+}
+
+// allowsHeaders reports whether every requested header is allowed.
+func (config CORSConfig) allowsHeaders(requested []string, anyHeader bool) bool {
+	if anyHeader {
+		return true
+	}
+	for _, name := range requested {
+		if !slices.ContainsFunc(config.AllowedHeaders, func(allowed string) bool { return strings.EqualFold(allowed, name) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHeaderList splits a comma separated list of header names.
+func parseHeaderList(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, http.CanonicalHeaderKey(name))
+		}
+	}
+	return names
+}
+
+// CORS enables Cross-Origin Resource Sharing for the routes registered on the
+// router, its groups, and its path builders after calling CORS. The CORS
+// middleware wraps those routes outside of all other middlewares, so error
+// responses written by authentication or rate limiting middlewares still
+// carry CORS headers. A preflight OPTIONS route is registered for every path
+// that has no OPTIONS route yet; it only runs the CORS middleware, since
+// browsers send preflights without credentials, and a later explicit Options
+// registration replaces it. CORS returns the Router to support chaining.
+//
+// Example:
+//
+//	router := routerx.New().CORS(routerx.CORSConfig{
+//	    AllowedOrigins:   []string{"https://app.example.com", "https://*.preview.example.com"},
+//	    AllowCredentials: true,
+//	    MaxAge:           10 * time.Minute,
+//	})
+func (router *Router) CORS(config CORSConfig) *Router {
+	router.cors = CORS(config)
+	return router
+}
+
+// CORS enables Cross-Origin Resource Sharing for the routes registered on the
+// group and its nested groups after calling CORS, replacing the configuration
+// inherited from the router or a parent group. See Router.CORS.
+//
+// Example:
+//
+//	public := router.Group("/public").CORS(routerx.CORSConfig{AllowedOrigins: []string{"*"}})
+//	public.Get("/status", statusHandler) // OPTIONS /public/status is registered as well
+func (group *RouteGroup) CORS(config CORSConfig) *RouteGroup {
+	group.cors = CORS(config)
+	return group
+}
+
+// withCORS puts cors in front of a middleware chain.
+func withCORS(cors Middleware, middlewares []Middleware) []Middleware {
+	if cors == nil {
+		return middlewares
+	}
+	return append([]Middleware{cors}, middlewares...)
+}
+
+// registerPreflight registers the OPTIONS route answering CORS preflights for
+// path when cors is set and the path has no OPTIONS route yet.
+func (router *Router) registerPreflight(method string, path string, cors Middleware) {
+	if cors == nil || method == "" || method == http.MethodOptions {
+		return
+	}
+	muxPath, _, _ := parseRoutePath(path)
+	if _, found := router.shapes[http.MethodOptions+" "+routeShapeOf(muxPath)]; found {
+		return
+	}
+	preflight := buildRoute(http.MethodOptions, muxPath, http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusNoContent)
+	}), []Middleware{cors}, nil)
+	preflight.preflight = true
+	router.insert(preflight)
+}
@@ -0,0 +1,263 @@
+package routerx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProfileLabelsConfig configures ProfileLabels.
+type ProfileLabelsConfig struct {
+	// Labels returns additional label pairs for a request, e.g.
+	// "tenant", tenantID, appended to the route and method labels. Keep
+	// their values bounded like metric labels.
+	Labels func(request *http.Request) []string
+}
+
+// ProfileLabels returns a Middleware that runs every request under the
+// pprof labels "http.route", the matched pattern such as "/users/{id}", and
+// "http.method", so that CPU profiles, and goroutine profiles, taken by
+// Router.Pprof or by continuous profilers built on pprof can be sliced by
+// endpoint. Goroutines started by handlers inherit the labels. Go does not
+// label heap samples. Use it on the router so that the route pattern is
+// known.
+//
+// Example:
+//
+//	router := routerx.New().Use(routerx.ProfileLabels())
+//	// go tool pprof -tagfocus=http.route=/reports/{id} http://localhost:8080/debug/pprof/profile
+func ProfileLabels(configs ...ProfileLabelsConfig) Middleware {
+	var config ProfileLabelsConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			labels := []string{"http.route", metricsRoute(request.Pattern), "http.method", metricsMethod(request.Method)}
+			if config.Labels != nil {
+				labels = append(labels, config.Labels(request)...)
+			}
+			runtimepprof.Do(request.Context(), runtimepprof.Labels(labels...), func(ctx context.Context) {
+				next.ServeHTTP(responseWriter, request.WithContext(ctx))
+			})
+		})
+	}
+}
+
+// Pprof serves the runtime profiles under prefix in the format of
+// net/http/pprof, e.g. the heap profile at prefix/heap and the index page at
+// prefix/, so that go tool pprof reads them. The handlers are built on
+// runtime/pprof and runtime/trace rather than net/http/pprof, whose import
+// registers the profiles on http.DefaultServeMux; importing routerx serves
+// nothing by itself. The routes run the router's middlewares and are left
+// out of the OpenAPI document. Profiles expose internals of the process, so
+// mount them on a guarded group, see RouteGroup.Pprof.
+//
+// Example:
+//
+//	router.Pprof("/debug/pprof")
+//	// go tool pprof http://localhost:8080/debug/pprof/heap
+func (router *Router) Pprof(prefix string) {
+	router.pprof(cleanPath(prefix), router.middlewares)
+}
+
+// Pprof serves the runtime profiles under the group's prefix joined with
+// prefix, guarded by the group's middleware chain. See Router.Pprof.
+//
+// Example:
+//
+//	debug := router.Group("/debug").Use(routerx.BasicAuth(routerx.BasicAuthUsers(operators)))
+//	debug.Pprof("/pprof")
+//	debug.Expvar("/vars")
+func (group *RouteGroup) Pprof(prefix string) {
+	group.seal()
+	group.router.pprof(joinPath(group.prefix, prefix), group.middlewares)
+}
+
+// Expvar serves the command line and the memory statistics of the process
+// as JSON at path, in the format of the expvar package. Variables published
+// with package expvar are not included, since importing it registers
+// /debug/vars on http.DefaultServeMux; programs that use it serve
+// expvar.Handler() themselves. See Router.Pprof about exposing it.
+//
+// Example:
+//
+//	router.Expvar("/debug/vars")
+func (router *Router) Expvar(path string) {
+	router.register("GET", cleanPath(path), http.HandlerFunc(serveExpvar), router.middlewares, nil).undocumented = true
+}
+
+// Expvar serves the runtime variables at the group's prefix joined with
+// path, guarded by the group's middleware chain. See Router.Expvar.
+func (group *RouteGroup) Expvar(path string) {
+	group.seal()
+	group.router.register("GET", joinPath(group.prefix, path), http.HandlerFunc(serveExpvar), group.middlewares, nil).undocumented = true
+}
+
+func serveExpvar(responseWriter http.ResponseWriter, request *http.Request) {
+	var memstats runtime.MemStats
+	runtime.ReadMemStats(&memstats)
+	writeJSON(responseWriter, http.StatusOK, map[string]any{"cmdline": os.Args, "memstats": memstats})
+}
+
+func (router *Router) pprof(prefix string, middlewares []Middleware) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	profiles := pprofHandler()
+	router.register("GET", prefix+"/{profile...}", profiles, middlewares, nil).undocumented = true
+	// go tool pprof posts the addresses to symbolize.
+	router.register("POST", prefix+"/symbol", http.HandlerFunc(pprofSymbol), middlewares, nil).undocumented = true
+	if prefix != "" {
+		router.register("GET", prefix, http.RedirectHandler(prefix+"/", http.StatusMovedPermanently), middlewares, nil).undocumented = true
+	}
+}
+
+// pprofHandler serves the profile named by the "profile" path value, or
+// the index page when it is empty.
+func pprofHandler() http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
+		switch name := request.PathValue("profile"); name {
+		case "":
+			pprofIndex(responseWriter)
+		case "cmdline":
+			responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(responseWriter, strings.Join(os.Args, "\x00"))
+		case "profile":
+			pprofCPU(responseWriter, request)
+		case "symbol":
+			pprofSymbol(responseWriter, request)
+		case "trace":
+			pprofTrace(responseWriter, request)
+		default:
+			pprofProfile(responseWriter, request, name)
+		}
+	})
+}
+
+// pprofIndex lists the profiles with relative links, so that the page works
+// under any prefix.
+func pprofIndex(responseWriter http.ResponseWriter) {
+	var page strings.Builder
+	page.WriteString("<html><head><title>profiles</title></head><body><table>\n")
+	for _, profile := range runtimepprof.Profiles() {
+		name := html.EscapeString(profile.Name())
+		fmt.Fprintf(&page, "<tr><td>%d</td><td><a href=\"%s?debug=1\">%s</a></td></tr>\n", profile.Count(), name, name)
+	}
+	page.WriteString(`</table><p><a href="profile">profile</a> (30 s CPU profile), <a href="trace?seconds=1">trace</a> (1 s execution trace), <a href="cmdline">cmdline</a>, <a href="goroutine?debug=2">full goroutine stack dump</a></p></body></html>`)
+	responseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(responseWriter, page.String())
+}
+
+// pprofProfile writes a named runtime profile, in text with debug=1 or 2
+// and in the binary protobuf format otherwise.
+func pprofProfile(responseWriter http.ResponseWriter, request *http.Request, name string) {
+	profile := runtimepprof.Lookup(name)
+	if profile == nil {
+		http.Error(responseWriter, "unknown profile "+strconv.Quote(name), http.StatusNotFound)
+		return
+	}
+	debug, _ := strconv.Atoi(request.FormValue("debug"))
+	if gc, _ := strconv.Atoi(request.FormValue("gc")); gc > 0 && name == "heap" {
+		runtime.GC()
+	}
+	if debug > 0 {
+		responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		responseWriter.Header().Set("Content-Type", "application/octet-stream")
+		responseWriter.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	}
+	_ = profile.WriteTo(responseWriter, debug)
+}
+
+// pprofCPU writes a CPU profile of the given number of seconds, 30 by
+// default.
+func pprofCPU(responseWriter http.ResponseWriter, request *http.Request) {
+	duration := pprofSeconds(request, 30*time.Second)
+	responseWriter.Header().Set("Content-Type", "application/octet-stream")
+	responseWriter.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	if err := runtimepprof.StartCPUProfile(responseWriter); err != nil {
+		pprofError(responseWriter, "could not enable CPU profiling: "+err.Error())
+		return
+	}
+	pprofWait(request, duration)
+	runtimepprof.StopCPUProfile()
+}
+
+// pprofTrace writes an execution trace of the given number of seconds, 1
+// by default.
+func pprofTrace(responseWriter http.ResponseWriter, request *http.Request) {
+	duration := pprofSeconds(request, time.Second)
+	responseWriter.Header().Set("Content-Type", "application/octet-stream")
+	responseWriter.Header().Set("Content-Disposition", `attachment; filename="trace"`)
+	if err := trace.Start(responseWriter); err != nil {
+		pprofError(responseWriter, "could not enable tracing: "+err.Error())
+		return
+	}
+	pprofWait(request, duration)
+	trace.Stop()
+}
+
+// pprofSymbol maps the program counters listed in the request, separated
+// by "+", to function names, as go tool pprof expects.
+func pprofSymbol(responseWriter http.ResponseWriter, request *http.Request) {
+	responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	var source io.Reader = strings.NewReader(request.URL.RawQuery)
+	if request.Method == http.MethodPost {
+		source = io.LimitReader(request.Body, 1<<20)
+	}
+	var symbols bytes.Buffer
+	reader := bufio.NewReader(source)
+	for {
+		word, err := reader.ReadString('+')
+		if pc, _ := strconv.ParseUint(strings.TrimSuffix(word, "+"), 0, 64); pc != 0 {
+			if function := runtime.FuncForPC(uintptr(pc)); function != nil {
+				fmt.Fprintf(&symbols, "%#x %s\n", pc, function.Name())
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	io.WriteString(responseWriter, "num_symbols: 1\n")
+	responseWriter.Write(symbols.Bytes())
+}
+
+// pprofSeconds returns the duration of the "seconds" query parameter, or
+// fallback.
+func pprofSeconds(request *http.Request, fallback time.Duration) time.Duration {
+	if seconds, err := strconv.ParseFloat(request.FormValue("seconds"), 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return fallback
+}
+
+// pprofWait waits for duration on the request clock, or until the client
+// disconnects.
+func pprofWait(request *http.Request, duration time.Duration) {
+	select {
+	case <-GetClock(request).After(duration):
+	case <-request.Context().Done():
+	}
+}
+
+// pprofError answers a profiling failure as net/http/pprof does, in plain
+// text for go tool pprof to print.
+func pprofError(responseWriter http.ResponseWriter, message string) {
+	header := responseWriter.Header()
+	header.Del("Content-Disposition")
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("X-Go-Pprof", "1")
+	responseWriter.WriteHeader(http.StatusInternalServerError)
+	io.WriteString(responseWriter, message)
+}
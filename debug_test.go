@@ -0,0 +1,51 @@
+package routerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugRoutesNotOnDefaultServeMux(t *testing.T) {
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/vars"} {
+		if _, pattern := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodGet, path, nil)); pattern != "" {
+			t.Errorf("http.DefaultServeMux serves %s with pattern %q", path, pattern)
+		}
+	}
+}
+
+func TestPprof(t *testing.T) {
+	router := New()
+	router.Group("/debug").Pprof("/pprof")
+	tests := []struct {
+		path            string
+		wantStatus      int
+		wantContentType string
+	}{
+		{"/debug/pprof", http.StatusMovedPermanently, ""},
+		{"/debug/pprof/", http.StatusOK, "text/html; charset=utf-8"},
+		{"/debug/pprof/heap", http.StatusOK, "application/octet-stream"},
+		{"/debug/pprof/goroutine?debug=1", http.StatusOK, "text/plain; charset=utf-8"},
+		{"/debug/pprof/symbol", http.StatusOK, "text/plain; charset=utf-8"},
+		{"/debug/pprof/unknown", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.path, nil))
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, test.wantStatus)
+			}
+			if contentType := recorder.Header().Get("Content-Type"); test.wantContentType != "" && contentType != test.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", contentType, test.wantContentType)
+			}
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/debug/pprof/symbol", strings.NewReader("0x1+0x2")))
+	if !strings.HasPrefix(recorder.Body.String(), "num_symbols: 1\n") {
+		t.Errorf("symbol body = %q", recorder.Body.String())
+	}
+}
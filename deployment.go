@@ -0,0 +1,138 @@
+package routerx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Deployment switches between alternative handler implementations keyed by a
+// deployment label such as "blue" and "green". Every route registered with
+// the same Deployment switches at the same instant, which allows in-process
+// blue/green rollouts of rewritten handlers.
+//
+// Example:
+//
+//	deployment := routerx.NewDeployment("blue")
+//	router.Get("/users/{id}", deployment.Handler(map[string]http.HandlerFunc{
+//	    "blue":  getUserV1,
+//	    "green": getUserV2,
+//	}))
+//	admin.Path("/deployment").Get(deployment.AdminHandler()).Put(deployment.AdminHandler())
+type Deployment struct {
+	active atomic.Pointer[string]
+
+	mutex       sync.Mutex
+	handlerSets []map[string]http.HandlerFunc
+}
+
+// NewDeployment creates a Deployment with the given label active.
+func NewDeployment(active string) *Deployment {
+	deployment := &Deployment{}
+	deployment.active.Store(&active)
+	return deployment
+}
+
+// Active returns the currently active deployment label.
+func (deployment *Deployment) Active() string {
+	return *deployment.active.Load()
+}
+
+// Labels returns the labels that are implemented by every handler registered
+// with the deployment, in sorted order.
+func (deployment *Deployment) Labels() []string {
+	deployment.mutex.Lock()
+	defer deployment.mutex.Unlock()
+
+	var labels []string
+	if len(deployment.handlerSets) == 0 {
+		return labels
+	}
+	for label := range deployment.handlerSets[0] {
+		if deployment.implemented(label) {
+			labels = append(labels, label)
+		}
+	}
+	slices.Sort(labels)
+	return labels
+}
+
+// Switch atomically activates label for every handler of the deployment. It
+// fails if any registered handler has no implementation for the label.
+func (deployment *Deployment) Switch(label string) error {
+	deployment.mutex.Lock()
+	defer deployment.mutex.Unlock()
+
+	if !deployment.implemented(label) {
+		return fmt.Errorf("routerx: deployment label %q is not implemented by every handler", label)
+	}
+	deployment.active.Store(&label)
+	return nil
+}
+
+// implemented reports whether every handler set has a handler for label.
+// The caller must hold the mutex.
+func (deployment *Deployment) implemented(label string) bool {
+	for _, handlers := range deployment.handlerSets {
+		if _, found := handlers[label]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Handler returns a handler that dispatches every request to the
+// implementation registered for the active label. The chosen label is
+// reported in the X-Deployment response header.
+func (deployment *Deployment) Handler(handlers map[string]http.HandlerFunc) http.HandlerFunc {
+	deployment.mutex.Lock()
+	deployment.handlerSets = append(deployment.handlerSets, handlers)
+	deployment.mutex.Unlock()
+
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		label := deployment.Active()
+		handler, found := handlers[label]
+		if !found {
+			writeError(responseWriter, http.StatusServiceUnavailable, fmt.Sprintf("deployment %q is not available", label))
+			return
+		}
+		responseWriter.Header().Set("X-Deployment", label)
+		handler(responseWriter, request)
+	}
+}
+
+// AdminHandler returns a handler for operating the deployment at runtime.
+// GET responds with {"active": ..., "labels": [...]}; PUT and POST accept
+// {"active": "green"} and switch to that label. The handler performs no
+// authentication, so it should be registered behind an authenticating
+// middleware.
+func (deployment *Deployment) AdminHandler() http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		switch request.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut, http.MethodPost:
+			var payload struct {
+				Active string `json:"active"`
+			}
+			if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
+				writeError(responseWriter, http.StatusBadRequest, "invalid JSON")
+				return
+			}
+			if err := deployment.Switch(payload.Active); err != nil {
+				writeError(responseWriter, http.StatusConflict, err.Error())
+				return
+			}
+		default:
+			responseWriter.Header().Set("Allow", "GET, HEAD, POST, PUT")
+			writeError(responseWriter, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+			return
+		}
+		writeJSON(responseWriter, http.StatusOK, map[string]any{
+			"active": deployment.Active(),
+			"labels": deployment.Labels(),
+		})
+	}
+}
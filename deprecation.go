@@ -0,0 +1,218 @@
+package routerx
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Deprecation describes a deprecated route or parameter.
+type Deprecation struct {
+	// Since is when the surface was deprecated, announced with the
+	// Deprecation header. When zero the header is "true".
+	Since time.Time
+
+	// Sunset is when the surface stops working, announced with the Sunset
+	// header.
+	Sunset time.Time
+
+	// Link is the URL of the migration guide, sent as a Link with
+	// rel="deprecation".
+	Link string
+
+	// Successor is the URL of the replacement, sent as a Link with
+	// rel="successor-version".
+	Successor string
+}
+
+// DeprecationsConfig configures Deprecations.
+type DeprecationsConfig struct {
+	// ClientKey identifies the consumer of a request in the usage report,
+	// e.g. by API key or authenticated principal. Defaults to the remote IP.
+	ClientKey func(request *http.Request) string
+
+	// MaxClients bounds the number of consumers tracked per deprecated
+	// surface; further consumers are counted under "other". Defaults to
+	// 1000.
+	MaxClients int
+}
+
+// Deprecations runs a deprecation program: it marks routes and parameters
+// deprecated with the Deprecation, Sunset, and Link headers, counts their
+// use per consumer, and serves a report of the consumers that still depend
+// on them, so they can be contacted before the sunset.
+//
+// Example:
+//
+//	deprecations := routerx.NewDeprecations(routerx.DeprecationsConfig{
+//	    ClientKey: func(request *http.Request) string { return request.Header.Get("X-API-Key") },
+//	})
+//	router.Path("/v1/users").
+//	    Use(deprecations.Route(routerx.Deprecation{
+//	        Sunset:    time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC),
+//	        Successor: "/v2/users",
+//	    })).
+//	    Use(deprecations.Parameter("sort", routerx.Deprecation{Link: "https://example.com/docs/ordering"})).
+//	    Get(listUsersV1)
+//	router.Get("/admin/deprecations", deprecations.Handler())
+type Deprecations struct {
+	config   DeprecationsConfig
+	mutex    sync.Mutex
+	surfaces map[string]*deprecatedSurface
+}
+
+// deprecatedSurface is the usage of one deprecated route or parameter.
+type deprecatedSurface struct {
+	kind        string
+	deprecation Deprecation
+	total       uint64
+	clients     map[string]*deprecatedUsage
+}
+
+type deprecatedUsage struct {
+	count    uint64
+	lastSeen time.Time
+}
+
+// NewDeprecations creates Deprecations from config.
+func NewDeprecations(config DeprecationsConfig) *Deprecations {
+	if config.ClientKey == nil {
+		config.ClientKey = remoteIP
+	}
+	if config.MaxClients <= 0 {
+		config.MaxClients = 1000
+	}
+	return &Deprecations{config: config, surfaces: make(map[string]*deprecatedSurface)}
+}
+
+// Route returns a Middleware marking every request it wraps as using a
+// deprecated route.
+func (deprecations *Deprecations) Route(deprecation Deprecation) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			deprecations.use(request, routePattern(request.Pattern), "route", deprecation)
+			setDeprecationHeaders(responseWriter.Header(), deprecation)
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
+
+// Parameter returns a Middleware marking requests that send the query
+// parameter name as using a deprecated parameter. Requests without it
+// pass through untouched.
+func (deprecations *Deprecations) Parameter(name string, deprecation Deprecation) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			if request.URL.Query().Has(name) {
+				deprecations.use(request, routePattern(request.Pattern)+"?"+name, "parameter", deprecation)
+				setDeprecationHeaders(responseWriter.Header(), deprecation)
+			}
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
+
+// use counts a request against a deprecated surface.
+func (deprecations *Deprecations) use(request *http.Request, name string, kind string, deprecation Deprecation) {
+	key := deprecations.config.ClientKey(request)
+	if key == "" {
+		key = "anonymous"
+	}
+	name = request.Method + " " + name
+
+	deprecations.mutex.Lock()
+	defer deprecations.mutex.Unlock()
+	surface := deprecations.surfaces[name]
+	if surface == nil {
+		surface = &deprecatedSurface{kind: kind, deprecation: deprecation, clients: make(map[string]*deprecatedUsage)}
+		deprecations.surfaces[name] = surface
+	}
+	surface.total++
+	usage := surface.clients[key]
+	if usage == nil {
+		if len(surface.clients) >= deprecations.config.MaxClients {
+			key = "other"
+			usage = surface.clients[key]
+		}
+		if usage == nil {
+			usage = &deprecatedUsage{}
+			surface.clients[key] = usage
+		}
+	}
+	usage.count++
+	usage.lastSeen = GetClock(request).Now()
+}
+
+// DeprecationReport is the usage of a deprecated surface served by
+// Deprecations.Handler.
+type DeprecationReport struct {
+	Surface string                    `json:"surface"`
+	Kind    string                    `json:"kind"`
+	Sunset  *time.Time                `json:"sunset,omitempty"`
+	Total   uint64                    `json:"total"`
+	Clients []DeprecationClientReport `json:"clients"`
+}
+
+// DeprecationClientReport is the usage of a deprecated surface by one
+// consumer.
+type DeprecationClientReport struct {
+	Key      string    `json:"key"`
+	Count    uint64    `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Report returns the usage of every deprecated surface used since start,
+// ordered by surface, with the heaviest consumers first.
+func (deprecations *Deprecations) Report() []DeprecationReport {
+	deprecations.mutex.Lock()
+	defer deprecations.mutex.Unlock()
+	reports := make([]DeprecationReport, 0, len(deprecations.surfaces))
+	for name, surface := range deprecations.surfaces {
+		report := DeprecationReport{Surface: name, Kind: surface.kind, Total: surface.total}
+		if sunset := surface.deprecation.Sunset; !sunset.IsZero() {
+			report.Sunset = &sunset
+		}
+		for key, usage := range surface.clients {
+			report.Clients = append(report.Clients, DeprecationClientReport{Key: key, Count: usage.count, LastSeen: usage.lastSeen})
+		}
+		slices.SortFunc(report.Clients, func(first DeprecationClientReport, second DeprecationClientReport) int {
+			return cmp.Or(cmp.Compare(second.Count, first.Count), strings.Compare(first.Key, second.Key))
+		})
+		reports = append(reports, report)
+	}
+	slices.SortFunc(reports, func(first DeprecationReport, second DeprecationReport) int {
+		return strings.Compare(first.Surface, second.Surface)
+	})
+	return reports
+}
+
+// Handler returns a handler serving Report as JSON. Guard it like any other
+// administrative endpoint, since it lists consumer keys.
+func (deprecations *Deprecations) Handler() http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusOK, deprecations.Report())
+	}
+}
+
+// setDeprecationHeaders announces deprecation as described by RFC 9745 and
+// RFC 8594.
+func setDeprecationHeaders(header http.Header, deprecation Deprecation) {
+	if deprecation.Since.IsZero() {
+		header.Set("Deprecation", "true")
+	} else {
+		header.Set("Deprecation", "@"+strconv.FormatInt(deprecation.Since.Unix(), 10))
+	}
+	if !deprecation.Sunset.IsZero() {
+		header.Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if deprecation.Link != "" {
+		header.Add("Link", "<"+deprecation.Link+`>; rel="deprecation"`)
+	}
+	if deprecation.Successor != "" {
+		header.Add("Link", "<"+deprecation.Successor+`>; rel="successor-version"`)
+	}
+}
@@ -0,0 +1,104 @@
+package routerx
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// DeviceClass is the coarse kind of client sending a request.
+type DeviceClass string
+
+const (
+	DeviceUnknown DeviceClass = "unknown"
+	DeviceBot     DeviceClass = "bot"
+	DeviceMobile  DeviceClass = "mobile"
+	DeviceDesktop DeviceClass = "desktop"
+
+	// DeviceApp is a native application using an HTTP library rather than
+	// a browser.
+	DeviceApp DeviceClass = "app"
+)
+
+// DeviceClassifier classifies the client of a request.
+type DeviceClassifier func(request *http.Request) DeviceClass
+
+type deviceContextKey struct{}
+
+// ClassifyDevices returns a Middleware that classifies the client of every
+// request with classify, or DefaultDeviceClassifier when classify is nil,
+// and attaches the result to the request for GetDeviceClass. Plug in a full
+// user agent parser when the built-in heuristics are not precise enough.
+//
+// Example:
+//
+//	router := routerx.New().Use(routerx.ClassifyDevices(nil))
+//	router.Get("/", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    if routerx.GetDeviceClass(request) == routerx.DeviceMobile {
+//	        renderMobileHome(responseWriter)
+//	        return
+//	    }
+//	    renderHome(responseWriter)
+//	})
+func ClassifyDevices(classify DeviceClassifier) Middleware {
+	if classify == nil {
+		classify = DefaultDeviceClassifier
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			class := classify(request)
+			next.ServeHTTP(responseWriter, request.WithContext(context.WithValue(request.Context(), deviceContextKey{}, class)))
+		})
+	}
+}
+
+// GetDeviceClass returns the class attached by ClassifyDevices, or
+// DeviceUnknown outside that middleware. Cacheable responses that differ by
+// class should list User-Agent in their Vary header.
+func GetDeviceClass(request *http.Request) DeviceClass {
+	if class, ok := request.Context().Value(deviceContextKey{}).(DeviceClass); ok {
+		return class
+	}
+	return DeviceUnknown
+}
+
+var (
+	botTokens = []string{
+		"bot", "crawler", "spider", "slurp", "crawl", "headlesschrome", "lighthouse",
+		"facebookexternalhit", "embedly", "preview", "curl/", "wget/", "python-requests",
+		"go-http-client", "httpie", "postman", "java/", "libwww",
+	}
+	appTokens    = []string{"okhttp/", "cfnetwork/", "dart/", "alamofire", "dalvik/", "electron/"}
+	mobileTokens = []string{"mobi", "android", "iphone", "ipad", "ipod", "windows phone", "blackberry", "opera mini"}
+)
+
+// DefaultDeviceClassifier classifies clients from the User-Agent header and
+// the Sec-CH-UA-Mobile client hint: crawlers, monitors, and command line
+// tools are bots, common native HTTP stacks such as OkHttp and CFNetwork
+// are apps, phones and tablets are mobile, and other browsers are desktop.
+func DefaultDeviceClassifier(request *http.Request) DeviceClass {
+	userAgent := strings.ToLower(request.UserAgent())
+	if userAgent == "" {
+		return DeviceUnknown
+	}
+	switch {
+	case containsAny(userAgent, botTokens):
+		return DeviceBot
+	case containsAny(userAgent, appTokens):
+		return DeviceApp
+	case request.Header.Get("Sec-CH-UA-Mobile") == "?1" || containsAny(userAgent, mobileTokens):
+		return DeviceMobile
+	case strings.HasPrefix(userAgent, "mozilla/") || strings.HasPrefix(userAgent, "opera/"):
+		return DeviceDesktop
+	}
+	return DeviceUnknown
+}
+
+func containsAny(value string, tokens []string) bool {
+	for _, token := range tokens {
+		if strings.Contains(value, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package routerx
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// DiagnosticsConfig configures Router.Diagnostics.
+type DiagnosticsConfig struct {
+	// Path of the endpoint. Defaults to "/_routerx/diag".
+	Path string
+
+	// Middlewares guard the endpoint, e.g. with an admin check. They run
+	// after the router's middlewares. At least one is required, since the
+	// report reveals the internals of the service.
+	Middlewares []Middleware
+
+	// Components report the state of parts the router cannot see into,
+	// such as session or rate limit stores and connection pools. Every
+	// function is called on each request and its result is included under
+	// its name.
+	Components map[string]func() any
+}
+
+// DiagnosticsReport describes the configuration of a router, see
+// Router.Diagnose.
+type DiagnosticsReport struct {
+	// Routes lists every route with the middlewares wrapping it, outermost
+	// first, which shows the chain each group contributes.
+	Routes []RouteDiagnostics `json:"routes"`
+
+	// Features lists the router-wide settings.
+	Features map[string]any `json:"features"`
+
+	// Components holds the state reported by DiagnosticsConfig.Components.
+	Components map[string]any `json:"components,omitempty"`
+
+	// Problems lists the misconfigurations detected while routes were
+	// registered, such as overlapping patterns and middlewares added with
+	// Use after the routes they were meant for.
+	Problems []string `json:"problems"`
+
+	// ResponseMismatches lists the most recent responses found by
+	// Router.CheckResponses not to match their declared types.
+	ResponseMismatches []string `json:"response_mismatches,omitempty"`
+
+	Runtime RuntimeDiagnostics `json:"runtime"`
+}
+
+// RouteDiagnostics describes a route in a DiagnosticsReport.
+type RouteDiagnostics struct {
+	Pattern     string   `json:"pattern"`
+	Name        string   `json:"name,omitempty"`
+	Middlewares []string `json:"middlewares"`
+
+	Meta map[string]string `json:"meta,omitempty"`
+	Tags []string          `json:"tags,omitempty"`
+
+	// Site is the source location that registered the route.
+	Site string `json:"site"`
+}
+
+// RuntimeDiagnostics describes the Go runtime in a DiagnosticsReport.
+type RuntimeDiagnostics struct {
+	GoVersion  string `json:"go_version"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	Goroutines int    `json:"goroutines"`
+}
+
+// Diagnose returns a report of the router's configuration: the middleware
+// chain of every route, the enabled features, and the problems detected
+// during registration.
+func (router *Router) Diagnose() DiagnosticsReport {
+	report := DiagnosticsReport{
+		Routes: make([]RouteDiagnostics, 0, len(router.routes)),
+		Features: map[string]any{
+			"strict":             router.strict,
+			"check_responses":    router.checkResponses,
+			"track_usage":        router.trackUsage,
+			"cors":               router.cors != nil,
+			"error_handler":      router.errorHandler != nil,
+			"not_found":          router.notFound != nil,
+			"method_not_allowed": router.methodNotAllowed != nil,
+			"spa_fallbacks":      len(router.spas),
+			"shutdown_hooks":     len(router.shutdownHooks),
+			"ready":              router.Ready(),
+			"trailing_slash":     int(router.trailingSlash),
+			"matchers":           len(router.matchers),
+		},
+		Problems:           slices.Clone(router.problems),
+		ResponseMismatches: router.responseMismatches.list(),
+		Runtime: RuntimeDiagnostics{
+			GoVersion:  runtime.Version(),
+			GOMAXPROCS: runtime.GOMAXPROCS(0),
+			Goroutines: runtime.NumGoroutine(),
+		},
+	}
+	if report.Problems == nil {
+		report.Problems = []string{}
+	}
+	for _, registered := range router.routes {
+		if registered.preflight {
+			continue
+		}
+		names := make([]string, len(registered.middlewares))
+		for index, middleware := range registered.middlewares {
+			names[index] = middlewareName(middleware)
+		}
+		report.Routes = append(report.Routes, RouteDiagnostics{
+			Pattern:     registered.pattern,
+			Name:        registered.name,
+			Middlewares: names,
+			Meta:        registered.meta,
+			Tags:        registered.tags,
+			Site:        registered.site,
+		})
+	}
+	return report
+}
+
+// Diagnostics serves the report of Router.Diagnose, with the configured
+// components, as JSON at the configured path, "/_routerx/diag" by default.
+// The endpoint is left out of the OpenAPI document. Diagnostics panics
+// without guarding middlewares.
+//
+// Example:
+//
+//	router.Diagnostics(routerx.DiagnosticsConfig{
+//	    Middlewares: []routerx.Middleware{routerx.BasicAuth(routerx.BasicAuthUsers(operators))},
+//	    Components: map[string]func() any{
+//	        "database": func() any { return db.Stats() },
+//	        "sessions": func() any { return "redis" },
+//	    },
+//	})
+func (router *Router) Diagnostics(config DiagnosticsConfig) {
+	if len(config.Middlewares) == 0 {
+		panic("routerx: Diagnostics needs middlewares restricting it to administrators")
+	}
+	if config.Path == "" {
+		config.Path = "/_routerx/diag"
+	}
+	middlewares := append(copyMiddlewares(router.middlewares), config.Middlewares...)
+	router.register("GET", cleanPath(config.Path), http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		report := router.Diagnose()
+		if len(config.Components) > 0 {
+			report.Components = make(map[string]any, len(config.Components))
+			for name, component := range config.Components {
+				report.Components[name] = component()
+			}
+		}
+		responseWriter.Header().Set("Cache-Control", "no-store")
+		writeJSON(responseWriter, http.StatusOK, report)
+	}), middlewares, nil).undocumented = true
+}
+
+// closureSuffix matches the suffix the compiler gives closures, such as
+// ".func1" or ".func2.1".
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+(\.\d+)*$`)
+
+// middlewareName names a middleware after the function that created it,
+// e.g. "routerx.Logger" or "main.requireAdmin".
+func middlewareName(middleware Middleware) string {
+	function := runtime.FuncForPC(reflect.ValueOf(middleware).Pointer())
+	if function == nil {
+		return "unknown"
+	}
+	name := closureSuffix.ReplaceAllString(function.Name(), "")
+	name = strings.TrimSuffix(name, "-fm")
+	if index := strings.LastIndexByte(name, '/'); index >= 0 {
+		name = name[index+1:]
+	}
+	return name
+}
@@ -0,0 +1,269 @@
+package routerx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DictionaryEncoder compresses a response body with a shared dictionary for
+// one content coding of the Compression Dictionary Transport specification
+// (RFC 9842): "dcb" (Brotli) or "dcz" (Zstandard). The standard library
+// implements neither algorithm, so encoders are provided by the application,
+// typically as thin wrappers around a Brotli or Zstandard package.
+type DictionaryEncoder interface {
+	// Encoding returns the content coding token, "dcb" or "dcz".
+	Encoding() string
+
+	// NewWriter returns a writer compressing into destination using
+	// dictionary as the shared dictionary.
+	NewWriter(destination io.Writer, dictionary []byte) (io.WriteCloser, error)
+}
+
+// dictionaryMagic holds the stream headers preceding the dictionary hash for
+// each dictionary-compressed content coding.
+var dictionaryMagic = map[string][]byte{
+	"dcb": {0xff, 0x44, 0x43, 0x42},
+	"dcz": {0x5e, 0x2a, 0x4d, 0x18, 0x20, 0x00, 0x00, 0x00},
+}
+
+// Dictionary is a shared compression dictionary announced to clients.
+type Dictionary struct {
+	// ID is sent to clients in the Use-As-Dictionary header and echoed back
+	// in the Dictionary-ID request header.
+	ID string `json:"id"`
+
+	// Match is the path pattern of the responses the dictionary applies to,
+	// e.g. "/api/v1/feed*". "*" matches any sequence of characters.
+	Match string `json:"match"`
+
+	// Size is the dictionary size in bytes.
+	Size int `json:"size"`
+
+	// Hash is the base64 encoded SHA-256 digest clients send in the
+	// Available-Dictionary header.
+	Hash string `json:"hash"`
+
+	data   []byte
+	digest [sha256.Size]byte
+}
+
+// DictionaryStore manages shared compression dictionaries and compresses
+// responses with them. A common setup for large, frequently updated JSON
+// documents registers the previous version of a document as the dictionary,
+// so clients that already hold it only download a small delta.
+type DictionaryStore struct {
+	encoders []DictionaryEncoder
+
+	mutex        sync.RWMutex
+	dictionaries map[string]*Dictionary
+}
+
+// NewDictionaryStore creates an empty store compressing with the given
+// encoders, in order of preference.
+func NewDictionaryStore(encoders ...DictionaryEncoder) *DictionaryStore {
+	return &DictionaryStore{
+		encoders:     encoders,
+		dictionaries: make(map[string]*Dictionary),
+	}
+}
+
+// Add registers or replaces the dictionary with the given ID.
+func (store *DictionaryStore) Add(id string, match string, data []byte) *Dictionary {
+	digest := sha256.Sum256(data)
+	dictionary := &Dictionary{
+		ID:     id,
+		Match:  match,
+		Size:   len(data),
+		Hash:   base64.StdEncoding.EncodeToString(digest[:]),
+		data:   bytes.Clone(data),
+		digest: digest,
+	}
+	store.mutex.Lock()
+	store.dictionaries[id] = dictionary
+	store.mutex.Unlock()
+	return dictionary
+}
+
+// Remove deletes the dictionary with the given ID.
+func (store *DictionaryStore) Remove(id string) {
+	store.mutex.Lock()
+	delete(store.dictionaries, id)
+	store.mutex.Unlock()
+}
+
+// Dictionaries returns all registered dictionaries sorted by ID.
+func (store *DictionaryStore) Dictionaries() []*Dictionary {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	dictionaries := make([]*Dictionary, 0, len(store.dictionaries))
+	for _, dictionary := range store.dictionaries {
+		dictionaries = append(dictionaries, dictionary)
+	}
+	slices.SortFunc(dictionaries, func(left, right *Dictionary) int { return strings.Compare(left.ID, right.ID) })
+	return dictionaries
+}
+
+// lookup finds the dictionary announced in an Available-Dictionary header.
+func (store *DictionaryStore) lookup(header string) *Dictionary {
+	encoded := strings.Trim(strings.TrimSpace(header), ":")
+	digest, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(digest) != sha256.Size {
+		return nil
+	}
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	for _, dictionary := range store.dictionaries {
+		if bytes.Equal(dictionary.digest[:], digest) {
+			return dictionary
+		}
+	}
+	return nil
+}
+
+// Middleware returns a Middleware that compresses responses with a shared
+// dictionary when the client announces one it holds in Available-Dictionary,
+// the dictionary's Match pattern covers the request path, and the client
+// accepts one of the store's encodings. Other responses are left untouched.
+//
+// Example:
+//
+//	dictionaries := routerx.NewDictionaryStore(zstdDictionaryEncoder{})
+//	router.Mount("/_dictionaries", dictionaries.Handler())
+//	api := router.Group("/api").Use(dictionaries.Middleware())
+func (store *DictionaryStore) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			addVary(responseWriter.Header(), "Accept-Encoding", "Available-Dictionary")
+
+			dictionary := store.lookup(request.Header.Get("Available-Dictionary"))
+			encoder := store.negotiate(request.Header.Get("Accept-Encoding"))
+			if dictionary == nil || encoder == nil || !matchDictionaryPath(dictionary.Match, request.URL.Path) {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+
+			buffered := newBufferedResponseWriter()
+			next.ServeHTTP(buffered, request)
+			body := buffered.body.Bytes()
+			if buffered.status() != http.StatusOK || buffered.header.Get("Content-Encoding") != "" || len(body) == 0 {
+				buffered.flushTo(responseWriter, body)
+				return
+			}
+
+			var compressed bytes.Buffer
+			compressed.Write(dictionaryMagic[encoder.Encoding()])
+			compressed.Write(dictionary.digest[:])
+			writer, err := encoder.NewWriter(&compressed, dictionary.data)
+			if err == nil {
+				_, err = writer.Write(body)
+				if closeErr := writer.Close(); err == nil {
+					err = closeErr
+				}
+			}
+			if err != nil {
+				buffered.flushTo(responseWriter, body)
+				return
+			}
+			buffered.header.Set("Content-Encoding", encoder.Encoding())
+			buffered.flushTo(responseWriter, compressed.Bytes())
+		})
+	}
+}
+
+// negotiate returns the first encoder whose coding is accepted.
+func (store *DictionaryStore) negotiate(acceptEncoding string) DictionaryEncoder {
+	for _, encoder := range store.encoders {
+		for _, member := range strings.Split(acceptEncoding, ",") {
+			coding, parameters, _ := strings.Cut(strings.TrimSpace(member), ";")
+			if !strings.EqualFold(strings.TrimSpace(coding), encoder.Encoding()) {
+				continue
+			}
+			if quality, found := strings.CutPrefix(strings.TrimSpace(parameters), "q="); found {
+				if value, err := strconv.ParseFloat(quality, 64); err == nil && value == 0 {
+					continue
+				}
+			}
+			return encoder
+		}
+	}
+	return nil
+}
+
+// matchDictionaryPath matches a URL path against a dictionary match pattern
+// in which "*" stands for any sequence of characters, including slashes.
+func matchDictionaryPath(pattern string, requestPath string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == requestPath
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(requestPath, parts[0]) {
+		return false
+	}
+	remaining := requestPath[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(remaining, part)
+		if index < 0 {
+			return false
+		}
+		remaining = remaining[index+len(part):]
+	}
+	return strings.HasSuffix(remaining, parts[len(parts)-1])
+}
+
+// Handler returns the dictionary management endpoints, meant to be mounted
+// with Router.Mount behind an authenticating middleware for writes:
+//
+//	GET    /      lists the dictionaries
+//	GET    /{id}  downloads a dictionary with its Use-As-Dictionary header
+//	PUT    /{id}  stores the request body as a dictionary; ?match= sets Match
+//	DELETE /{id}  removes a dictionary
+func (store *DictionaryStore) Handler() http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		id := strings.Trim(path.Clean("/"+request.URL.Path), "/")
+		switch {
+		case id == "" && request.Method == http.MethodGet:
+			writeJSON(responseWriter, http.StatusOK, store.Dictionaries())
+		case id == "":
+			responseWriter.Header().Set("Allow", http.MethodGet)
+			writeError(responseWriter, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		case request.Method == http.MethodGet || request.Method == http.MethodHead:
+			store.mutex.RLock()
+			dictionary := store.dictionaries[id]
+			store.mutex.RUnlock()
+			if dictionary == nil {
+				writeError(responseWriter, http.StatusNotFound, "dictionary not found")
+				return
+			}
+			responseWriter.Header().Set("Use-As-Dictionary", `match="`+dictionary.Match+`", id="`+dictionary.ID+`"`)
+			responseWriter.Header().Set("Content-Type", "application/octet-stream")
+			responseWriter.Header().Set("Cache-Control", "public, max-age=86400")
+			_, _ = responseWriter.Write(dictionary.data)
+		case request.Method == http.MethodPut:
+			data, err := io.ReadAll(request.Body)
+			if err != nil {
+				writeError(responseWriter, http.StatusBadRequest, "failed to read dictionary")
+				return
+			}
+			match := request.URL.Query().Get("match")
+			if match == "" {
+				writeError(responseWriter, http.StatusBadRequest, "the match query parameter is required")
+				return
+			}
+			writeJSON(responseWriter, http.StatusOK, store.Add(id, match, data))
+		case request.Method == http.MethodDelete:
+			store.Remove(id)
+			responseWriter.WriteHeader(http.StatusNoContent)
+		default:
+			responseWriter.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+			writeError(responseWriter, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package routerx
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// digestAlgorithms maps the algorithm names of RFC 9530 and RFC 3230 to their
+// hash constructors. MD5 is only accepted in the legacy Content-MD5 and
+// Digest headers.
+var digestAlgorithms = map[string]func() hash.Hash{
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+	"md5":     md5.New,
+}
+
+// DigestConfig configures the VerifyDigest middleware.
+type DigestConfig struct {
+	// Required rejects requests that carry a body but no digest header.
+	Required bool
+
+	// MaxBodyBytes limits how much of the body is buffered for
+	// verification. Larger bodies are rejected with 413. Defaults to 10 MiB.
+	MaxBodyBytes int64
+}
+
+// VerifyDigest returns a Middleware that verifies request bodies against the
+// integrity headers sent by the client: Content-Digest and Repr-Digest from
+// RFC 9530, the legacy Digest header from RFC 3230, and Content-MD5. Every
+// supported digest present must match, in each header separately; requests
+// with a mismatching digest are rejected with 400 Bad Request before the
+// handler runs, as are malformed digests of supported algorithms.
+// Unsupported algorithms are ignored, and so is Repr-Digest on requests with
+// a Content-Encoding, since it covers the decoded representation rather
+// than the received bytes.
+//
+// Example:
+//
+//	ingest := router.Group("/ingest").Use(routerx.VerifyDigest(routerx.DigestConfig{Required: true}))
+func VerifyDigest(config DigestConfig) Middleware {
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 10 << 20
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			expected, err := requestDigests(request.Header)
+			if err != nil {
+				responseWriter.Header().Set("Want-Content-Digest", "sha-256=10, sha-512=3")
+				writeError(responseWriter, http.StatusBadRequest, err.Error())
+				return
+			}
+			if len(expected) == 0 {
+				if config.Required && request.ContentLength != 0 && request.Body != nil && request.Body != http.NoBody {
+					responseWriter.Header().Set("Want-Content-Digest", "sha-256=10, sha-512=3")
+					writeError(responseWriter, http.StatusBadRequest, "missing Content-Digest header")
+					return
+				}
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(request.Body, config.MaxBodyBytes+1))
+			if err != nil {
+				writeError(responseWriter, http.StatusBadRequest, "failed to read request body")
+				return
+			}
+			if int64(len(body)) > config.MaxBodyBytes {
+				writeError(responseWriter, http.StatusRequestEntityTooLarge, "request body too large to verify")
+				return
+			}
+			for _, digest := range expected {
+				hasher := digestAlgorithms[digest.algorithm]()
+				hasher.Write(body)
+				if subtle.ConstantTimeCompare(hasher.Sum(nil), digest.value) != 1 {
+					responseWriter.Header().Set("Want-Content-Digest", "sha-256=10, sha-512=3")
+					writeError(responseWriter, http.StatusBadRequest, fmt.Sprintf("%s digest in %s header does not match the request body", digest.algorithm, digest.header))
+					return
+				}
+			}
+			request.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
+
+// requestDigest is a digest sent in an integrity header.
+type requestDigest struct {
+	header    string
+	algorithm string
+	value     []byte
+}
+
+// requestDigests collects the decoded digests of all supported algorithms
+// from the integrity headers that cover the received bytes, one per header
+// and algorithm. It fails on a digest of a supported algorithm that is not
+// valid base64.
+func requestDigests(header http.Header) ([]requestDigest, error) {
+	var digests []requestDigest
+	names := []string{"Content-Digest", "Repr-Digest"}
+	if encoding := header.Get("Content-Encoding"); encoding != "" && !strings.EqualFold(encoding, "identity") {
+		names = names[:1]
+	}
+	for _, name := range names {
+		for _, member := range strings.Split(header.Get(name), ",") {
+			algorithm, value, found := strings.Cut(strings.TrimSpace(member), "=")
+			algorithm = strings.ToLower(algorithm)
+			if !found || algorithm == "md5" || digestAlgorithms[algorithm] == nil {
+				continue
+			}
+			// Structured field byte sequences are wrapped in colons.
+			value = strings.TrimSuffix(strings.TrimPrefix(value, ":"), ":")
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, fmt.Errorf("malformed %s digest in %s header", algorithm, name)
+			}
+			digests = append(digests, requestDigest{header: name, algorithm: algorithm, value: decoded})
+		}
+	}
+	for _, member := range strings.Split(header.Get("Digest"), ",") {
+		algorithm, value, found := strings.Cut(strings.TrimSpace(member), "=")
+		algorithm = strings.ToLower(algorithm)
+		if !found || digestAlgorithms[algorithm] == nil {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("malformed %s digest in Digest header", algorithm)
+		}
+		digests = append(digests, requestDigest{header: "Digest", algorithm: algorithm, value: decoded})
+	}
+	if value := header.Get("Content-MD5"); value != "" {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, errors.New("malformed Content-MD5 header")
+		}
+		digests = append(digests, requestDigest{header: "Content-MD5", algorithm: "md5", value: decoded})
+	}
+	return digests, nil
+}
+
+// ContentDigest returns a Middleware that buffers responses and adds an RFC
+// 9530 Content-Digest header computed with algorithm, which must be
+// "sha-256" or "sha-512".
+//
+// Example:
+//
+//	router.Use(routerx.ContentDigest("sha-256"))
+func ContentDigest(algorithm string) Middleware {
+	algorithm = strings.ToLower(algorithm)
+	newHash := digestAlgorithms[algorithm]
+	if newHash == nil || algorithm == "md5" {
+		panic("routerx: unsupported digest algorithm " + algorithm)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			buffered := newBufferedResponseWriter()
+			next.ServeHTTP(buffered, request)
+
+			body := buffered.body.Bytes()
+			hasher := newHash()
+			hasher.Write(body)
+			buffered.header.Set("Content-Digest", algorithm+"=:"+base64.StdEncoding.EncodeToString(hasher.Sum(nil))+":")
+			buffered.flushTo(responseWriter, body)
+		})
+	}
+}
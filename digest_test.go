@@ -0,0 +1,47 @@
+package routerx
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifyDigest(t *testing.T) {
+	body := `{"id":42}`
+	sum := sha256.Sum256([]byte(body))
+	good := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+	zero := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+	bogus := "sha-256=:" + zero + ":"
+
+	tests := []struct {
+		name       string
+		header     map[string]string
+		wantStatus int
+	}{
+		{"matching Content-Digest", map[string]string{"Content-Digest": good}, http.StatusOK},
+		{"mismatching Content-Digest", map[string]string{"Content-Digest": bogus}, http.StatusBadRequest},
+		{"bogus Content-Digest, matching Repr-Digest", map[string]string{"Content-Digest": bogus, "Repr-Digest": good}, http.StatusBadRequest},
+		{"matching Content-Digest, bogus Digest", map[string]string{"Content-Digest": good, "Digest": "sha-256=" + zero}, http.StatusBadRequest},
+		{"Repr-Digest with Content-Encoding", map[string]string{"Content-Digest": good, "Repr-Digest": bogus, "Content-Encoding": "gzip"}, http.StatusOK},
+		{"Repr-Digest without Content-Encoding", map[string]string{"Repr-Digest": bogus}, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := New()
+			router.Use(VerifyDigest(DigestConfig{}))
+			router.Post("/", func(http.ResponseWriter, *http.Request) {})
+			request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			for name, value := range test.header {
+				request.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package routerx
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+//go:embed docsui/swagger.html docsui/redoc.html
+var embeddedDocsUI embed.FS
+
+var docsUITemplates = template.Must(template.ParseFS(embeddedDocsUI, "docsui/*.html"))
+
+// DocsUIConfig configures Router.DocsUI.
+type DocsUIConfig struct {
+	// Info describes the API in the served OpenAPI document.
+	Info OpenAPIInfo
+
+	// Redoc serves a Redoc reference page instead of Swagger UI.
+	Redoc bool
+
+	// AssetsURL is the base URL of the swagger-ui-dist or redoc bundle
+	// assets. Defaults to the jsDelivr CDN; point it at self-hosted assets,
+	// e.g. served with Router.Static, for offline or locked-down
+	// deployments.
+	AssetsURL string
+
+	// Middlewares guard the page and the document, e.g. with basic
+	// authentication on staging. They run after the router's middlewares.
+	Middlewares []Middleware
+}
+
+// DocsUI serves an API reference page at prefix, Swagger UI by default or
+// Redoc, and the OpenAPI document generated by Router.OpenAPI at
+// prefix/openapi.json. Both are left out of the OpenAPI document and are
+// guarded by the configured middlewares in addition to the router's.
+//
+// Example:
+//
+//	router.DocsUI("/docs", routerx.DocsUIConfig{
+//	    Info:        routerx.OpenAPIInfo{Title: "Users API", Version: "1.0.0"},
+//	    Middlewares: []routerx.Middleware{requireStaffLogin},
+//	})
+func (router *Router) DocsUI(prefix string, configs ...DocsUIConfig) {
+	var config DocsUIConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	name, assetsURL := "swagger.html", "https://cdn.jsdelivr.net/npm/swagger-ui-dist@5"
+	if config.Redoc {
+		name, assetsURL = "redoc.html", "https://cdn.jsdelivr.net/npm/redoc@2/bundles"
+	}
+	if config.AssetsURL != "" {
+		assetsURL = strings.TrimSuffix(config.AssetsURL, "/")
+	}
+	title := config.Info.Title
+	if title == "" {
+		title = "API reference"
+	}
+
+	prefix = strings.TrimSuffix(cleanPath(prefix), "/")
+	specPath := prefix + "/openapi.json"
+	var page bytes.Buffer
+	err := docsUITemplates.ExecuteTemplate(&page, name, map[string]any{
+		"Title":     title,
+		"SpecURL":   specPath,
+		"AssetsURL": assetsURL,
+	})
+	if err != nil {
+		panic("routerx: render docs UI: " + err.Error())
+	}
+
+	middlewares := append(copyMiddlewares(router.middlewares), config.Middlewares...)
+	pagePath := prefix
+	if pagePath == "" {
+		pagePath = "/{$}"
+	}
+	ui := router.register("GET", pagePath, http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = responseWriter.Write(page.Bytes())
+	}), middlewares, nil)
+	spec := router.register("GET", specPath, router.OpenAPIHandler(config.Info), middlewares, nil)
+	ui.undocumented = true
+	spec.undocumented = true
+}
@@ -0,0 +1,130 @@
+package routerx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// EnvelopeMode selects what the Envelope middleware does with JSON responses.
+type EnvelopeMode int
+
+const (
+	// WrapEnvelope wraps responses in {"data": ..., "meta": ..., "error": ...}.
+	// Successful bodies become "data" and bodies of 4xx/5xx responses
+	// become "error".
+	WrapEnvelope EnvelopeMode = iota + 1
+
+	// UnwrapEnvelope strips a standard envelope produced by a backend,
+	// returning "error" for failed responses and "data" otherwise.
+	UnwrapEnvelope
+)
+
+// envelope is the standard response envelope.
+type envelope struct {
+	Data  json.RawMessage `json:"data"`
+	Meta  map[string]any  `json:"meta,omitempty"`
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// envelopeState is shared between the Envelope middleware and the handlers
+// it wraps through the request context.
+type envelopeState struct {
+	meta map[string]any
+	skip bool
+}
+
+type envelopeContextKey struct{}
+
+// Envelope returns a Middleware that converts JSON responses between bare
+// bodies and the standard envelope, so groups migrating between conventions
+// can serve both. Non-JSON responses pass through unchanged.
+//
+// Example:
+//
+//	v2 := router.Group("/v2").Use(routerx.Envelope(routerx.WrapEnvelope))
+//	v2.Get("/users", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    routerx.EnvelopeMeta(request, "total", len(users))
+//	    responseWriter.Header().Set("Content-Type", "application/json")
+//	    json.NewEncoder(responseWriter).Encode(users)
+//	})
+func Envelope(mode EnvelopeMode) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			state := &envelopeState{}
+			request = request.WithContext(context.WithValue(request.Context(), envelopeContextKey{}, state))
+
+			buffered := newBufferedResponseWriter()
+			next.ServeHTTP(buffered, request)
+
+			body := bytes.TrimSpace(buffered.body.Bytes())
+			if state.skip || len(body) == 0 || !isJSONContentType(buffered.header.Get("Content-Type")) {
+				buffered.flushTo(responseWriter, buffered.body.Bytes())
+				return
+			}
+
+			var converted []byte
+			var err error
+			if mode == WrapEnvelope {
+				wrapped := envelope{Meta: state.meta}
+				if buffered.status() >= http.StatusBadRequest {
+					wrapped.Data, wrapped.Error = json.RawMessage("null"), body
+				} else {
+					wrapped.Data = body
+				}
+				converted, err = json.Marshal(wrapped)
+			} else {
+				converted, err = unwrapEnvelope(body, buffered.status())
+			}
+			if err != nil {
+				buffered.flushTo(responseWriter, buffered.body.Bytes())
+				return
+			}
+			buffered.flushTo(responseWriter, append(converted, '\n'))
+		})
+	}
+}
+
+// unwrapEnvelope extracts the payload from an enveloped body. Bodies that are
+// not envelopes are returned unchanged.
+func unwrapEnvelope(body []byte, statusCode int) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body, nil
+	}
+	for key := range fields {
+		if key != "data" && key != "meta" && key != "error" {
+			return body, nil
+		}
+	}
+	errorBody, hasError := fields["error"]
+	if hasError && string(errorBody) != "null" && (statusCode >= http.StatusBadRequest || fields["data"] == nil) {
+		return errorBody, nil
+	}
+	if data, found := fields["data"]; found {
+		return data, nil
+	}
+	return body, nil
+}
+
+// EnvelopeMeta records a value for the "meta" member of the envelope written
+// by a WrapEnvelope middleware. It does nothing outside such a middleware.
+func EnvelopeMeta(request *http.Request, key string, value any) {
+	state, ok := request.Context().Value(envelopeContextKey{}).(*envelopeState)
+	if !ok {
+		return
+	}
+	if state.meta == nil {
+		state.meta = make(map[string]any)
+	}
+	state.meta[key] = value
+}
+
+// SkipEnvelope tells an enclosing Envelope middleware to pass the response
+// through unchanged, for handlers that already write the desired shape.
+func SkipEnvelope(request *http.Request) {
+	if state, ok := request.Context().Value(envelopeContextKey{}).(*envelopeState); ok {
+		state.skip = true
+	}
+}
@@ -0,0 +1,309 @@
+package routerx
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed errorpages/error.html
+var embeddedErrorPages embed.FS
+
+// ErrorPageText is the localized text of an error page.
+type ErrorPageText struct {
+	Title   string
+	Message string
+}
+
+// defaultErrorPageTexts holds the built-in translations, keyed by language and
+// status code. Status 0 holds the home and support link labels as Title and
+// Message.
+var defaultErrorPageTexts = map[string]map[int]ErrorPageText{
+	"en": {
+		0:                              {Title: "Back to the home page", Message: "Contact support"},
+		http.StatusNotFound:            {Title: "Page not found", Message: "The page you are looking for does not exist or has been moved."},
+		http.StatusInternalServerError: {Title: "Something went wrong", Message: "An unexpected error occurred. Please try again in a moment."},
+		http.StatusServiceUnavailable:  {Title: "Temporarily unavailable", Message: "We are performing maintenance or experiencing high load. Please try again shortly."},
+	},
+	"de": {
+		0:                              {Title: "Zur Startseite", Message: "Support kontaktieren"},
+		http.StatusNotFound:            {Title: "Seite nicht gefunden", Message: "Die gesuchte Seite existiert nicht oder wurde verschoben."},
+		http.StatusInternalServerError: {Title: "Etwas ist schiefgelaufen", Message: "Ein unerwarteter Fehler ist aufgetreten. Bitte versuchen Sie es gleich noch einmal."},
+		http.StatusServiceUnavailable:  {Title: "Vorübergehend nicht verfügbar", Message: "Wir führen Wartungsarbeiten durch oder sind stark ausgelastet. Bitte versuchen Sie es in Kürze erneut."},
+	},
+	"fr": {
+		0:                              {Title: "Retour à l'accueil", Message: "Contacter le support"},
+		http.StatusNotFound:            {Title: "Page introuvable", Message: "La page que vous recherchez n'existe pas ou a été déplacée."},
+		http.StatusInternalServerError: {Title: "Une erreur est survenue", Message: "Une erreur inattendue s'est produite. Veuillez réessayer dans un instant."},
+		http.StatusServiceUnavailable:  {Title: "Temporairement indisponible", Message: "Nous effectuons une maintenance ou subissons une forte charge. Veuillez réessayer sous peu."},
+	},
+	"es": {
+		0:                              {Title: "Volver al inicio", Message: "Contactar con soporte"},
+		http.StatusNotFound:            {Title: "Página no encontrada", Message: "La página que busca no existe o ha sido movida."},
+		http.StatusInternalServerError: {Title: "Algo salió mal", Message: "Se produjo un error inesperado. Inténtelo de nuevo en un momento."},
+		http.StatusServiceUnavailable:  {Title: "No disponible temporalmente", Message: "Estamos realizando tareas de mantenimiento o soportando una carga elevada. Inténtelo de nuevo en breve."},
+	},
+}
+
+// ErrorPagesConfig configures ErrorPages.
+type ErrorPagesConfig struct {
+	// Brand, LogoURL, PrimaryColor, and SupportURL customize the built-in
+	// template. PrimaryColor defaults to "#0969da".
+	Brand        string
+	LogoURL      string
+	PrimaryColor string
+	SupportURL   string
+
+	// Templates overrides the built-in template. For a status and language
+	// the first existing file of "404.de.html", "404.html", "error.de.html",
+	// and "error.html" is used. Templates receive the same data as the
+	// built-in one: Status, Title, Message, Lang, Brand, LogoURL,
+	// PrimaryColor, SupportURL, HomeLabel, SupportLabel, and Path.
+	Templates fs.FS
+
+	// Texts adds or replaces translations, keyed by language and status
+	// code. Adding a status code makes ErrorPages render pages for it.
+	// Status 0 holds the home and support link labels as Title and Message.
+	Texts map[string]map[int]ErrorPageText
+
+	// DefaultLanguage is used when the client accepts no available
+	// language. Defaults to "en".
+	DefaultLanguage string
+}
+
+// ErrorPages renders branded HTML error pages in the language negotiated from
+// the Accept-Language header. English, German, French, and Spanish texts for
+// 404, 500, and 503 are built in.
+type ErrorPages struct {
+	config    ErrorPagesConfig
+	texts     map[string]map[int]ErrorPageText
+	languages []string
+
+	mutex     sync.Mutex
+	templates map[string]*template.Template
+}
+
+// NewErrorPages creates ErrorPages from config.
+//
+// Example:
+//
+//	pages := routerx.NewErrorPages(routerx.ErrorPagesConfig{
+//	    Brand:        "Acme",
+//	    LogoURL:      "/assets/logo.svg",
+//	    PrimaryColor: "#d1242f",
+//	    Templates:    os.DirFS("templates/errors"),
+//	})
+//	router.NotFound(pages.NotFoundHandler())
+//	web := router.Group("/").Use(pages.Middleware())
+func NewErrorPages(config ErrorPagesConfig) *ErrorPages {
+	if config.PrimaryColor == "" {
+		config.PrimaryColor = "#0969da"
+	}
+	if config.DefaultLanguage == "" {
+		config.DefaultLanguage = "en"
+	}
+	pages := &ErrorPages{config: config, texts: make(map[string]map[int]ErrorPageText), templates: make(map[string]*template.Template)}
+	for _, catalog := range []map[string]map[int]ErrorPageText{defaultErrorPageTexts, config.Texts} {
+		for language, texts := range catalog {
+			if pages.texts[language] == nil {
+				pages.texts[language] = make(map[int]ErrorPageText)
+				pages.languages = append(pages.languages, language)
+			}
+			for status, text := range texts {
+				pages.texts[language][status] = text
+			}
+		}
+	}
+	return pages
+}
+
+// Has reports whether a page exists for status.
+func (pages *ErrorPages) Has(status int) bool {
+	_, found := pages.texts[pages.config.DefaultLanguage][status]
+	if !found {
+		_, found = pages.texts["en"][status]
+	}
+	return found
+}
+
+// Render writes the error page for status. Statuses without a page get a
+// plain text response.
+func (pages *ErrorPages) Render(responseWriter http.ResponseWriter, request *http.Request, status int) {
+	language := negotiateLanguage(request.Header.Get("Accept-Language"), pages.languages, pages.config.DefaultLanguage)
+	text, found := pages.text(language, status)
+	if !found {
+		http.Error(responseWriter, http.StatusText(status), status)
+		return
+	}
+	labels, _ := pages.text(language, 0)
+	data := map[string]any{
+		"Status":       status,
+		"Title":        text.Title,
+		"Message":      text.Message,
+		"Lang":         language,
+		"Brand":        pages.config.Brand,
+		"LogoURL":      pages.config.LogoURL,
+		"PrimaryColor": template.CSS(pages.config.PrimaryColor),
+		"SupportURL":   pages.config.SupportURL,
+		"HomeLabel":    labels.Title,
+		"SupportLabel": labels.Message,
+		"Path":         request.URL.Path,
+	}
+
+	var body bytes.Buffer
+	page, err := pages.template(status, language)
+	if err == nil {
+		err = page.Execute(&body, data)
+	}
+	if err != nil {
+		log.Printf("routerx: render error page %d: %v", status, err)
+		http.Error(responseWriter, http.StatusText(status), status)
+		return
+	}
+	header := responseWriter.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	header.Set("Cache-Control", "no-store")
+	addVary(header, "Accept-Language")
+	responseWriter.WriteHeader(status)
+	_, _ = responseWriter.Write(body.Bytes())
+}
+
+// text returns the text for status in language, falling back to the default
+// language and English.
+func (pages *ErrorPages) text(language string, status int) (ErrorPageText, bool) {
+	for _, candidate := range []string{language, pages.config.DefaultLanguage, "en"} {
+		if text, found := pages.texts[candidate][status]; found {
+			return text, true
+		}
+	}
+	return ErrorPageText{}, false
+}
+
+// template returns the parsed template for status and language.
+func (pages *ErrorPages) template(status int, language string) (*template.Template, error) {
+	code := strconv.Itoa(status)
+	candidates := []string{code + "." + language + ".html", code + ".html", "error." + language + ".html", "error.html"}
+	pages.mutex.Lock()
+	defer pages.mutex.Unlock()
+	key := code + "." + language
+	if parsed, found := pages.templates[key]; found {
+		return parsed, nil
+	}
+	var parsed *template.Template
+	var err error
+	if pages.config.Templates != nil {
+		for _, name := range candidates {
+			if _, statErr := fs.Stat(pages.config.Templates, name); statErr == nil {
+				parsed, err = template.ParseFS(pages.config.Templates, name)
+				break
+			}
+		}
+	}
+	if parsed == nil && err == nil {
+		parsed, err = template.ParseFS(embeddedErrorPages, "errorpages/error.html")
+	}
+	if err != nil {
+		return nil, err
+	}
+	pages.templates[key] = parsed
+	return parsed, nil
+}
+
+// NotFoundHandler returns a handler rendering the 404 page, for use with
+// Router.NotFound.
+func (pages *ErrorPages) NotFoundHandler() http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		pages.Render(responseWriter, request, http.StatusNotFound)
+	}
+}
+
+// ErrorHandler returns an ErrorHandlerFunc for Router.ErrorHandler that
+// renders error pages for clients accepting HTML and falls back to
+// DefaultErrorHandler otherwise.
+func (pages *ErrorPages) ErrorHandler() ErrorHandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request, err error) {
+		status := http.StatusInternalServerError
+		var coded interface{ StatusCode() int }
+		if errors.As(err, &coded) {
+			status = coded.StatusCode()
+		}
+		if !acceptsHTML(request) || !pages.Has(status) {
+			DefaultErrorHandler(responseWriter, request, err)
+			return
+		}
+		if status >= 500 {
+			log.Printf("routerx: %s %s: %v", request.Method, request.URL.Path, err)
+		}
+		pages.Render(responseWriter, request, status)
+	}
+}
+
+// Middleware returns a Middleware for HTML-serving groups that replaces the
+// body of responses with a status that has a page, e.g. a plain "404 page not
+// found" written by http.NotFound, with the rendered error page. Requests
+// that do not accept HTML are left untouched.
+func (pages *ErrorPages) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			if !acceptsHTML(request) {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			next.ServeHTTP(&errorPageWriter{ResponseWriter: responseWriter, pages: pages, request: request}, request)
+		})
+	}
+}
+
+// errorPageWriter renders an error page instead of the response written by
+// the handler when the status has a page.
+type errorPageWriter struct {
+	http.ResponseWriter
+	pages       *ErrorPages
+	request     *http.Request
+	wroteHeader bool
+	replaced    bool
+}
+
+func (writer *errorPageWriter) WriteHeader(status int) {
+	if writer.wroteHeader {
+		return
+	}
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		writer.ResponseWriter.WriteHeader(status)
+		return
+	}
+	writer.wroteHeader = true
+	if writer.pages.Has(status) {
+		writer.replaced = true
+		writer.pages.Render(writer.ResponseWriter, writer.request, status)
+		return
+	}
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *errorPageWriter) Write(data []byte) (int, error) {
+	if !writer.wroteHeader {
+		writer.WriteHeader(http.StatusOK)
+	}
+	if writer.replaced {
+		return len(data), nil
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *errorPageWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// acceptsHTML reports whether the client asks for an HTML response.
+func acceptsHTML(request *http.Request) bool {
+	return strings.Contains(request.Header.Get("Accept"), "text/html")
+}
@@ -0,0 +1,271 @@
+package routerx
+
+import (
+	"errors"
+	"log"
+	"net/http"
+)
+
+// HandlerFunc is a handler that reports failures by returning an error
+// instead of writing an error response itself. It is registered with the
+// E-suffixed methods such as Router.GetE, and returned errors are passed to
+// the router's error handler, see Router.ErrorHandler.
+type HandlerFunc func(responseWriter http.ResponseWriter, request *http.Request) error
+
+// ErrorHandlerFunc writes the response for an error returned by a
+// HandlerFunc.
+type ErrorHandlerFunc func(responseWriter http.ResponseWriter, request *http.Request, err error)
+
+// HTTPError is an error carrying the HTTP status code it should be answered
+// with.
+type HTTPError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+// NewHTTPError returns an HTTPError with the given status code and message.
+//
+// Example:
+//
+//	return routerx.NewHTTPError(http.StatusNotFound, "user not found")
+func NewHTTPError(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message}
+}
+
+func (err *HTTPError) Error() string {
+	if err.Message == "" {
+		return http.StatusText(err.Status)
+	}
+	return err.Message
+}
+
+// StatusCode returns the HTTP status code of the error.
+func (err *HTTPError) StatusCode() int {
+	return err.Status
+}
+
+// Unwrap returns the underlying error.
+func (err *HTTPError) Unwrap() error {
+	return err.Err
+}
+
+// ErrorHandler sets the function that writes the response for errors returned
+// by HandlerFunc handlers registered on the router, its groups, and its path
+// builders, so that status codes, logging, and the response shape are decided
+// in one place. It applies to routes registered before and after the call.
+// ErrorHandler returns the Router to support chaining.
+//
+// Without an error handler, DefaultErrorHandler is used.
+//
+// Example:
+//
+//	router.ErrorHandler(func(responseWriter http.ResponseWriter, request *http.Request, err error) {
+//	    if errors.Is(err, sql.ErrNoRows) {
+//	        err = routerx.NewHTTPError(http.StatusNotFound, "not found")
+//	    }
+//	    routerx.DefaultErrorHandler(responseWriter, request, err)
+//	})
+//	router.GetE("/users/{id}", func(responseWriter http.ResponseWriter, request *http.Request) error {
+//	    user, err := store.User(request.Context(), request.PathValue("id"))
+//	    if err != nil {
+//	        return err
+//	    }
+//	    routerx.Render(responseWriter, request, http.StatusOK, user)
+//	    return nil
+//	})
+func (router *Router) ErrorHandler(handler ErrorHandlerFunc) *Router {
+	router.errorHandler = handler
+	return router
+}
+
+// DefaultErrorHandler answers errors implementing StatusCode() int with that
+// status and the error message as {"error": message}. A *ValidationError also
+// lists its field errors under "fields", and a *GoneError the deletion time
+// under "deleted_at". A *Problem is rendered as application/problem+json,
+// see ProblemErrorHandler for answering every error that way. Other errors
+// and server errors are logged and answered with a generic 500 Internal
+// Server Error, so that internal details do not leak to clients, and so are
+// status codes outside 100 to 599.
+func DefaultErrorHandler(responseWriter http.ResponseWriter, request *http.Request, err error) {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		ProblemErrorHandler(responseWriter, request, err)
+		return
+	}
+	status := errorStatus(err)
+	if status >= 500 {
+		log.Printf("routerx: %s %s: %v", request.Method, request.URL.Path, err)
+		writeError(responseWriter, status, http.StatusText(status))
+		return
+	}
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		writeJSON(responseWriter, status, map[string]any{"error": "validation failed", "fields": validationError.Fields})
+		return
+	}
+	var goneError *GoneError
+	if errors.As(err, &goneError) && !goneError.DeletedAt.IsZero() {
+		writeJSON(responseWriter, status, map[string]any{"error": err.Error(), "deleted_at": goneError.DeletedAt})
+		return
+	}
+	writeError(responseWriter, status, err.Error())
+}
+
+// errorStatus returns the status code of err if it implements
+// StatusCode() int with a valid code, and 500 Internal Server Error
+// otherwise.
+func errorStatus(err error) int {
+	var coded interface{ StatusCode() int }
+	if errors.As(err, &coded) {
+		if status := coded.StatusCode(); status >= 100 && status <= 599 {
+			return status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// handleError returns an http.HandlerFunc that runs handler and passes
+// returned errors to the router's error handler.
+func (router *Router) handleError(handler HandlerFunc) http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		err := handler(responseWriter, request)
+		if err == nil {
+			return
+		}
+		if router.errorHandler != nil {
+			router.errorHandler(responseWriter, request, err)
+			return
+		}
+		DefaultErrorHandler(responseWriter, request, err)
+	}
+}
+
+type errorHandlerContextKey struct{}
+
+// HandleError answers err with the error handler of the router serving the
+// request, see Router.ErrorHandler, or with DefaultErrorHandler. Middlewares
+// use it to reject requests the same way handlers returning errors are
+// answered.
+//
+// Example:
+//
+//	if !allowed(request) {
+//	    routerx.HandleError(responseWriter, request, routerx.NewHTTPError(http.StatusForbidden, "not allowed"))
+//	    return
+//	}
+func HandleError(responseWriter http.ResponseWriter, request *http.Request, err error) {
+	if handler, ok := request.Context().Value(errorHandlerContextKey{}).(ErrorHandlerFunc); ok {
+		handler(responseWriter, request, err)
+		return
+	}
+	DefaultErrorHandler(responseWriter, request, err)
+}
+
+func (router *Router) GetE(path string, handler HandlerFunc) {
+	router.Get(path, router.handleError(handler))
+}
+
+func (router *Router) PostE(path string, handler HandlerFunc) {
+	router.Post(path, router.handleError(handler))
+}
+
+func (router *Router) PatchE(path string, handler HandlerFunc) {
+	router.Patch(path, router.handleError(handler))
+}
+
+func (router *Router) DeleteE(path string, handler HandlerFunc) {
+	router.Delete(path, router.handleError(handler))
+}
+
+func (router *Router) HeadE(path string, handler HandlerFunc) {
+	router.Head(path, router.handleError(handler))
+}
+
+func (router *Router) PutE(path string, handler HandlerFunc) {
+	router.Put(path, router.handleError(handler))
+}
+
+func (router *Router) OptionsE(path string, handler HandlerFunc) {
+	router.Options(path, router.handleError(handler))
+}
+
+func (router *Router) ConnectE(path string, handler HandlerFunc) {
+	router.Connect(path, router.handleError(handler))
+}
+
+func (router *Router) TraceE(path string, handler HandlerFunc) {
+	router.Trace(path, router.handleError(handler))
+}
+
+func (group *RouteGroup) GetE(path string, handler HandlerFunc) {
+	group.Get(path, group.router.handleError(handler))
+}
+
+func (group *RouteGroup) PostE(path string, handler HandlerFunc) {
+	group.Post(path, group.router.handleError(handler))
+}
+
+func (group *RouteGroup) PatchE(path string, handler HandlerFunc) {
+	group.Patch(path, group.router.handleError(handler))
+}
+
+func (group *RouteGroup) DeleteE(path string, handler HandlerFunc) {
+	group.Delete(path, group.router.handleError(handler))
+}
+
+func (group *RouteGroup) HeadE(path string, handler HandlerFunc) {
+	group.Head(path, group.router.handleError(handler))
+}
+
+func (group *RouteGroup) PutE(path string, handler HandlerFunc) {
+	group.Put(path, group.router.handleError(handler))
+}
+
+func (group *RouteGroup) OptionsE(path string, handler HandlerFunc) {
+	group.Options(path, group.router.handleError(handler))
+}
+
+func (group *RouteGroup) ConnectE(path string, handler HandlerFunc) {
+	group.Connect(path, group.router.handleError(handler))
+}
+
+func (group *RouteGroup) TraceE(path string, handler HandlerFunc) {
+	group.Trace(path, group.router.handleError(handler))
+}
+
+func (builder *PathBuilder) GetE(handler HandlerFunc) *PathBuilder {
+	return builder.Get(builder.router.handleError(handler))
+}
+
+func (builder *PathBuilder) PostE(handler HandlerFunc) *PathBuilder {
+	return builder.Post(builder.router.handleError(handler))
+}
+
+func (builder *PathBuilder) PatchE(handler HandlerFunc) *PathBuilder {
+	return builder.Patch(builder.router.handleError(handler))
+}
+
+func (builder *PathBuilder) DeleteE(handler HandlerFunc) *PathBuilder {
+	return builder.Delete(builder.router.handleError(handler))
+}
+
+func (builder *PathBuilder) HeadE(handler HandlerFunc) *PathBuilder {
+	return builder.Head(builder.router.handleError(handler))
+}
+
+func (builder *PathBuilder) PutE(handler HandlerFunc) *PathBuilder {
+	return builder.Put(builder.router.handleError(handler))
+}
+
+func (builder *PathBuilder) OptionsE(handler HandlerFunc) *PathBuilder {
+	return builder.Options(builder.router.handleError(handler))
+}
+
+func (builder *PathBuilder) ConnectE(handler HandlerFunc) *PathBuilder {
+	return builder.Connect(builder.router.handleError(handler))
+}
+
+func (builder *PathBuilder) TraceE(handler HandlerFunc) *PathBuilder {
+	return builder.Trace(builder.router.handleError(handler))
+}
@@ -0,0 +1,46 @@
+package routerx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDefaultErrorHandlerStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"HTTPError", NewHTTPError(http.StatusNotFound, "user not found"), http.StatusNotFound},
+		{"plain error", errors.New("boom"), http.StatusInternalServerError},
+		{"empty HTTPError", &HTTPError{}, http.StatusInternalServerError},
+		{"zero status", NewHTTPError(0, "no status"), http.StatusInternalServerError},
+		{"status below 100", NewHTTPError(42, "too low"), http.StatusInternalServerError},
+		{"status above 599", NewHTTPError(600, "too high"), http.StatusInternalServerError},
+		{"Problem with invalid status", &Problem{Status: 1000}, http.StatusInternalServerError},
+	}
+	for _, handler := range []struct {
+		name   string
+		handle ErrorHandlerFunc
+	}{
+		{"DefaultErrorHandler", DefaultErrorHandler},
+		{"ProblemErrorHandler", ProblemErrorHandler},
+	} {
+		for _, test := range tests {
+			t.Run(handler.name+"/"+test.name, func(t *testing.T) {
+				router := New()
+				router.ErrorHandler(handler.handle)
+				router.GetE("/", func(http.ResponseWriter, *http.Request) error {
+					return test.err
+				})
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+				if recorder.Code != test.wantStatus {
+					t.Errorf("status = %d, want %d", recorder.Code, test.wantStatus)
+				}
+			})
+		}
+	}
+}
@@ -1,17 +1,17 @@
 package main
 
 import (
-	"encoding/json"
 	"log"
+	"log/slog"
 	"net/http"
-	"time"
+	"os"
 
 	"github.com/Mark-Bazylev/routerx"
 )
 
 func main() {
 	router := routerx.New().
-		Use(LoggingMiddleware)
+		Use(routerx.Logger(slog.NewTextHandler(os.Stderr, nil)))
 
 	apiV1 := router.
 		Group("/api").
@@ -32,7 +32,7 @@ func main() {
 func getUserHandler(responseWriter http.ResponseWriter, request *http.Request) {
 	userID := request.PathValue("id")
 
-	JSON(responseWriter, http.StatusOK, map[string]any{
+	routerx.JSON(responseWriter, http.StatusOK, map[string]any{
 		"id":      userID,
 		"message": "fetched user by id",
 	})
@@ -42,7 +42,7 @@ func getUserHandler(responseWriter http.ResponseWriter, request *http.Request) {
 func updateUserHandler(responseWriter http.ResponseWriter, request *http.Request) {
 	userID := request.PathValue("id")
 
-	JSON(responseWriter, http.StatusOK, map[string]any{
+	routerx.JSON(responseWriter, http.StatusOK, map[string]any{
 		"id":      userID,
 		"message": "updated user by id (demo only)",
 	})
@@ -52,27 +52,8 @@ func updateUserHandler(responseWriter http.ResponseWriter, request *http.Request
 func deleteUserHandler(responseWriter http.ResponseWriter, request *http.Request) {
 	userID := request.PathValue("id")
 
-	JSON(responseWriter, http.StatusOK, map[string]any{
+	routerx.JSON(responseWriter, http.StatusOK, map[string]any{
 		"id":      userID,
 		"message": "deleted user by id (demo only)",
 	})
 }
-
-// LoggingMiddleware prints each request with execution time.
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
-		startTime := time.Now()
-		log.Printf(">> %s %s", request.Method, request.URL.Path)
-		next.ServeHTTP(responseWriter, request)
-		log.Printf("<< %s %s (%s)", request.Method, request.URL.Path, time.Since(startTime))
-	})
-}
-
-func JSON(responseWriter http.ResponseWriter, statusCode int, data any) {
-	responseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
-	responseWriter.WriteHeader(statusCode)
-
-	if err := json.NewEncoder(responseWriter).Encode(data); err != nil {
-		log.Println("JSON encode error:", err)
-	}
-}
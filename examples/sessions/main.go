@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/Mark-Bazylev/routerx"
+)
+
+func main() {
+	// Derive the 32 byte session key from a secret
+	key := sha256.Sum256([]byte(os.Getenv("SESSION_SECRET")))
+
+	config := routerx.SessionConfig{
+		Keys:    [][]byte{key[:]},
+		Encrypt: true,
+	}
+
+	// Keep sessions in Redis when REDIS_ADDR is set, e.g. localhost:6379,
+	// otherwise in the cookie
+	if address := os.Getenv("REDIS_ADDR"); address != "" {
+		config.Store = NewRedisStore(address)
+	}
+
+	router := routerx.New().Use(routerx.Sessions(config))
+
+	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		session := routerx.GetSession(r)
+		var visits int
+		session.Get("visits", &visits)
+		session.Set("visits", visits+1)
+
+		for _, message := range session.Flashes() {
+			fmt.Fprintln(w, message)
+		}
+		fmt.Fprintf(w, "Hello %s, visit %d\n", session.GetString("name"), visits+1)
+	})
+
+	router.Get("/login/{name}", func(w http.ResponseWriter, r *http.Request) {
+		session := routerx.GetSession(r)
+		session.Renew()
+		session.Set("name", r.PathValue("name"))
+		session.Flash("Logged in")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	router.Get("/logout", func(w http.ResponseWriter, r *http.Request) {
+		routerx.GetSession(r).Destroy()
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	log.Println("listening on :8080")
+	log.Fatal(http.ListenAndServe(":8080", router))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisStore is a routerx.SessionStore keeping sessions in Redis. It speaks
+// the Redis protocol over a single connection to stay dependency free; a
+// production store would wrap a client library such as go-redis instead.
+type RedisStore struct {
+	address string
+	prefix  string
+
+	mutex      sync.Mutex
+	connection net.Conn
+	reader     *bufio.Reader
+}
+
+// NewRedisStore creates a RedisStore for the server at address, keeping
+// sessions under the "session:" key prefix.
+func NewRedisStore(address string) *RedisStore {
+	return &RedisStore{address: address, prefix: "session:"}
+}
+
+// Load returns the session data of id, or nil when it expired.
+func (store *RedisStore) Load(ctx context.Context, id string) ([]byte, error) {
+	return store.do(ctx, "GET", store.prefix+id)
+}
+
+// Save stores the session data of id with the given time to live.
+func (store *RedisStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	milliseconds := strconv.FormatInt(ttl.Milliseconds(), 10)
+	_, err := store.do(ctx, "SET", store.prefix+id, string(data), "PX", milliseconds)
+	return err
+}
+
+// Delete removes the session data of id.
+func (store *RedisStore) Delete(ctx context.Context, id string) error {
+	_, err := store.do(ctx, "DEL", store.prefix+id)
+	return err
+}
+
+// do sends a command and returns its reply, reconnecting after failures.
+func (store *RedisStore) do(ctx context.Context, arguments ...string) ([]byte, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.connection == nil {
+		var dialer net.Dialer
+		connection, err := dialer.DialContext(ctx, "tcp", store.address)
+		if err != nil {
+			return nil, err
+		}
+		store.connection = connection
+		store.reader = bufio.NewReader(connection)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = store.connection.SetDeadline(deadline)
+	} else {
+		_ = store.connection.SetDeadline(time.Now().Add(5 * time.Second))
+	}
+
+	command := fmt.Sprintf("*%d\r\n", len(arguments))
+	for _, argument := range arguments {
+		command += fmt.Sprintf("$%d\r\n%s\r\n", len(argument), argument)
+	}
+	reply, err := store.send(command)
+	var redisError redisError
+	if err != nil && !errors.As(err, &redisError) {
+		store.connection.Close()
+		store.connection = nil
+	}
+	return reply, err
+}
+
+func (store *RedisStore) send(command string) ([]byte, error) {
+	if _, err := io.WriteString(store.connection, command); err != nil {
+		return nil, err
+	}
+	line, err := store.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+', ':':
+		return []byte(line), nil
+	case '-':
+		return nil, redisError(line)
+	case '$':
+		length, err := strconv.Atoi(line)
+		if err != nil || length < 0 {
+			return nil, err
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(store.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:length], nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+// redisError is an error reply, after which the connection stays usable.
+type redisError string
+
+func (err redisError) Error() string {
+	return "redis: " + string(err)
+}
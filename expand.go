@@ -0,0 +1,236 @@
+package routerx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Expander loads the related resource of a single item. The item is the JSON
+// object being expanded, e.g. a post for the "author" relation, and the
+// returned value replaces the relation field of that item.
+type Expander func(ctx context.Context, item map[string]any) (any, error)
+
+// Expansions implements "?expand=author,comments.author" semantics for REST
+// resources. Handlers register one Expander per relation path and call
+// Expand with the requested relations; relations on the same level are
+// resolved concurrently.
+//
+// Example:
+//
+//	expansions := routerx.NewExpansions().
+//	    Register("author", loadAuthor).
+//	    Register("comments", loadComments).
+//	    Register("comments.author", loadAuthor)
+//
+//	router.Get("/posts/{id}", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    post, err := expansions.Expand(request.Context(), routerx.ExpandParam(request), loadPost(request))
+//	    ...
+//	})
+type Expansions struct {
+	// MaxDepth limits how many relations a relation path may chain, so that
+	// the default of 3 allows "comments.author.avatar" but not one level
+	// more. Defaults to 3.
+	MaxDepth int
+
+	// MaxConcurrency limits how many expanders run at the same time for one
+	// Expand call. Defaults to 8.
+	MaxConcurrency int
+
+	expanders map[string]Expander
+}
+
+// NewExpansions creates an empty set of expanders with default limits.
+func NewExpansions() *Expansions {
+	return &Expansions{
+		MaxDepth:       3,
+		MaxConcurrency: 8,
+		expanders:      make(map[string]Expander),
+	}
+}
+
+// Register adds the expander for a relation path such as "author" or
+// "comments.author". It returns the Expansions to support chaining.
+func (expansions *Expansions) Register(relation string, expander Expander) *Expansions {
+	expansions.expanders[relation] = expander
+	return expansions
+}
+
+// ExpandParam returns the relations requested with the expand query
+// parameter. Both "?expand=a,b" and "?expand=a&expand=b" are accepted.
+func ExpandParam(request *http.Request) []string {
+	var relations []string
+	for _, value := range request.URL.Query()["expand"] {
+		for _, relation := range strings.Split(value, ",") {
+			if relation = strings.TrimSpace(relation); relation != "" {
+				relations = append(relations, relation)
+			}
+		}
+	}
+	return relations
+}
+
+// expandNode is one relation in the tree of requested expansions.
+type expandNode struct {
+	name     string
+	path     string
+	children []*expandNode
+}
+
+// Expand resolves the requested relations on document and returns the
+// expanded document. document may be a JSON object, an array of objects, or
+// any value that encodes to one. Unknown relations and relations deeper than
+// MaxDepth are reported as errors before any expander runs.
+func (expansions *Expansions) Expand(ctx context.Context, requested []string, document any) (any, error) {
+	root := &expandNode{}
+	for _, relation := range requested {
+		names := strings.Split(relation, ".")
+		if expansions.MaxDepth > 0 && len(names) > expansions.MaxDepth {
+			return nil, fmt.Errorf("routerx: expansion %q exceeds the maximum depth of %d", relation, expansions.MaxDepth)
+		}
+		node := root
+		for index, name := range names {
+			path := strings.Join(names[:index+1], ".")
+			if _, found := expansions.expanders[path]; !found {
+				return nil, fmt.Errorf("routerx: unknown expansion %q", path)
+			}
+			node = node.child(name, path)
+		}
+	}
+	if len(root.children) == 0 {
+		return document, nil
+	}
+
+	document, err := normalizeJSON(document)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := expansions.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	run := &expandRun{expanders: expansions.expanders, limit: make(chan struct{}, concurrency)}
+	ctx, run.cancel = context.WithCancel(ctx)
+	defer run.cancel()
+
+	run.expand(ctx, root.children, document)
+	run.wait.Wait()
+	if run.err == nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return document, run.err
+}
+
+func (node *expandNode) child(name string, path string) *expandNode {
+	for _, child := range node.children {
+		if child.name == name {
+			return child
+		}
+	}
+	child := &expandNode{name: name, path: path}
+	node.children = append(node.children, child)
+	return child
+}
+
+// expandRun holds the state of a single Expand call.
+type expandRun struct {
+	expanders map[string]Expander
+	limit     chan struct{}
+	wait      sync.WaitGroup
+	cancel    context.CancelFunc
+
+	mutex sync.Mutex
+	err   error
+}
+
+// expand resolves nodes on every object in value. Each expander runs in its
+// own goroutine on a deep copy of the object, since sibling expansions keep
+// writing into it, and expands the children of its node once it has
+// finished.
+func (run *expandRun) expand(ctx context.Context, nodes []*expandNode, value any) {
+	switch typed := value.(type) {
+	case []any:
+		for _, element := range typed {
+			run.expand(ctx, nodes, element)
+		}
+	case map[string]any:
+		for _, node := range nodes {
+			run.wait.Add(1)
+			go func() {
+				defer run.wait.Done()
+				select {
+				case run.limit <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				run.mutex.Lock()
+				item := cloneJSON(typed).(map[string]any)
+				run.mutex.Unlock()
+				result, err := run.expanders[node.path](ctx, item)
+				<-run.limit
+				if err != nil {
+					run.fail(fmt.Errorf("routerx: expand %q: %w", node.path, err))
+					return
+				}
+				if result, err = normalizeJSON(result); err != nil {
+					run.fail(err)
+					return
+				}
+				run.mutex.Lock()
+				typed[node.name] = result
+				run.mutex.Unlock()
+				run.expand(ctx, node.children, result)
+			}()
+		}
+	}
+}
+
+func (run *expandRun) fail(err error) {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+	if run.err == nil {
+		run.err = err
+		run.cancel()
+	}
+}
+
+// cloneJSON returns a deep copy of a value in the generic representation
+// produced by encoding/json.
+func cloneJSON(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		clone := make(map[string]any, len(typed))
+		for key, element := range typed {
+			clone[key] = cloneJSON(element)
+		}
+		return clone
+	case []any:
+		clone := make([]any, len(typed))
+		for index, element := range typed {
+			clone[index] = cloneJSON(element)
+		}
+		return clone
+	}
+	return value
+}
+
+// normalizeJSON converts value into the generic representation produced by
+// encoding/json, so that structs can be expanded like decoded objects.
+func normalizeJSON(value any) (any, error) {
+	switch value.(type) {
+	case nil, map[string]any, []any, string, bool, float64, json.Number:
+		return value, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized any
+	if err := json.Unmarshal(encoded, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
@@ -0,0 +1,181 @@
+package routerx
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Explanation describes how the router would handle a request. It is returned
+// by Router.Explain.
+type Explanation struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Matched bool   `json:"matched"`
+
+	// Pattern and Name identify the selected route.
+	Pattern string `json:"pattern,omitempty"`
+	Name    string `json:"name,omitempty"`
+
+	// Params holds the path parameters the route would receive.
+	Params map[string]string `json:"params,omitempty"`
+
+	// Middlewares lists the middlewares of the selected route from the
+	// outermost to the innermost.
+	Middlewares []string `json:"middlewares,omitempty"`
+
+	// Candidates lists the routes sharing the matched ServeMux pattern in the
+	// order they are tried, with the constraints evaluated for each.
+	Candidates []ExplainedCandidate `json:"candidates,omitempty"`
+
+	// Allowed lists the methods registered for the path when the request
+	// method has no route.
+	Allowed []string `json:"allowed,omitempty"`
+}
+
+// ExplainedCandidate is a route tried while matching a request.
+type ExplainedCandidate struct {
+	Pattern     string                `json:"pattern"`
+	Selected    bool                  `json:"selected"`
+	Constraints []ExplainedConstraint `json:"constraints,omitempty"`
+}
+
+// ExplainedConstraint is a path parameter constraint evaluated for a
+// candidate route.
+type ExplainedConstraint struct {
+	Param      string `json:"param"`
+	Expression string `json:"expression"`
+	Value      string `json:"value"`
+	Matched    bool   `json:"matched"`
+}
+
+// Explain reports which route would serve a request with the given method and
+// path, the middlewares that would run in order, and the constraints
+// evaluated on the way. It does not run any handler.
+func (router *Router) Explain(method string, path string) Explanation {
+	explanation := Explanation{Method: method, Path: path}
+	target, err := url.Parse(path)
+	if err != nil {
+		return explanation
+	}
+	probe := &http.Request{Method: method, URL: target, Host: "localhost", Header: make(http.Header)}
+	_, pattern := router.mux.Handler(probe)
+	if pattern == "" {
+		explanation.Allowed = router.allowedMethods(probe)
+		return explanation
+	}
+
+	patternMethod, patternPath, found := strings.Cut(pattern, " ")
+	if !found {
+		patternMethod, patternPath = "", pattern
+	}
+	shape := router.shapes[patternMethod+" "+routeShapeOf(patternPath)]
+	if shape == nil {
+		return explanation
+	}
+	values := wildcardValues(patternPath, target.Path)
+	for _, candidate := range shape.routes {
+		explained := ExplainedCandidate{Pattern: candidate.pattern}
+		matched := true
+		for index, name := range candidate.wildcards {
+			expression, constrained := candidate.constraints[name]
+			if !constrained || index >= len(values) {
+				continue
+			}
+			ok := expression.MatchString(values[index])
+			matched = matched && ok
+			explained.Constraints = append(explained.Constraints, ExplainedConstraint{
+				Param:      name,
+				Expression: expression.String(),
+				Value:      values[index],
+				Matched:    ok,
+			})
+		}
+		if matched && !explanation.Matched {
+			explained.Selected = true
+			explanation.Matched = true
+			explanation.Pattern = candidate.pattern
+			explanation.Name = candidate.name
+			for index, name := range candidate.wildcards {
+				if index < len(values) {
+					if explanation.Params == nil {
+						explanation.Params = make(map[string]string)
+					}
+					explanation.Params[name] = values[index]
+				}
+			}
+			for _, middleware := range candidate.middlewares {
+				explanation.Middlewares = append(explanation.Middlewares, functionName(middleware))
+			}
+		}
+		explanation.Candidates = append(explanation.Candidates, explained)
+	}
+	return explanation
+}
+
+// ExplainHandler returns a handler answering
+// GET ?method=POST&path=/api/v1/users with the JSON encoded Explanation of
+// that request. It exposes the internals of the route table and is meant for
+// development only.
+//
+// Example:
+//
+//	if development {
+//	    router.Get("/_routerx/explain", router.ExplainHandler())
+//	}
+func (router *Router) ExplainHandler() http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+		method := query.Get("method")
+		if method == "" {
+			method = http.MethodGet
+		}
+		path := query.Get("path")
+		if !strings.HasPrefix(path, "/") {
+			writeError(responseWriter, http.StatusBadRequest, "the path query parameter must be an absolute path")
+			return
+		}
+		writeJSON(responseWriter, http.StatusOK, router.Explain(strings.ToUpper(method), path))
+	}
+}
+
+// wildcardValues returns the values of the wildcards of a ServeMux path for a
+// request path the mux matched against it.
+func wildcardValues(muxPath string, requestPath string) []string {
+	var values []string
+	patternSegments := strings.Split(strings.TrimPrefix(muxPath, "/"), "/")
+	requestSegments := strings.Split(strings.TrimPrefix(requestPath, "/"), "/")
+	for index, segment := range patternSegments {
+		if !strings.HasPrefix(segment, "{") || segment == "{$}" {
+			continue
+		}
+		if strings.HasSuffix(segment, "...}") {
+			if index < len(requestSegments) {
+				values = append(values, strings.Join(requestSegments[index:], "/"))
+			} else {
+				values = append(values, "")
+			}
+			break
+		}
+		if index < len(requestSegments) {
+			value, err := url.PathUnescape(requestSegments[index])
+			if err != nil {
+				value = requestSegments[index]
+			}
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// functionName returns the package qualified name of a function, e.g.
+// "routerx.CORS.func1".
+func functionName(function any) string {
+	name := runtime.FuncForPC(reflect.ValueOf(function).Pointer()).Name()
+	if index := strings.LastIndexByte(name, '/'); index >= 0 {
+		name = name[index+1:]
+	}
+	return name
+}
@@ -0,0 +1,396 @@
+package routerx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FixtureConfig configures a FixtureRecorder.
+type FixtureConfig struct {
+	// Dir is the directory Save writes the fixtures to and Load reads them
+	// from, one JSON file per route, so that they can be committed and
+	// served as examples in production.
+	Dir string
+
+	// RedactHeaders lists headers whose values are replaced with
+	// "[REDACTED]". Authorization, Cookie, Set-Cookie, Proxy-Authorization,
+	// and X-Api-Key are always redacted.
+	RedactHeaders []string
+
+	// RedactFields lists JSON object keys, at any depth, query parameters,
+	// and path wildcards whose values are replaced with "[REDACTED]",
+	// ignoring case. Defaults to password, secret, token, access_token,
+	// refresh_token, and api_key.
+	RedactFields []string
+}
+
+// Fixture is a captured request and response of a route, see
+// FixtureRecorder.
+type Fixture struct {
+	// Pattern is the ServeMux pattern of the route, e.g.
+	// "GET /users/{id}".
+	Pattern  string         `json:"pattern"`
+	Status   int            `json:"status"`
+	Request  FixtureMessage `json:"request"`
+	Response FixtureMessage `json:"response"`
+}
+
+// FixtureMessage is the sanitized request or response of a Fixture. Body is
+// only kept for JSON messages.
+type FixtureMessage struct {
+	Path   string          `json:"path,omitempty"`
+	Query  string          `json:"query,omitempty"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// FixtureRecorder captures real requests and responses of every route, one
+// per route and status, sanitized of credentials and secrets, as examples
+// for documentation. Router.OpenAPI embeds them as examples when the
+// recorder is set as OpenAPIInfo.Examples. Record in development or in
+// integration tests, where the traffic is realistic but not sensitive, and
+// Save the fixtures; production loads them with Load.
+type FixtureRecorder struct {
+	config FixtureConfig
+
+	mutex    sync.Mutex
+	fixtures map[string]map[int]Fixture
+}
+
+var (
+	defaultRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"}
+	defaultRedactedFields  = []string{"password", "secret", "token", "access_token", "refresh_token", "api_key"}
+)
+
+// NewFixtureRecorder creates a FixtureRecorder from config.
+//
+// Example:
+//
+//	fixtures := routerx.NewFixtureRecorder(routerx.FixtureConfig{Dir: "docs/fixtures"})
+//	if development {
+//	    router.Use(fixtures.Middleware())
+//	    router.OnShutdown(func(context.Context) error { return fixtures.Save() })
+//	} else if err := fixtures.Load(); err != nil {
+//	    log.Print(err)
+//	}
+//	router.DocsUI("/docs", routerx.DocsUIConfig{Info: routerx.OpenAPIInfo{Title: "Users API", Version: "1.0.0", Examples: fixtures}})
+func NewFixtureRecorder(config FixtureConfig) *FixtureRecorder {
+	config.RedactHeaders = append(slices.Clone(config.RedactHeaders), defaultRedactedHeaders...)
+	if len(config.RedactFields) == 0 {
+		config.RedactFields = defaultRedactedFields
+	}
+	return &FixtureRecorder{config: config, fixtures: make(map[string]map[int]Fixture)}
+}
+
+// Middleware returns a Middleware capturing the first request and response
+// of every route and status. Bodies larger than 1 MiB are left out. Use it
+// on the router so that the route pattern is known.
+func (recorder *FixtureRecorder) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			body := &capturedBody{source: request.Body}
+			if request.Body != nil && request.Body != http.NoBody {
+				request.Body = body
+			}
+			response := &responseRecorder{ResponseWriter: responseWriter}
+			next.ServeHTTP(response, request)
+			if request.Pattern == "" || response.status == 0 || recorder.has(request.Pattern, response.status) {
+				return
+			}
+			fixture := Fixture{
+				Pattern: request.Pattern,
+				Status:  response.status,
+				Request: FixtureMessage{
+					Path:   recorder.path(request),
+					Query:  recorder.query(request.URL.RawQuery),
+					Header: recorder.header(request.Header),
+				},
+				Response: FixtureMessage{Header: recorder.header(responseWriter.Header())},
+			}
+			if !body.overflow {
+				fixture.Request.Body = recorder.body(request.Header, body.buffer.Bytes())
+			}
+			if !response.overflow {
+				fixture.Response.Body = recorder.body(responseWriter.Header(), response.body.Bytes())
+			}
+			recorder.add(fixture)
+		})
+	}
+}
+
+// Fixtures returns the captured fixtures, ordered by pattern and status.
+func (recorder *FixtureRecorder) Fixtures() []Fixture {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	var fixtures []Fixture
+	for _, byStatus := range recorder.fixtures {
+		for _, fixture := range byStatus {
+			fixtures = append(fixtures, fixture)
+		}
+	}
+	slices.SortFunc(fixtures, func(left, right Fixture) int {
+		if left.Pattern != right.Pattern {
+			return strings.Compare(left.Pattern, right.Pattern)
+		}
+		return left.Status - right.Status
+	})
+	return fixtures
+}
+
+// Save writes the fixtures to the configured directory, one file per route
+// named after its pattern, e.g. "GET_users_{id}.json".
+func (recorder *FixtureRecorder) Save() error {
+	if recorder.config.Dir == "" {
+		return errors.New("routerx: FixtureConfig.Dir is not set")
+	}
+	if err := os.MkdirAll(recorder.config.Dir, 0o755); err != nil {
+		return err
+	}
+	byPattern := make(map[string][]Fixture)
+	for _, fixture := range recorder.Fixtures() {
+		byPattern[fixture.Pattern] = append(byPattern[fixture.Pattern], fixture)
+	}
+	for pattern, fixtures := range byPattern {
+		data, err := json.MarshalIndent(fixtures, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(recorder.config.Dir, fixtureFileName(pattern)), append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads the fixtures saved in the configured directory.
+func (recorder *FixtureRecorder) Load() error {
+	if recorder.config.Dir == "" {
+		return errors.New("routerx: FixtureConfig.Dir is not set")
+	}
+	files, err := filepath.Glob(filepath.Join(recorder.config.Dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var fixtures []Fixture
+		if err := json.Unmarshal(data, &fixtures); err != nil {
+			return errors.New("routerx: load fixtures " + file + ": " + err.Error())
+		}
+		for _, fixture := range fixtures {
+			recorder.add(fixture)
+		}
+	}
+	return nil
+}
+
+// route returns the fixtures of a route pattern by status.
+func (recorder *FixtureRecorder) route(pattern string) map[int]Fixture {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	fixtures := make(map[int]Fixture, len(recorder.fixtures[pattern]))
+	for status, fixture := range recorder.fixtures[pattern] {
+		fixtures[status] = fixture
+	}
+	return fixtures
+}
+
+func (recorder *FixtureRecorder) has(pattern string, status int) bool {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	_, found := recorder.fixtures[pattern][status]
+	return found
+}
+
+func (recorder *FixtureRecorder) add(fixture Fixture) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if recorder.fixtures[fixture.Pattern] == nil {
+		recorder.fixtures[fixture.Pattern] = make(map[int]Fixture)
+	}
+	recorder.fixtures[fixture.Pattern][fixture.Status] = fixture
+}
+
+// header returns a copy of header with the configured headers redacted.
+func (recorder *FixtureRecorder) header(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	sanitized := header.Clone()
+	for _, name := range recorder.config.RedactHeaders {
+		if values := sanitized.Values(name); len(values) > 0 {
+			sanitized.Set(name, "[REDACTED]")
+		}
+	}
+	return sanitized
+}
+
+// path returns the request path with the values of the configured path
+// wildcards of the route pattern redacted, e.g. "/reset/[REDACTED]" for
+// "/reset/{token}".
+func (recorder *FixtureRecorder) path(request *http.Request) string {
+	pattern := request.Pattern
+	if index := strings.Index(pattern, "/"); index >= 0 {
+		pattern = pattern[index:]
+	}
+	segments := strings.Split(request.URL.Path, "/")
+	for index, segment := range strings.Split(pattern, "/") {
+		if index >= len(segments) || !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name, rest := strings.CutSuffix(segment[1:len(segment)-1], "...")
+		if !containsFold(recorder.config.RedactFields, name) {
+			continue
+		}
+		if rest {
+			segments = append(segments[:index], "[REDACTED]")
+			break
+		}
+		segments[index] = "[REDACTED]"
+	}
+	return strings.Join(segments, "/")
+}
+
+// query returns a query string with the values of the configured fields
+// redacted. Malformed query strings are left out.
+func (recorder *FixtureRecorder) query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return ""
+	}
+	for name, parameters := range values {
+		if containsFold(recorder.config.RedactFields, name) {
+			for index := range parameters {
+				parameters[index] = "[REDACTED]"
+			}
+		}
+	}
+	return values.Encode()
+}
+
+// body returns a JSON body with the configured fields redacted, or nil for
+// other bodies.
+func (recorder *FixtureRecorder) body(header http.Header, body []byte) json.RawMessage {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if len(body) == 0 || mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil
+	}
+	sanitized, err := json.Marshal(redactFields(value, recorder.config.RedactFields))
+	if err != nil {
+		return nil
+	}
+	return sanitized
+}
+
+// redactFields replaces the values of the named object keys in a decoded
+// JSON value.
+func redactFields(value any, fields []string) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, field := range typed {
+			if containsFold(fields, key) {
+				typed[key] = "[REDACTED]"
+			} else {
+				typed[key] = redactFields(field, fields)
+			}
+		}
+	case []any:
+		for index, item := range typed {
+			typed[index] = redactFields(item, fields)
+		}
+	}
+	return value
+}
+
+// fixtureFileName returns the file name of the fixtures of a pattern.
+func fixtureFileName(pattern string) string {
+	name := strings.NewReplacer(" /", "_", " ", "_", "/", "_", "$", "", ".", "").Replace(pattern)
+	name = strings.Trim(name, "_")
+	if name == "" {
+		name = "root"
+	}
+	return name + ".json"
+}
+
+// capturedBody copies up to 1 MiB of a request body as the handler reads it.
+type capturedBody struct {
+	source   io.ReadCloser
+	buffer   bytes.Buffer
+	overflow bool
+}
+
+func (body *capturedBody) Read(data []byte) (int, error) {
+	count, err := body.source.Read(data)
+	if body.buffer.Len()+count > maxCheckedResponse {
+		body.overflow = true
+	} else if !body.overflow {
+		body.buffer.Write(data[:count])
+	}
+	return count, err
+}
+
+func (body *capturedBody) Close() error {
+	return body.source.Close()
+}
+
+// openAPIExamples adds the request and response examples of the fixtures of
+// a route to its OpenAPI operation.
+func openAPIExamples(operation map[string]any, fixtures map[int]Fixture) {
+	for _, status := range slices.Sorted(maps.Keys(fixtures)) {
+		fixture := fixtures[status]
+		if fixture.Request.Body != nil {
+			body, _ := operation["requestBody"].(map[string]any)
+			if body == nil {
+				body = map[string]any{"content": map[string]any{"application/json": map[string]any{}}}
+				operation["requestBody"] = body
+			}
+			if media, ok := body["content"].(map[string]any)["application/json"].(map[string]any); ok && media["example"] == nil {
+				media["example"] = fixture.Request.Body
+			}
+		}
+		responses, _ := operation["responses"].(map[string]any)
+		if responses == nil {
+			responses = make(map[string]any)
+			operation["responses"] = responses
+		}
+		code := strconv.Itoa(status)
+		response, _ := responses[code].(map[string]any)
+		if response == nil {
+			response = map[string]any{"description": http.StatusText(status)}
+			responses[code] = response
+		}
+		if fixture.Response.Body == nil {
+			continue
+		}
+		content, _ := response["content"].(map[string]any)
+		if content == nil {
+			content = map[string]any{"application/json": map[string]any{}}
+			response["content"] = content
+		}
+		if media, ok := content["application/json"].(map[string]any); ok {
+			media["example"] = fixture.Response.Body
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package routerx
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GenerateRoutes writes Go source declaring a typed constant and a path
+// builder function for every registered path, so that internal code and
+// tests can reference routes without string literals. Paths named with
+// PathBuilder.Name use that name; other names are derived from the path, e.g.
+// "UsersByID" for "/users/{id}". The output is gofmt formatted.
+//
+// GenerateRoutes is meant to run from a small program invoked by go:generate
+// that builds the application router.
+//
+// Example:
+//
+//	//go:generate go run ./cmd/genroutes
+//
+//	// cmd/genroutes/main.go
+//	func main() {
+//	    file, _ := os.Create("routes/routes_gen.go")
+//	    defer file.Close()
+//	    if err := app.NewRouter().GenerateRoutes(file, "routes"); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+//
+//	// elsewhere
+//	location := routes.UserShow(user.ID)                 // "/users/42"
+//	request := httptest.NewRequest("GET", routes.UserShow("42"), nil)
+func (router *Router) GenerateRoutes(writer io.Writer, packageName string) error {
+	type generatedPath struct {
+		path    string
+		name    string
+		methods []string
+	}
+	var paths []*generatedPath
+	byPath := make(map[string]*generatedPath)
+	for _, info := range router.Routes() {
+		generated, found := byPath[info.Path]
+		if !found {
+			generated = &generatedPath{path: info.Path}
+			byPath[info.Path] = generated
+			paths = append(paths, generated)
+		}
+		if generated.name == "" && info.Name != "" {
+			generated.name = info.Name
+		}
+		method := info.Method
+		if method == "" {
+			method = "ANY"
+		}
+		if !slices.Contains(generated.methods, method) {
+			generated.methods = append(generated.methods, method)
+		}
+	}
+
+	var declarations bytes.Buffer
+	used := make(map[string]bool)
+	for _, generated := range paths {
+		name := generated.name
+		if name == "" {
+			name = deriveRouteName(generated.path)
+		}
+		name = exportedIdentifier(name)
+		if !token.IsIdentifier(name) {
+			return fmt.Errorf("routerx: route name %q of %q is not a Go identifier", name, generated.path)
+		}
+		unique := name
+		for suffix := 2; used[unique] || used[unique+"Pattern"]; suffix++ {
+			unique = name + strconv.Itoa(suffix)
+		}
+		used[unique] = true
+		used[unique+"Pattern"] = true
+
+		parameters, expression := routePathExpression(generated.path)
+		methods := strings.Join(generated.methods, ", ")
+		fmt.Fprintf(&declarations, "// %sPattern is the pattern of %s %s.\n", unique, methods, generated.path)
+		fmt.Fprintf(&declarations, "const %sPattern Pattern = %s\n\n", unique, strconv.Quote(generated.path))
+		fmt.Fprintf(&declarations, "// %s returns the path of %s %s.\n", unique, methods, generated.path)
+		fmt.Fprintf(&declarations, "func %s(%s) string {\nreturn %s\n}\n\n", unique, parameters, expression)
+	}
+
+	var source bytes.Buffer
+	fmt.Fprintf(&source, "// Code generated by routerx; DO NOT EDIT.\n\npackage %s\n\n", packageName)
+	body := declarations.String()
+	var imports []string
+	if strings.Contains(body, "url.PathEscape(") {
+		imports = append(imports, `"net/url"`)
+	}
+	if strings.Contains(body, "strings.ReplaceAll(") {
+		imports = append(imports, `"strings"`)
+	}
+	if len(imports) > 0 {
+		fmt.Fprintf(&source, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+	source.WriteString("// Pattern is a route path as registered on the router.\ntype Pattern string\n\n")
+	source.WriteString(body)
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return fmt.Errorf("routerx: format generated routes: %w", err)
+	}
+	_, err = writer.Write(formatted)
+	return err
+}
+
+// routePathExpression returns the parameter list and the Go expression
+// building a ServeMux path from its wildcard values.
+func routePathExpression(muxPath string) (string, string) {
+	var parameters []string
+	var parts []string
+	var literal strings.Builder
+	for index := 0; index < len(muxPath); index++ {
+		if muxPath[index] != '{' {
+			literal.WriteByte(muxPath[index])
+			continue
+		}
+		end := strings.IndexByte(muxPath[index:], '}') + index
+		wildcard := muxPath[index+1 : end]
+		index = end
+		if wildcard == "$" {
+			continue
+		}
+		if literal.Len() > 0 {
+			parts = append(parts, strconv.Quote(literal.String()))
+			literal.Reset()
+		}
+		name, remainder := strings.CutSuffix(wildcard, "...")
+		parameter := parameterIdentifier(name)
+		parameters = append(parameters, parameter)
+		if remainder {
+			parts = append(parts, `strings.ReplaceAll(url.PathEscape(`+parameter+`), "%2F", "/")`)
+		} else {
+			parts = append(parts, "url.PathEscape("+parameter+")")
+		}
+	}
+	if literal.Len() > 0 || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(literal.String()))
+	}
+	if len(parameters) == 0 {
+		return "", strings.Join(parts, " + ")
+	}
+	return strings.Join(parameters, ", ") + " string", strings.Join(parts, " + ")
+}
+
+// deriveRouteName turns a path such as "/users/{id}/files" into
+// "UsersByIDFiles".
+func deriveRouteName(muxPath string) string {
+	var name strings.Builder
+	for _, segment := range strings.Split(muxPath, "/") {
+		if segment == "" || segment == "{$}" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") {
+			name.WriteString("By")
+			segment = strings.TrimSuffix(strings.Trim(segment, "{}"), "...")
+		}
+		name.WriteString(exportedIdentifier(segment))
+	}
+	if name.Len() == 0 {
+		return "Root"
+	}
+	return name.String()
+}
+
+// routeInitialisms are written in upper case in generated identifiers.
+var routeInitialisms = map[string]bool{
+	"api": true, "html": true, "http": true, "id": true, "ip": true,
+	"json": true, "uri": true, "url": true, "uuid": true,
+}
+
+// exportedIdentifier converts words separated by non-alphanumeric
+// characters into an exported camel case identifier.
+func exportedIdentifier(text string) string {
+	var identifier strings.Builder
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for _, word := range words {
+		if routeInitialisms[strings.ToLower(word)] {
+			identifier.WriteString(strings.ToUpper(word))
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		identifier.WriteString(string(runes))
+	}
+	result := identifier.String()
+	if result != "" && unicode.IsDigit([]rune(result)[0]) {
+		result = "Route" + result
+	}
+	return result
+}
+
+// parameterIdentifier converts a wildcard name into a Go parameter name.
+func parameterIdentifier(name string) string {
+	identifier := exportedIdentifier(name)
+	if identifier == "" {
+		return "value"
+	}
+	if routeInitialisms[strings.ToLower(identifier)] {
+		identifier = strings.ToLower(identifier)
+	} else {
+		runes := []rune(identifier)
+		runes[0] = unicode.ToLower(runes[0])
+		identifier = string(runes)
+	}
+	// Keywords and the imported package names of the generated file cannot
+	// be parameter names.
+	if token.Lookup(identifier).IsKeyword() || identifier == "url" || identifier == "strings" {
+		identifier += "Param"
+	}
+	return identifier
+}
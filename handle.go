@@ -0,0 +1,42 @@
+package routerx
+
+import (
+	"context"
+	"net/http"
+)
+
+// Handle adapts a typed function to a HandlerFunc. The request is decoded
+// into Req and validated with Bind. The result is written with Render, so a
+// Res implementing ETagger or LastModifier gets validators, and a Res
+// implementing StatusCode() int chooses the status code, which defaults to
+// 200 OK. Binding and validation errors and errors returned by handler are
+// passed to the router's error handler.
+//
+// Example:
+//
+//	type GetUserRequest struct {
+//	    ID     string `path:"id"`
+//	    Fields string `query:"fields" validate:"omitempty,oneof=summary full"`
+//	}
+//
+//	router.GetE("/users/{id}", routerx.Handle(func(ctx context.Context, request GetUserRequest) (User, error) {
+//	    return store.User(ctx, request.ID)
+//	}))
+func Handle[Req any, Res any](handler func(ctx context.Context, request Req) (Res, error)) HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) error {
+		var input Req
+		if err := Bind(request, &input); err != nil {
+			return err
+		}
+		output, err := handler(request.Context(), input)
+		if err != nil {
+			return err
+		}
+		status := http.StatusOK
+		if coded, ok := any(output).(interface{ StatusCode() int }); ok {
+			status = coded.StatusCode()
+		}
+		Render(responseWriter, request, status, output)
+		return nil
+	}
+}
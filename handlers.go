@@ -0,0 +1,156 @@
+package routerx
+
+import "net/http"
+
+// GetHandler registers an http.Handler for HTTP GET requests at the specified
+// path, so that existing handler types such as http.FileServer or a
+// Prometheus handler need no wrapping. The other methods have XxxHandler
+// variants as well, and Handle accepts any method.
+//
+// Example:
+//
+//	router.GetHandler("/metrics", promhttp.Handler())
+func (router *Router) GetHandler(path string, handler http.Handler) {
+	router.handle("GET", cleanPath(path), handler, router.middlewares)
+}
+
+func (router *Router) PostHandler(path string, handler http.Handler) {
+	router.handle("POST", cleanPath(path), handler, router.middlewares)
+}
+
+func (router *Router) PatchHandler(path string, handler http.Handler) {
+	router.handle("PATCH", cleanPath(path), handler, router.middlewares)
+}
+
+func (router *Router) DeleteHandler(path string, handler http.Handler) {
+	router.handle("DELETE", cleanPath(path), handler, router.middlewares)
+}
+
+func (router *Router) HeadHandler(path string, handler http.Handler) {
+	router.handle("HEAD", cleanPath(path), handler, router.middlewares)
+}
+
+func (router *Router) PutHandler(path string, handler http.Handler) {
+	router.handle("PUT", cleanPath(path), handler, router.middlewares)
+}
+
+func (router *Router) OptionsHandler(path string, handler http.Handler) {
+	router.handle("OPTIONS", cleanPath(path), handler, router.middlewares)
+}
+
+func (router *Router) ConnectHandler(path string, handler http.Handler) {
+	router.handle("CONNECT", cleanPath(path), handler, router.middlewares)
+}
+
+func (router *Router) TraceHandler(path string, handler http.Handler) {
+	router.handle("TRACE", cleanPath(path), handler, router.middlewares)
+}
+
+// AnyHandler registers an http.Handler for every HTTP method at the
+// specified path, see Router.Any.
+func (router *Router) AnyHandler(path string, handler http.Handler) {
+	router.handle("", cleanPath(path), handler, router.middlewares)
+}
+
+// GetHandler registers an http.Handler for HTTP GET requests at the specified
+// path under the group's prefix, see Router.GetHandler.
+func (group *RouteGroup) GetHandler(path string, handler http.Handler) {
+	group.handle("GET", path, handler)
+}
+
+func (group *RouteGroup) PostHandler(path string, handler http.Handler) {
+	group.handle("POST", path, handler)
+}
+
+func (group *RouteGroup) PatchHandler(path string, handler http.Handler) {
+	group.handle("PATCH", path, handler)
+}
+
+func (group *RouteGroup) DeleteHandler(path string, handler http.Handler) {
+	group.handle("DELETE", path, handler)
+}
+
+func (group *RouteGroup) HeadHandler(path string, handler http.Handler) {
+	group.handle("HEAD", path, handler)
+}
+
+func (group *RouteGroup) PutHandler(path string, handler http.Handler) {
+	group.handle("PUT", path, handler)
+}
+
+func (group *RouteGroup) OptionsHandler(path string, handler http.Handler) {
+	group.handle("OPTIONS", path, handler)
+}
+
+func (group *RouteGroup) ConnectHandler(path string, handler http.Handler) {
+	group.handle("CONNECT", path, handler)
+}
+
+func (group *RouteGroup) TraceHandler(path string, handler http.Handler) {
+	group.handle("TRACE", path, handler)
+}
+
+// AnyHandler registers an http.Handler for every HTTP method at the
+// specified path under the group's prefix, see Router.Any.
+func (group *RouteGroup) AnyHandler(path string, handler http.Handler) {
+	group.handle("", path, handler)
+}
+
+// GetHandler registers an http.Handler for HTTP GET requests on the
+// builder's path, see Router.GetHandler.
+//
+// Example:
+//
+//	router.Path("/assets/{file...}").
+//	    GetHandler(http.StripPrefix("/assets", http.FileServerFS(assets)))
+func (builder *PathBuilder) GetHandler(handler http.Handler) *PathBuilder {
+	builder.register("GET", handler)
+	return builder
+}
+
+func (builder *PathBuilder) PostHandler(handler http.Handler) *PathBuilder {
+	builder.register("POST", handler)
+	return builder
+}
+
+func (builder *PathBuilder) PatchHandler(handler http.Handler) *PathBuilder {
+	builder.register("PATCH", handler)
+	return builder
+}
+
+func (builder *PathBuilder) DeleteHandler(handler http.Handler) *PathBuilder {
+	builder.register("DELETE", handler)
+	return builder
+}
+
+func (builder *PathBuilder) HeadHandler(handler http.Handler) *PathBuilder {
+	builder.register("HEAD", handler)
+	return builder
+}
+
+func (builder *PathBuilder) PutHandler(handler http.Handler) *PathBuilder {
+	builder.register("PUT", handler)
+	return builder
+}
+
+func (builder *PathBuilder) OptionsHandler(handler http.Handler) *PathBuilder {
+	builder.register("OPTIONS", handler)
+	return builder
+}
+
+func (builder *PathBuilder) ConnectHandler(handler http.Handler) *PathBuilder {
+	builder.register("CONNECT", handler)
+	return builder
+}
+
+func (builder *PathBuilder) TraceHandler(handler http.Handler) *PathBuilder {
+	builder.register("TRACE", handler)
+	return builder
+}
+
+// AnyHandler registers an http.Handler for every HTTP method on the
+// builder's path, see PathBuilder.Any.
+func (builder *PathBuilder) AnyHandler(handler http.Handler) *PathBuilder {
+	builder.register("", handler)
+	return builder
+}
@@ -0,0 +1,182 @@
+package routerx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HealthCheck checks a dependency of the service, such as a database or a
+// downstream API, and returns an error while it is unusable. A check may
+// implement Name() string to name it in health reports; see CheckFunc.
+type HealthCheck interface {
+	Check(ctx context.Context) error
+}
+
+// namedCheck is a HealthCheck created by CheckFunc.
+type namedCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (check namedCheck) Name() string {
+	return check.name
+}
+
+func (check namedCheck) Check(ctx context.Context) error {
+	return check.check(ctx)
+}
+
+// CheckFunc returns a HealthCheck named name that calls check.
+//
+// Example:
+//
+//	routerx.CheckFunc("cache", func(ctx context.Context) error {
+//	    return cache.Ping(ctx).Err()
+//	})
+func CheckFunc(name string, check func(ctx context.Context) error) HealthCheck {
+	return namedCheck{name: name, check: check}
+}
+
+// Pinger is implemented by clients that can check their connection, such
+// as *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingCheck returns a HealthCheck named name that pings target.
+func PingCheck(name string, target Pinger) HealthCheck {
+	return CheckFunc(name, target.PingContext)
+}
+
+// HTTPCheck returns a HealthCheck named name that passes while a GET request
+// to url is answered with a status below 500.
+func HTTPCheck(name string, url string) HealthCheck {
+	return CheckFunc(name, func(ctx context.Context) error {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		if response.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%s answered %s", url, response.Status)
+		}
+		return nil
+	})
+}
+
+// HealthReport is the JSON body of the health endpoints.
+type HealthReport struct {
+	// Status is "up" when every check passed, "down" when any failed, and
+	// "draining" while the router shuts down.
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+// HealthCheckResult is the outcome of a single check.
+type HealthCheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// healthCheckTimeout bounds every check so that a hanging dependency fails
+// its check instead of the probe.
+const healthCheckTimeout = 5 * time.Second
+
+// Health registers health endpoints under path:
+//
+//   - GET path/live is the liveness probe. It runs no checks and answers 200
+//     while the process serves requests, so an unavailable dependency does
+//     not get the process restarted.
+//   - GET path/ready is the readiness probe. It runs every check
+//     concurrently and answers 503 Service Unavailable when any fails, so
+//     load balancers stop sending traffic until the dependencies recover.
+//   - GET path reports the readiness probe with the status and latency of
+//     every check.
+//
+// Once the router drains, see Router.SetReady, the readiness probe answers
+// 503 with the status "draining" without running the checks.
+//
+// Each check fails after 5 seconds, even when it ignores its context.
+// Checks without a Name method are named by their position.
+//
+// Example:
+//
+//	router.Health("/healthz",
+//	    routerx.PingCheck("database", db),
+//	    routerx.HTTPCheck("payments", "http://payments.internal/healthz"),
+//	)
+func (router *Router) Health(path string, checks ...HealthCheck) {
+	names := make([]string, len(checks))
+	for index, check := range checks {
+		names[index] = "check_" + strconv.Itoa(index+1)
+		if named, ok := check.(interface{ Name() string }); ok {
+			names[index] = named.Name()
+		}
+	}
+	readiness := func(responseWriter http.ResponseWriter, request *http.Request) {
+		report := HealthReport{Status: "draining"}
+		if !router.draining.Load() {
+			report = runHealthChecks(request.Context(), names, checks)
+		}
+		status := http.StatusOK
+		if report.Status != "up" {
+			status = http.StatusServiceUnavailable
+		}
+		responseWriter.Header().Set("Cache-Control", "no-store")
+		writeJSON(responseWriter, status, report)
+	}
+	router.Get(path, readiness)
+	router.Get(joinPath(path, "/ready"), readiness)
+	router.Get(joinPath(path, "/live"), func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Header().Set("Cache-Control", "no-store")
+		writeJSON(responseWriter, http.StatusOK, HealthReport{Status: "up"})
+	})
+}
+
+// runHealthChecks runs checks concurrently and aggregates their results.
+func runHealthChecks(ctx context.Context, names []string, checks []HealthCheck) HealthReport {
+	report := HealthReport{Status: "up", Checks: make(map[string]HealthCheckResult, len(checks))}
+	var mutex sync.Mutex
+	var waitGroup sync.WaitGroup
+	clock := clockFrom(ctx)
+	for index, check := range checks {
+		waitGroup.Go(func() {
+			checkContext, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			started := clock.Now()
+			done := make(chan error, 1)
+			go func() { done <- check.Check(checkContext) }()
+			var err error
+			select {
+			case err = <-done:
+			case <-checkContext.Done():
+				err = checkContext.Err()
+			}
+			result := HealthCheckResult{
+				Status:    "up",
+				LatencyMS: float64(clock.Now().Sub(started).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = "down"
+				result.Error = err.Error()
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			report.Checks[names[index]] = result
+			if err != nil {
+				report.Status = "down"
+			}
+		})
+	}
+	waitGroup.Wait()
+	return report
+}
@@ -0,0 +1,94 @@
+package routerx
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// JSONPConfig configures the JSONP middleware.
+type JSONPConfig struct {
+	// Parameter is the query parameter naming the callback. Defaults to
+	// "callback".
+	Parameter string
+
+	// MaxCallbackLength limits the length of the callback name. Defaults to
+	// 64.
+	MaxCallbackLength int
+}
+
+// jsonpCallbackPattern accepts JavaScript identifiers and dotted member
+// paths such as "jQuery123_456" or "app.callbacks.users", and nothing that
+// could inject code.
+var jsonpCallbackPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+// JSONP returns a Middleware that serves JSON responses of GET and HEAD
+// requests carrying a callback parameter as JSONP, for legacy embedded
+// clients that cannot use CORS. The body becomes
+// "/**/callback(body, status);" with the original status as second argument,
+// sent as 200 OK with a text/javascript content type so that the script runs
+// in every case. Requests without the parameter are served unchanged;
+// invalid callback names are rejected with 400 Bad Request.
+//
+// JSONP executes the response as script on the embedding page, so only use
+// it for public, non-personalized data: any site can read it. The response
+// carries X-Content-Type-Options: nosniff, and the embedding page's
+// Content-Security-Policy must allow the API origin in script-src.
+//
+// Example:
+//
+//	legacy := router.Group("/widgets").Use(routerx.JSONP(routerx.JSONPConfig{}))
+//	legacy.Get("/stats", statsHandler) // GET /widgets/stats?callback=render
+func JSONP(config JSONPConfig) Middleware {
+	if config.Parameter == "" {
+		config.Parameter = "callback"
+	}
+	if config.MaxCallbackLength <= 0 {
+		config.MaxCallbackLength = 64
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			callback := request.URL.Query().Get(config.Parameter)
+			if callback == "" || (request.Method != http.MethodGet && request.Method != http.MethodHead) {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			if len(callback) > config.MaxCallbackLength || !jsonpCallbackPattern.MatchString(callback) {
+				writeError(responseWriter, http.StatusBadRequest, "invalid "+config.Parameter+" parameter")
+				return
+			}
+
+			buffered := newBufferedResponseWriter()
+			next.ServeHTTP(buffered, request)
+			body := bytes.TrimSpace(buffered.body.Bytes())
+			if !isJSONContentType(buffered.header.Get("Content-Type")) {
+				buffered.flushTo(responseWriter, buffered.body.Bytes())
+				return
+			}
+			if len(body) == 0 {
+				body = []byte("null")
+			}
+			// U+2028 and U+2029 are valid in JSON but end statements in
+			// older JavaScript engines.
+			body = bytes.ReplaceAll(body, []byte("\u2028"), []byte(`\u2028`))
+			body = bytes.ReplaceAll(body, []byte("\u2029"), []byte(`\u2029`))
+
+			var script bytes.Buffer
+			script.WriteString("/**/")
+			script.WriteString(callback)
+			script.WriteByte('(')
+			script.Write(body)
+			script.WriteString(", ")
+			script.WriteString(strconv.Itoa(buffered.status()))
+			script.WriteString(");\n")
+
+			header := buffered.header
+			header.Set("Content-Type", "text/javascript; charset=utf-8")
+			header.Set("X-Content-Type-Options", "nosniff")
+			header.Del("Content-Disposition")
+			buffered.statusCode = http.StatusOK
+			buffered.flushTo(responseWriter, script.Bytes())
+		})
+	}
+}
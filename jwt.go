@@ -0,0 +1,482 @@
+package routerx
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWTConfig configures the JWT middleware. At least one of Secret, Keys, or
+// JWKSURL must be set.
+type JWTConfig struct {
+	// Secret verifies tokens signed with HS256, HS384, or HS512.
+	Secret []byte
+
+	// Keys verifies tokens signed with RS*, PS*, ES*, or EdDSA by key ID,
+	// the "kid" header of the token. The key "" verifies tokens without a
+	// key ID. Values are *rsa.PublicKey, *ecdsa.PublicKey, or
+	// ed25519.PublicKey.
+	Keys map[string]crypto.PublicKey
+
+	// JWKSURL is the URL of a JSON Web Key Set, e.g. an identity provider's
+	// "jwks_uri". Keys are fetched on first use, refreshed every
+	// JWKSRefresh, and refetched at most once a minute for unknown key IDs
+	// to pick up rotated keys.
+	JWKSURL string
+
+	// JWKSRefresh defaults to one hour.
+	JWKSRefresh time.Duration
+
+	// HTTPClient fetches the key set. Defaults to a client with a 10 second
+	// timeout.
+	HTTPClient *http.Client
+
+	// Algorithms restricts the accepted signature algorithms, e.g.
+	// []string{"RS256"}. Defaults to every algorithm matching a configured
+	// key. "none" is never accepted.
+	Algorithms []string
+
+	// Issuer and Audience, when set, must match the "iss" claim and one of
+	// the "aud" claim values.
+	Issuer   string
+	Audience string
+
+	// Leeway tolerates clock skew when checking "exp", "nbf", and "iat".
+	Leeway time.Duration
+
+	// Token extracts the token from a request. Defaults to the bearer token
+	// of the Authorization header.
+	Token func(request *http.Request) string
+
+	// Optional lets requests without a token through unauthenticated.
+	// Requests with an invalid token are rejected regardless.
+	Optional bool
+}
+
+// JWTClaims are the claims of a verified JSON Web Token.
+type JWTClaims map[string]any
+
+// Subject returns the "sub" claim.
+func (claims JWTClaims) Subject() string {
+	subject, _ := claims["sub"].(string)
+	return subject
+}
+
+// Scopes returns the space separated "scope" claim or the "scp" array.
+func (claims JWTClaims) Scopes() []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	return claimStrings(claims["scp"])
+}
+
+// ExpiresAt returns the "exp" claim, or the zero time.
+func (claims JWTClaims) ExpiresAt() time.Time {
+	return claims.time("exp")
+}
+
+func (claims JWTClaims) time(name string) time.Time {
+	if seconds, ok := claims[name].(float64); ok {
+		return time.Unix(int64(seconds), 0)
+	}
+	return time.Time{}
+}
+
+func claimStrings(value any) []string {
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []any:
+		var values []string
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+type jwtContextKey struct{}
+
+// JWT returns a Middleware that authenticates requests with a signed JSON Web
+// Token, by default the bearer token of the Authorization header. Verified
+// claims are available through Claims, and a Principal built from the "sub",
+// "scope" or "scp", and "roles" claims is attached with SetPrincipal, so the
+// auth requirements and Authorize policies apply. Requests without a valid
+// token are answered with 401 Unauthorized and a WWW-Authenticate challenge
+// through the router's error handler, see HandleError.
+//
+// Example:
+//
+//	api := router.Group("/api").Use(routerx.JWT(routerx.JWTConfig{
+//	    JWKSURL:  "https://login.example.com/.well-known/jwks.json",
+//	    Issuer:   "https://login.example.com/",
+//	    Audience: "orders-api",
+//	}))
+//	api.Get("/me", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    routerx.JSON(responseWriter, http.StatusOK, map[string]string{"user": routerx.Claims(request).Subject()})
+//	})
+func JWT(config JWTConfig) Middleware {
+	if config.Secret == nil && config.Keys == nil && config.JWKSURL == "" {
+		panic("routerx: JWT needs a secret, keys, or a JWKS URL")
+	}
+	if config.Token == nil {
+		config.Token = bearerToken
+	}
+	verifier := &jwtVerifier{config: config}
+	if config.JWKSURL != "" {
+		verifier.jwks = &jwksCache{url: config.JWKSURL, refresh: config.JWKSRefresh, client: config.HTTPClient}
+		if verifier.jwks.refresh <= 0 {
+			verifier.jwks.refresh = time.Hour
+		}
+		if verifier.jwks.client == nil {
+			verifier.jwks.client = &http.Client{Timeout: 10 * time.Second}
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			token := config.Token(request)
+			if token == "" {
+				if config.Optional {
+					next.ServeHTTP(responseWriter, request)
+					return
+				}
+				responseWriter.Header().Set("WWW-Authenticate", "Bearer")
+				HandleError(responseWriter, request, &HTTPError{Status: http.StatusUnauthorized, Message: "missing bearer token"})
+				return
+			}
+			claims, err := verifier.verify(request.Context(), token)
+			if err != nil {
+				responseWriter.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				HandleError(responseWriter, request, &HTTPError{Status: http.StatusUnauthorized, Message: "invalid bearer token", Err: err})
+				return
+			}
+			request = request.WithContext(context.WithValue(request.Context(), jwtContextKey{}, claims))
+			request = SetPrincipal(request, &Principal{
+				ID:     claims.Subject(),
+				Scheme: SchemeBearer,
+				Scopes: claims.Scopes(),
+				Roles:  claimStrings(claims["roles"]),
+			})
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
+
+// Claims returns the claims of the token verified by JWT, or nil for
+// unauthenticated requests.
+func Claims(request *http.Request) JWTClaims {
+	claims, _ := request.Context().Value(jwtContextKey{}).(JWTClaims)
+	return claims
+}
+
+func bearerToken(request *http.Request) string {
+	scheme, token, found := strings.Cut(request.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+type jwtVerifier struct {
+	config JWTConfig
+	jwks   *jwksCache
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// verify checks the signature and the registered claims of a compact JWS.
+func (verifier *jwtVerifier) verify(ctx context.Context, token string) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+	if header.Algorithm == "" || strings.EqualFold(header.Algorithm, "none") {
+		return nil, errors.New("unsigned token")
+	}
+	if len(verifier.config.Algorithms) > 0 && !slices.Contains(verifier.config.Algorithms, header.Algorithm) {
+		return nil, fmt.Errorf("algorithm %s not accepted", header.Algorithm)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	key, err := verifier.key(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims JWTClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %w", err)
+	}
+	return claims, verifier.checkClaims(claims, clockFrom(ctx).Now())
+}
+
+// key returns the key verifying a token with header.
+func (verifier *jwtVerifier) key(ctx context.Context, header jwtHeader) (any, error) {
+	if strings.HasPrefix(header.Algorithm, "HS") {
+		if verifier.config.Secret == nil {
+			return nil, fmt.Errorf("algorithm %s not accepted", header.Algorithm)
+		}
+		return verifier.config.Secret, nil
+	}
+	if key, found := verifier.config.Keys[header.KeyID]; found {
+		return key, nil
+	}
+	if verifier.jwks != nil {
+		return verifier.jwks.key(ctx, header.KeyID)
+	}
+	return nil, fmt.Errorf("unknown key %q", header.KeyID)
+}
+
+// checkClaims validates the time claims against now, and the issuer and
+// audience claims.
+func (verifier *jwtVerifier) checkClaims(claims JWTClaims, now time.Time) error {
+	leeway := verifier.config.Leeway
+	if expires := claims.time("exp"); !expires.IsZero() && now.After(expires.Add(leeway)) {
+		return errors.New("token expired")
+	}
+	if notBefore := claims.time("nbf"); !notBefore.IsZero() && now.Add(leeway).Before(notBefore) {
+		return errors.New("token not valid yet")
+	}
+	if issuedAt := claims.time("iat"); !issuedAt.IsZero() && now.Add(leeway).Before(issuedAt) {
+		return errors.New("token issued in the future")
+	}
+	if verifier.config.Issuer != "" && claims["iss"] != verifier.config.Issuer {
+		return errors.New("unexpected issuer")
+	}
+	if verifier.config.Audience != "" && !slices.Contains(claimStrings(claims["aud"]), verifier.config.Audience) {
+		return errors.New("unexpected audience")
+	}
+	return nil
+}
+
+func decodeJWTPart(part string, destination any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, destination)
+}
+
+// verifyJWTSignature verifies signature over signed for a JWS algorithm,
+// rejecting keys of the wrong type so that, for example, an RSA public key
+// can never be used as an HMAC secret.
+func verifyJWTSignature(algorithm string, key any, signed []byte, signature []byte) error {
+	invalid := errors.New("invalid signature")
+	if algorithm == "EdDSA" {
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(publicKey, signed, signature) {
+			return invalid
+		}
+		return nil
+	}
+
+	hashes := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+	hash, found := hashes[strings.TrimLeft(algorithm, "HRPES")]
+	if !found || len(algorithm) != 5 {
+		return fmt.Errorf("unsupported algorithm %s", algorithm)
+	}
+	digester := hash.New()
+	digester.Write(signed)
+	digest := digester.Sum(nil)
+
+	switch algorithm[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return invalid
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return invalid
+		}
+	case "RS":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(publicKey, hash, digest, signature) != nil {
+			return invalid
+		}
+	case "PS":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPSS(publicKey, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) != nil {
+			return invalid
+		}
+	case "ES":
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return invalid
+		}
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return invalid
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(publicKey, digest, r, s) {
+			return invalid
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %s", algorithm)
+	}
+	return nil
+}
+
+// jwksCache holds the keys of a JSON Web Key Set.
+type jwksCache struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mutex       sync.Mutex
+	keys        map[string]any
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	err         error
+}
+
+// key returns the key with the given ID, fetching the key set when it is
+// stale or when the key is unknown. Fetches, failed ones included, happen at
+// most once a minute, so that requests fail fast instead of queueing behind
+// fetches while the identity provider is unreachable.
+func (cache *jwksCache) key(ctx context.Context, id string) (any, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	now := clockFrom(ctx).Now()
+	if key, found := cache.keys[id]; found && now.Sub(cache.fetchedAt) < cache.refresh {
+		return key, nil
+	}
+	if cache.attemptedAt.IsZero() || now.Sub(cache.attemptedAt) >= min(cache.refresh, time.Minute) {
+		cache.attemptedAt = now
+		keys, err := cache.fetch(ctx)
+		cache.err = err
+		if err == nil {
+			cache.keys, cache.fetchedAt = keys, now
+		}
+	}
+	if key, found := cache.keys[id]; found {
+		return key, nil
+	}
+	if cache.keys == nil && cache.err != nil {
+		return nil, cache.err
+	}
+	return nil, fmt.Errorf("unknown key %q", id)
+}
+
+// jsonWebKey holds the members of a JWK needed for signature verification.
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Curve   string `json:"crv"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func (cache *jwksCache) fetch(ctx context.Context) (map[string]any, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, cache.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := cache.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: %s", response.Status)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+	keys := make(map[string]any)
+	for _, webKey := range set.Keys {
+		if webKey.Use != "" && webKey.Use != "sig" {
+			continue
+		}
+		if key, err := webKey.publicKey(); err == nil {
+			keys[webKey.KeyID] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey converts an RSA, EC, or OKP Ed25519 JWK to a public key.
+func (webKey jsonWebKey) publicKey() (any, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch webKey.KeyType {
+	case "RSA":
+		modulus, err := decode(webKey.N)
+		if err != nil {
+			return nil, err
+		}
+		exponent, err := decode(webKey.E)
+		if err != nil || len(exponent) > 4 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
+		}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, found := curves[webKey.Curve]
+		if !found {
+			return nil, fmt.Errorf("unsupported curve %s", webKey.Curve)
+		}
+		x, err := decode(webKey.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(webKey.Y)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsa.ParseUncompressedPublicKey(curve, bytes.Join([][]byte{{4}, x, y}, nil))
+	case "OKP":
+		if webKey.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", webKey.Curve)
+		}
+		x, err := decode(webKey.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", webKey.KeyType)
+}
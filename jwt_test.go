@@ -0,0 +1,240 @@
+package routerx
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var jwtTestNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// signJWT returns a compact JWS of claims. HS* algorithms are signed with
+// secret and RS256 with key; "none" is left unsigned.
+func signJWT(t *testing.T, header map[string]any, claims map[string]any, secret []byte, key *rsa.PrivateKey) string {
+	t.Helper()
+	encode := func(value any) string {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(header) + "." + encode(claims)
+	var signature []byte
+	switch header["alg"] {
+	case "HS256":
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case "RS256":
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// serveJWT serves a request with token through JWT(config) on a router whose
+// clock reads jwtTestNow, returning the status and the authenticated subject.
+func serveJWT(config JWTConfig, token string) (int, string) {
+	router := New(WithClock(NewFakeClock(jwtTestNow)))
+	router.Use(JWT(config))
+	router.Get("/", func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Write([]byte(GetPrincipal(request).ID))
+	})
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder.Code, recorder.Body.String()
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("test-secret")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER})
+
+	hs256 := map[string]any{"alg": "HS256", "typ": "JWT"}
+	rs256 := map[string]any{"alg": "RS256", "typ": "JWT"}
+	valid := map[string]any{
+		"sub": "42",
+		"exp": jwtTestNow.Add(time.Hour).Unix(),
+		"aud": "orders-api",
+	}
+	with := func(name string, value any) map[string]any {
+		claims := map[string]any{}
+		for key, value := range valid {
+			claims[key] = value
+		}
+		claims[name] = value
+		return claims
+	}
+	secretConfig := JWTConfig{Secret: secret, Audience: "orders-api"}
+	keyConfig := JWTConfig{Keys: map[string]crypto.PublicKey{"": &key.PublicKey}, Audience: "orders-api"}
+
+	tests := []struct {
+		name       string
+		config     JWTConfig
+		token      string
+		wantStatus int
+	}{
+		{
+			name:       "valid HS256",
+			config:     secretConfig,
+			token:      signJWT(t, hs256, valid, secret, nil),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "valid RS256",
+			config:     keyConfig,
+			token:      signJWT(t, rs256, valid, nil, key),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing token",
+			config:     secretConfig,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "alg none",
+			config:     secretConfig,
+			token:      signJWT(t, map[string]any{"alg": "none"}, valid, nil, nil),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "alg None",
+			config:     secretConfig,
+			token:      signJWT(t, map[string]any{"alg": "None"}, valid, nil, nil),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "HS256 signed with the RSA public key",
+			config:     keyConfig,
+			token:      signJWT(t, hs256, valid, publicKeyPEM, nil),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "HS256 while only RS256 is accepted",
+			config:     JWTConfig{Keys: map[string]crypto.PublicKey{"": &key.PublicKey}, Algorithms: []string{"RS256"}, Secret: publicKeyPEM},
+			token:      signJWT(t, hs256, valid, publicKeyPEM, nil),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong secret",
+			config:     secretConfig,
+			token:      signJWT(t, hs256, valid, []byte("other-secret"), nil),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "expired",
+			config:     secretConfig,
+			token:      signJWT(t, hs256, with("exp", jwtTestNow.Add(-time.Minute).Unix()), secret, nil),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "expired within leeway",
+			config:     JWTConfig{Secret: secret, Audience: "orders-api", Leeway: 2 * time.Minute},
+			token:      signJWT(t, hs256, with("exp", jwtTestNow.Add(-time.Minute).Unix()), secret, nil),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "not valid yet",
+			config:     secretConfig,
+			token:      signJWT(t, hs256, with("nbf", jwtTestNow.Add(time.Minute).Unix()), secret, nil),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong audience",
+			config:     secretConfig,
+			token:      signJWT(t, hs256, with("aud", "billing-api"), secret, nil),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "audience in a list",
+			config:     secretConfig,
+			token:      signJWT(t, hs256, with("aud", []string{"billing-api", "orders-api"}), secret, nil),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong issuer",
+			config:     JWTConfig{Secret: secret, Issuer: "https://login.example.com/"},
+			token:      signJWT(t, hs256, with("iss", "https://evil.example.com/"), secret, nil),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, subject := serveJWT(test.config, test.token)
+			if status != test.wantStatus {
+				t.Fatalf("status = %d, want %d", status, test.wantStatus)
+			}
+			if status == http.StatusOK && subject != "42" {
+				t.Errorf("subject = %q, want %q", subject, "42")
+			}
+		})
+	}
+}
+
+func TestJWTKeySetBackoff(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		fetches.Add(1)
+		http.Error(responseWriter, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	clock := NewFakeClock(jwtTestNow)
+	router := New(WithClock(clock))
+	router.Use(JWT(JWTConfig{JWKSURL: server.URL}))
+	router.Get("/", func(responseWriter http.ResponseWriter, request *http.Request) {})
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signJWT(t, map[string]any{"alg": "RS256", "kid": "1"}, map[string]any{"sub": "42"}, nil, key)
+
+	steps := []struct {
+		advance     time.Duration
+		wantFetches int32
+	}{
+		{0, 1},
+		{0, 1},
+		{30 * time.Second, 1},
+		{30 * time.Second, 2},
+	}
+	for index, step := range steps {
+		clock.Advance(step.advance)
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("request %d: status = %d, want %d", index, recorder.Code, http.StatusUnauthorized)
+		}
+		if got := fetches.Load(); got != step.wantFetches {
+			t.Fatalf("request %d: fetches = %d, want %d", index, got, step.wantFetches)
+		}
+	}
+}
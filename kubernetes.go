@@ -0,0 +1,148 @@
+package routerx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+)
+
+// RoutesAnnotation is the annotation key under which RoutesAnnotationValue
+// is meant to be published, e.g. on the Service in front of the router.
+const RoutesAnnotation = "routerx.io/routes"
+
+// DefaultTerminationLog is the file Kubernetes reads the termination
+// message of a container from, unless its terminationMessagePath differs.
+const DefaultTerminationLog = "/dev/termination-log"
+
+// KubernetesConfig configures Router.Drain and Router.PreStopHandler.
+type KubernetesConfig struct {
+	// PreStopDelay is how long the router keeps serving after it became
+	// unready, so that endpoint controllers and load balancers stop routing
+	// new requests to the pod before the server closes its listeners.
+	// Defaults to 5 seconds.
+	PreStopDelay time.Duration
+}
+
+func kubernetesConfig(configs []KubernetesConfig) KubernetesConfig {
+	var config KubernetesConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.PreStopDelay <= 0 {
+		config.PreStopDelay = 5 * time.Second
+	}
+	return config
+}
+
+// SetReady marks the router ready or draining. While draining, the
+// readiness probes registered with Router.Health answer 503 Service
+// Unavailable, and regular routes keep being served. Routers start ready.
+func (router *Router) SetReady(ready bool) {
+	router.draining.Store(!ready)
+}
+
+// Ready reports whether the router is ready, see SetReady.
+func (router *Router) Ready() bool {
+	return !router.draining.Load()
+}
+
+// Drain shuts server down the way Kubernetes expects on SIGTERM: it flips
+// the readiness probe to failing, keeps serving for the pre-stop delay
+// while the pod is removed from the endpoints, and then shuts server down,
+// waiting for in-flight requests until ctx is done.
+//
+// Example:
+//
+//	signals, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+//	defer stop()
+//	go server.ListenAndServe()
+//	<-signals.Done()
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	if err := router.Drain(ctx, server); err != nil {
+//	    routerx.WriteTerminationLog("", "drain: "+err.Error())
+//	}
+func (router *Router) Drain(ctx context.Context, server *http.Server, configs ...KubernetesConfig) error {
+	config := kubernetesConfig(configs)
+	if router.Ready() {
+		router.SetReady(false)
+		select {
+		case <-router.timeSource().After(config.PreStopDelay):
+		case <-ctx.Done():
+		}
+	}
+	return server.Shutdown(ctx)
+}
+
+// PreStopHandler returns a handler for a preStop httpGet hook. It marks the
+// router draining and answers after the pre-stop delay, so that Kubernetes
+// sends SIGTERM only once the pod stopped receiving traffic. Drain then
+// skips its own delay. Keep the route on a port the pod does not expose.
+//
+// Example:
+//
+//	router.Get("/internal/prestop", router.PreStopHandler())
+func (router *Router) PreStopHandler(configs ...KubernetesConfig) http.HandlerFunc {
+	config := kubernetesConfig(configs)
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		router.SetReady(false)
+		select {
+		case <-GetClock(request).After(config.PreStopDelay):
+		case <-request.Context().Done():
+		}
+		responseWriter.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// WriteTerminationLog writes message to the termination log at path, or at
+// DefaultTerminationLog when path is empty, where Kubernetes shows it as the
+// reason the container terminated. Messages are cut to the 4096 bytes
+// Kubernetes keeps.
+func WriteTerminationLog(path string, message string) error {
+	if path == "" {
+		path = DefaultTerminationLog
+	}
+	if len(message) > 4096 {
+		message = message[:4096]
+	}
+	return os.WriteFile(path, []byte(message), 0o644)
+}
+
+// RoutesAnnotationValue returns the route table as compact JSON, to be
+// published under RoutesAnnotation so that gateway controllers can
+// configure routing for the service.
+func (router *Router) RoutesAnnotationValue() (string, error) {
+	encoded, err := json.Marshal(router.Routes())
+	return string(encoded), err
+}
+
+// RoutesConfigMap returns a Kubernetes ConfigMap manifest named name in
+// namespace holding the route table as "routes.json", for gateway
+// controllers that read route tables from ConfigMaps. The manifest is JSON,
+// which kubectl apply accepts.
+//
+// Example:
+//
+//	manifest, err := router.RoutesConfigMap("orders-routes", "shop")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	os.WriteFile("deploy/routes.json", manifest, 0o644)
+func (router *Router) RoutesConfigMap(name string, namespace string) ([]byte, error) {
+	routes, err := json.MarshalIndent(router.Routes(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": namespace,
+			"labels":    map[string]string{"app.kubernetes.io/managed-by": "routerx"},
+		},
+		"data": map[string]string{"routes.json": string(routes)},
+	}, "", "  ")
+}
@@ -0,0 +1,120 @@
+package routerx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type languageContextKey struct{}
+
+// NegotiateLanguage returns a Middleware that selects the locale of the
+// response from available according to the Accept-Language header, falling
+// back to the first available locale. The choice is read with GetLanguage
+// and announced with Content-Language unless the handler sets its own.
+// Accept-Language is added to Vary when the response headers are written,
+// so it survives handlers and middlewares, such as compression, that set
+// Vary themselves.
+//
+// Example:
+//
+//	router.Path("/terms").
+//	    Use(routerx.NegotiateLanguage("en", "de", "fr-CA")).
+//	    Get(func(responseWriter http.ResponseWriter, request *http.Request) {
+//	        renderTerms(responseWriter, routerx.GetLanguage(request))
+//	    })
+func NegotiateLanguage(available ...string) Middleware {
+	if len(available) == 0 {
+		panic("routerx: NegotiateLanguage needs at least one locale")
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			language := negotiateLanguage(request.Header.Get("Accept-Language"), available, available[0])
+			request = request.WithContext(context.WithValue(request.Context(), languageContextKey{}, language))
+			next.ServeHTTP(&languageWriter{ResponseWriter: responseWriter, language: language}, request)
+		})
+	}
+}
+
+// GetLanguage returns the locale selected by NegotiateLanguage, or "" outside
+// that middleware.
+func GetLanguage(request *http.Request) string {
+	language, _ := request.Context().Value(languageContextKey{}).(string)
+	return language
+}
+
+// languageWriter sets Content-Language and Vary when the response headers are
+// written.
+type languageWriter struct {
+	http.ResponseWriter
+	language    string
+	wroteHeader bool
+}
+
+func (writer *languageWriter) WriteHeader(statusCode int) {
+	if !writer.wroteHeader {
+		writer.wroteHeader = true
+		header := writer.Header()
+		if header.Get("Content-Language") == "" {
+			header.Set("Content-Language", writer.language)
+		}
+		addVary(header, "Accept-Language")
+	}
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (writer *languageWriter) Write(data []byte) (int, error) {
+	if !writer.wroteHeader {
+		writer.WriteHeader(http.StatusOK)
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *languageWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// negotiateLanguage returns the available language preferred in an
+// Accept-Language header, or fallback. A range matches an available locale
+// exactly or by its primary subtag, so "de-CH" selects "de" and "fr" selects
+// "fr-CA".
+func negotiateLanguage(acceptLanguage string, available []string, fallback string) string {
+	best, bestQuality := fallback, 0.0
+	for _, member := range strings.Split(acceptLanguage, ",") {
+		tag, parameters, _ := strings.Cut(strings.TrimSpace(member), ";")
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(parameters), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= bestQuality {
+			continue
+		}
+		if match := findLanguage(available, strings.TrimSpace(tag)); match != "" {
+			best, bestQuality = match, quality
+		}
+	}
+	return best
+}
+
+// findLanguage returns the available locale matching tag exactly, or else
+// sharing its primary subtag.
+func findLanguage(available []string, tag string) string {
+	for _, language := range available {
+		if strings.EqualFold(language, tag) {
+			return language
+		}
+	}
+	primary, _, _ := strings.Cut(tag, "-")
+	for _, language := range available {
+		candidate, _, _ := strings.Cut(language, "-")
+		if strings.EqualFold(candidate, primary) {
+			return language
+		}
+	}
+	return ""
+}
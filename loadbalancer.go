@@ -0,0 +1,263 @@
+package routerx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// BalanceStrategy selects the upstream of a LoadBalancer for a request.
+type BalanceStrategy int
+
+const (
+	// RoundRobin sends requests to the upstreams in turn.
+	RoundRobin BalanceStrategy = iota
+
+	// LeastConnections sends a request to the upstream with the fewest
+	// requests in flight, which suits requests of very different cost.
+	LeastConnections
+
+	// Weighted sends requests to the upstreams in proportion to their
+	// weights, interleaved smoothly rather than in bursts.
+	Weighted
+)
+
+// Upstream is a backend of a LoadBalancer.
+type Upstream struct {
+	// URL is the absolute base URL of the backend.
+	URL string
+
+	// Weight is the share of requests for the Weighted strategy. Defaults
+	// to 1.
+	Weight int
+}
+
+// LoadBalancerConfig configures a LoadBalancer.
+type LoadBalancerConfig struct {
+	Upstreams []Upstream
+	Strategy  BalanceStrategy
+
+	// MaxFails is the number of consecutive failures, connection errors or
+	// 502, 503, and 504 responses, after which an upstream is ejected.
+	// Defaults to 3.
+	MaxFails int
+
+	// EjectFor is how long an ejected upstream receives no requests before
+	// it is tried again. Defaults to 30 seconds.
+	EjectFor time.Duration
+
+	// Clock tells the time for ejections, e.g. a FakeClock in tests.
+	// Defaults to SystemClock.
+	Clock Clock
+}
+
+// LoadBalancer spreads proxied requests over upstreams with a strategy and
+// ejects failing upstreams, see Router.ProxyBalanced. Health is checked
+// passively from the proxied requests. While every upstream is ejected,
+// requests are spread over all of them rather than failed outright. A
+// LoadBalancer may be shared by several routes and groups.
+type LoadBalancer struct {
+	config LoadBalancerConfig
+
+	mutex    sync.Mutex
+	backends []*backend
+	next     int
+}
+
+type backend struct {
+	url          *url.URL
+	weight       int
+	current      int
+	active       int
+	fails        int
+	ejectedUntil time.Time
+	requests     uint64
+	failures     uint64
+}
+
+// UpstreamStatus is the state of an upstream of a LoadBalancer.
+type UpstreamStatus struct {
+	URL      string `json:"url"`
+	Weight   int    `json:"weight"`
+	Healthy  bool   `json:"healthy"`
+	Active   int    `json:"active"`
+	Requests uint64 `json:"requests"`
+	Failures uint64 `json:"failures"`
+}
+
+type balancedRequestContextKey struct{}
+
+// balancedRequest tracks a proxied request on its upstream.
+type balancedRequest struct {
+	backend *backend
+	failed  bool
+}
+
+// NewLoadBalancer creates a LoadBalancer from config. It panics without
+// upstreams or with an upstream URL that is not absolute.
+func NewLoadBalancer(config LoadBalancerConfig) *LoadBalancer {
+	if len(config.Upstreams) == 0 {
+		panic("routerx: NewLoadBalancer needs at least one upstream")
+	}
+	if config.MaxFails <= 0 {
+		config.MaxFails = 3
+	}
+	if config.EjectFor <= 0 {
+		config.EjectFor = 30 * time.Second
+	}
+	if config.Clock == nil {
+		config.Clock = SystemClock
+	}
+	balancer := &LoadBalancer{config: config}
+	for _, upstream := range config.Upstreams {
+		balancer.backends = append(balancer.backends, &backend{
+			url:    parseUpstream(upstream.URL),
+			weight: max(upstream.Weight, 1),
+		})
+	}
+	return balancer
+}
+
+// pick selects the upstream of a request and counts it as active.
+func (balancer *LoadBalancer) pick() *backend {
+	balancer.mutex.Lock()
+	defer balancer.mutex.Unlock()
+	now := balancer.config.Clock.Now()
+	candidates := make([]*backend, 0, len(balancer.backends))
+	for _, candidate := range balancer.backends {
+		if now.After(candidate.ejectedUntil) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = balancer.backends
+	}
+
+	var chosen *backend
+	switch balancer.config.Strategy {
+	case LeastConnections:
+		for _, candidate := range candidates {
+			if chosen == nil || candidate.active < chosen.active {
+				chosen = candidate
+			}
+		}
+	case Weighted:
+		// Smooth weighted round-robin, as in nginx.
+		total := 0
+		for _, candidate := range candidates {
+			candidate.current += candidate.weight
+			total += candidate.weight
+			if chosen == nil || candidate.current > chosen.current {
+				chosen = candidate
+			}
+		}
+		chosen.current -= total
+	default:
+		chosen = candidates[balancer.next%len(candidates)]
+		balancer.next++
+	}
+	chosen.active++
+	chosen.requests++
+	return chosen
+}
+
+// release ends a request on its upstream and ejects the upstream after too
+// many consecutive failures.
+func (balancer *LoadBalancer) release(chosen *backend, failed bool) {
+	balancer.mutex.Lock()
+	defer balancer.mutex.Unlock()
+	chosen.active--
+	if !failed {
+		chosen.fails = 0
+		return
+	}
+	chosen.failures++
+	chosen.fails++
+	if chosen.fails >= balancer.config.MaxFails {
+		chosen.fails = 0
+		chosen.ejectedUntil = balancer.config.Clock.Now().Add(balancer.config.EjectFor)
+	}
+}
+
+// Status returns the state of every upstream, for dashboards and health
+// endpoints.
+func (balancer *LoadBalancer) Status() []UpstreamStatus {
+	balancer.mutex.Lock()
+	defer balancer.mutex.Unlock()
+	now := balancer.config.Clock.Now()
+	statuses := make([]UpstreamStatus, len(balancer.backends))
+	for index, backend := range balancer.backends {
+		statuses[index] = UpstreamStatus{
+			URL:      backend.url.String(),
+			Weight:   backend.weight,
+			Healthy:  now.After(backend.ejectedUntil),
+			Active:   backend.active,
+			Requests: backend.requests,
+			Failures: backend.failures,
+		}
+	}
+	return statuses
+}
+
+// ProxyBalanced forwards every request under prefix to an upstream of
+// balancer, like Router.Proxy does to a single target.
+//
+// Example:
+//
+//	users := routerx.NewLoadBalancer(routerx.LoadBalancerConfig{
+//	    Upstreams: []routerx.Upstream{
+//	        {URL: "http://users-1.internal:8080", Weight: 3},
+//	        {URL: "http://users-2.internal:8080", Weight: 1},
+//	    },
+//	    Strategy: routerx.Weighted,
+//	})
+//	router.ProxyBalanced("/api/users", users, routerx.ProxyConfig{StripPrefix: true})
+func (router *Router) ProxyBalanced(prefix string, balancer *LoadBalancer, configs ...ProxyConfig) {
+	router.proxyBalanced(cleanPath(prefix), balancer, configs, router.middlewares)
+}
+
+// ProxyBalanced forwards every request under the group's prefix joined with
+// prefix to an upstream of balancer, applying the group's middleware chain.
+// See Router.ProxyBalanced.
+func (group *RouteGroup) ProxyBalanced(prefix string, balancer *LoadBalancer, configs ...ProxyConfig) {
+	group.seal()
+	group.router.proxyBalanced(joinPath(group.prefix, prefix), balancer, configs, group.middlewares)
+}
+
+func (router *Router) proxyBalanced(prefix string, balancer *LoadBalancer, configs []ProxyConfig, middlewares []Middleware) {
+	var config ProxyConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	prefix = proxyPrefix(prefix)
+	proxy := newReverseProxy(prefix, config, func(request *http.Request) *url.URL {
+		return request.Context().Value(balancedRequestContextKey{}).(*balancedRequest).backend.url
+	})
+	proxy.ModifyResponse = func(response *http.Response) error {
+		switch response.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			response.Request.Context().Value(balancedRequestContextKey{}).(*balancedRequest).failed = true
+		}
+		if config.ModifyResponse != nil {
+			return config.ModifyResponse(response)
+		}
+		return nil
+	}
+	proxy.ErrorHandler = func(responseWriter http.ResponseWriter, request *http.Request, err error) {
+		if !errors.Is(err, context.Canceled) || request.Context().Err() == nil {
+			request.Context().Value(balancedRequestContextKey{}).(*balancedRequest).failed = true
+		}
+		proxyErrorHandler(responseWriter, request, err)
+	}
+	handler := http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		balanced := &balancedRequest{backend: balancer.pick()}
+		defer func() {
+			balancer.release(balanced.backend, balanced.failed)
+		}()
+		proxy.ServeHTTP(responseWriter, request.WithContext(context.WithValue(request.Context(), balancedRequestContextKey{}, balanced)))
+	})
+	router.registerProxy(prefix, handler, middlewares)
+}
@@ -0,0 +1,673 @@
+// Package loadgen generates load against a routerx router from scenarios
+// built on its route table, for smoke-load checks in CI: steps name routes
+// instead of spelling out URLs, path parameters come from generators, and
+// traffic is ramped up and down in stages, either in process or against a
+// running server. The Report has the latency percentiles of every step.
+//
+//	func TestLoad(t *testing.T) {
+//	    report, err := loadgen.Run(context.Background(), newRouter(), loadgen.Config{
+//	        Scenarios: []loadgen.Scenario{{
+//	            Name: "browse",
+//	            Steps: []loadgen.Step{
+//	                {Route: "UserList"},
+//	                {Route: "UserShow", Params: map[string]loadgen.Generator{"id": loadgen.IntRange(1, 100)}},
+//	            },
+//	        }},
+//	        Stages: []loadgen.Stage{
+//	            {Duration: 5 * time.Second, Users: 20},
+//	            {Duration: 10 * time.Second, Users: 20},
+//	        },
+//	    })
+//	    if err != nil {
+//	        t.Fatal(err)
+//	    }
+//	    t.Log(report)
+//	    if err := report.Check(loadgen.Thresholds{P95: 50 * time.Millisecond, ErrorRate: 0.01}); err != nil {
+//	        t.Fatal(err)
+//	    }
+//	}
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Mark-Bazylev/routerx"
+)
+
+// Generator produces a value, such as a path parameter, for every request.
+type Generator func(random *rand.Rand) string
+
+// Values returns a Generator picking one of values at random.
+func Values(values ...string) Generator {
+	if len(values) == 0 {
+		panic("loadgen: Values needs at least one value")
+	}
+	return func(random *rand.Rand) string {
+		return values[random.IntN(len(values))]
+	}
+}
+
+// IntRange returns a Generator of integers between min and max, inclusive.
+func IntRange(min int, max int) Generator {
+	if max < min {
+		panic("loadgen: IntRange needs min <= max")
+	}
+	return func(random *rand.Rand) string {
+		return strconv.Itoa(min + random.IntN(max-min+1))
+	}
+}
+
+// Format returns a Generator formatting the values of generators with
+// fmt.Sprintf, e.g. for request bodies.
+//
+// Example:
+//
+//	loadgen.Format(`{"name":%q,"age":%s}`, loadgen.Values("Ann", "Bob"), loadgen.IntRange(18, 90))
+func Format(format string, generators ...Generator) Generator {
+	return func(random *rand.Rand) string {
+		values := make([]any, len(generators))
+		for index, generator := range generators {
+			values[index] = generator(random)
+		}
+		return fmt.Sprintf(format, values...)
+	}
+}
+
+// Scenario is a sequence of steps a virtual user repeats until the run ends.
+type Scenario struct {
+	Name  string
+	Steps []Step
+
+	// Weight is the share of the iterations running this scenario relative
+	// to the other scenarios. Defaults to 1.
+	Weight int
+}
+
+// Step is a request of a Scenario.
+type Step struct {
+	// Name identifies the step in the Report. Defaults to Route, or to the
+	// method and path.
+	Name string
+
+	// Route is the name given to the route with PathBuilder.Name, or its
+	// pattern such as "GET /users/{id}". When several methods share the
+	// name, Method picks one; GET is preferred otherwise.
+	Route string
+
+	// Method and Path describe the request when Route is empty. Path may
+	// contain {name} parameters. Method defaults to GET.
+	Method string
+	Path   string
+
+	// Params generate the path parameters by name.
+	Params map[string]Generator
+
+	// Query generates the query parameters by name.
+	Query map[string]Generator
+
+	// Header is sent with every request.
+	Header http.Header
+
+	// Body generates the request body.
+	Body Generator
+
+	// Status is the expected status code. By default, responses with a
+	// status below 400 succeed.
+	Status int
+
+	method string
+	path   string
+}
+
+// Stage ramps the number of virtual users linearly from the users of the
+// previous stage, or zero for the first one, to Users over Duration.
+type Stage struct {
+	Duration time.Duration
+	Users    int
+}
+
+// Config configures Run.
+type Config struct {
+	Scenarios []Scenario
+
+	// Target is the base URL of a running server, e.g.
+	// "http://localhost:8080". By default, the requests are served by the
+	// router in process.
+	Target string
+
+	// Client sends the requests to Target. Defaults to a client keeping a
+	// connection alive per user.
+	Client *http.Client
+
+	// Stages shape the traffic. Without stages, Users virtual users run for
+	// Duration.
+	Stages []Stage
+
+	// Users defaults to 10 and Duration to 10 seconds.
+	Users    int
+	Duration time.Duration
+
+	// Seed makes the generated values repeatable across runs.
+	Seed uint64
+}
+
+// Thresholds are the limits Report.Check enforces. Zero values are not
+// checked.
+type Thresholds struct {
+	P95       time.Duration
+	P99       time.Duration
+	ErrorRate float64
+}
+
+// Report is the outcome of Run.
+type Report struct {
+	Duration time.Duration
+
+	// Total summarizes all requests, and Steps every step by name, in the
+	// order of the scenarios.
+	Total Stats
+	Steps []Stats
+
+	// Statuses counts the responses by status code.
+	Statuses map[int]int
+
+	// Errors counts the requests that got no response by error message.
+	Errors map[string]int
+}
+
+// Stats are the request counts and latency percentiles of a step.
+type Stats struct {
+	Name     string
+	Requests int
+	Failures int
+	Mean     time.Duration
+	P50      time.Duration
+	P90      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+// ErrorRate returns the share of the requests that failed.
+func (stats Stats) ErrorRate() float64 {
+	if stats.Requests == 0 {
+		return 0
+	}
+	return float64(stats.Failures) / float64(stats.Requests)
+}
+
+// Throughput returns the requests per second.
+func (report Report) Throughput() float64 {
+	if report.Duration <= 0 {
+		return 0
+	}
+	return float64(report.Total.Requests) / report.Duration.Seconds()
+}
+
+// Check returns an error describing the totals exceeding thresholds, or nil.
+func (report Report) Check(thresholds Thresholds) error {
+	var failures []string
+	if thresholds.P95 > 0 && report.Total.P95 > thresholds.P95 {
+		failures = append(failures, fmt.Sprintf("p95 latency %v exceeds %v", report.Total.P95, thresholds.P95))
+	}
+	if thresholds.P99 > 0 && report.Total.P99 > thresholds.P99 {
+		failures = append(failures, fmt.Sprintf("p99 latency %v exceeds %v", report.Total.P99, thresholds.P99))
+	}
+	if thresholds.ErrorRate > 0 && report.Total.ErrorRate() > thresholds.ErrorRate {
+		failures = append(failures, fmt.Sprintf("error rate %.2f%% exceeds %.2f%%", report.Total.ErrorRate()*100, thresholds.ErrorRate*100))
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return errors.New("loadgen: " + strings.Join(failures, "; "))
+}
+
+// String formats the report as a table.
+func (report Report) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%d requests in %v, %.1f/s\n", report.Total.Requests, report.Duration.Round(time.Millisecond), report.Throughput())
+	writer := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "STEP\tREQUESTS\tFAILED\tMEAN\tP50\tP90\tP95\tP99\tMAX")
+	for _, stats := range append(slices.Clone(report.Steps), report.Total) {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%v\t%v\t%v\t%v\t%v\t%v\n", stats.Name, stats.Requests, stats.Failures,
+			round(stats.Mean), round(stats.P50), round(stats.P90), round(stats.P95), round(stats.P99), round(stats.Max))
+	}
+	writer.Flush()
+	for _, status := range slices.Sorted(maps.Keys(report.Statuses)) {
+		fmt.Fprintf(&builder, "status %d: %d\n", status, report.Statuses[status])
+	}
+	for _, message := range slices.Sorted(maps.Keys(report.Errors)) {
+		fmt.Fprintf(&builder, "error %q: %d\n", message, report.Errors[message])
+	}
+	return builder.String()
+}
+
+// Run generates load on router, or on config.Target when set, until the
+// stages complete or ctx is canceled. The route table of router resolves the
+// routes named by the steps. Run returns an error when a step cannot be
+// resolved.
+func Run(ctx context.Context, router *routerx.Router, config Config) (Report, error) {
+	if len(config.Scenarios) == 0 {
+		return Report{}, errors.New("loadgen: no scenarios")
+	}
+	if router == nil && config.Target == "" {
+		return Report{}, errors.New("loadgen: a router or a target is required")
+	}
+	var routes []routerx.RouteInfo
+	if router != nil {
+		routes = router.Routes()
+	}
+	scenarios := slices.Clone(config.Scenarios)
+	totalWeight := 0
+	for index := range scenarios {
+		scenario := &scenarios[index]
+		if len(scenario.Steps) == 0 {
+			return Report{}, fmt.Errorf("loadgen: scenario %q has no steps", scenario.Name)
+		}
+		if scenario.Weight <= 0 {
+			scenario.Weight = 1
+		}
+		totalWeight += scenario.Weight
+		scenario.Steps = slices.Clone(scenario.Steps)
+		for step := range scenario.Steps {
+			if err := scenario.Steps[step].resolve(routes); err != nil {
+				return Report{}, fmt.Errorf("loadgen: scenario %q: %w", scenario.Name, err)
+			}
+		}
+	}
+
+	stages := config.Stages
+	if len(stages) == 0 {
+		users, duration := config.Users, config.Duration
+		if users <= 0 {
+			users = 10
+		}
+		if duration <= 0 {
+			duration = 10 * time.Second
+		}
+		// Start at full load: a stage ramping from users to users.
+		stages = []Stage{{Duration: 0, Users: users}, {Duration: duration, Users: users}}
+	}
+	var total time.Duration
+	maxUsers := 0
+	for _, stage := range stages {
+		total += stage.Duration
+		maxUsers = max(maxUsers, stage.Users)
+	}
+
+	var send sender
+	if config.Target == "" {
+		send = serveInProcess(router)
+	} else {
+		client := config.Client
+		if client == nil {
+			client = &http.Client{
+				Timeout:   30 * time.Second,
+				Transport: &http.Transport{MaxIdleConnsPerHost: maxUsers},
+			}
+		}
+		send = sendTo(client, strings.TrimSuffix(config.Target, "/"))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, total)
+	defer cancel()
+	var active atomic.Int64
+	workers := make([]*worker, maxUsers)
+	var group sync.WaitGroup
+	start := time.Now()
+	for index := range workers {
+		workers[index] = &worker{
+			index:     int64(index),
+			random:    rand.New(rand.NewPCG(config.Seed, uint64(index))),
+			samples:   make(map[string][]sample),
+			statuses:  make(map[int]int),
+			errors:    make(map[string]int),
+			scenarios: scenarios,
+			weight:    totalWeight,
+			send:      send,
+		}
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			workers[index].run(ctx, &active)
+		}()
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	for running := true; running; {
+		active.Store(int64(activeUsers(stages, time.Since(start))))
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-ticker.C:
+		}
+	}
+	ticker.Stop()
+	group.Wait()
+	return buildReport(scenarios, workers, time.Since(start)), nil
+}
+
+// activeUsers returns the number of virtual users elapsed into the stages.
+func activeUsers(stages []Stage, elapsed time.Duration) int {
+	previous := 0
+	for _, stage := range stages {
+		if elapsed < stage.Duration {
+			progress := float64(elapsed) / float64(stage.Duration)
+			return previous + int(float64(stage.Users-previous)*progress+0.5)
+		}
+		elapsed -= stage.Duration
+		previous = stage.Users
+	}
+	return previous
+}
+
+var parameterPattern = regexp.MustCompile(`\{([^}]*)\}`)
+
+// resolve looks up the route of the step and checks its parameters.
+func (step *Step) resolve(routes []routerx.RouteInfo) error {
+	step.method, step.path = step.Method, step.Path
+	if step.Route != "" {
+		route, err := findRoute(routes, step.Route, step.Method)
+		if err != nil {
+			return err
+		}
+		if step.method == "" {
+			step.method = route.Method
+		}
+		step.path = route.Path
+		// Strip the host of patterns such as "example.com/users".
+		step.path = step.path[strings.Index(step.path, "/"):]
+	}
+	if step.path == "" {
+		return errors.New("step needs a route or a path")
+	}
+	if step.method == "" {
+		step.method = http.MethodGet
+	}
+	if step.Name == "" {
+		step.Name = step.Route
+		if step.Name == "" {
+			step.Name = step.method + " " + step.path
+		}
+	}
+	for _, match := range parameterPattern.FindAllStringSubmatch(step.path, -1) {
+		name := strings.TrimSuffix(match[1], "...")
+		if name != "$" && step.Params[name] == nil {
+			return fmt.Errorf("step %q has no generator for parameter %q", step.Name, name)
+		}
+	}
+	return nil
+}
+
+// findRoute returns the route named name, or with the pattern name.
+func findRoute(routes []routerx.RouteInfo, name string, method string) (routerx.RouteInfo, error) {
+	var candidates []routerx.RouteInfo
+	for _, route := range routes {
+		pattern := strings.TrimSpace(route.Method + " " + route.Path)
+		if route.Name == name || pattern == name {
+			candidates = append(candidates, route)
+		}
+	}
+	if len(candidates) == 0 {
+		return routerx.RouteInfo{}, fmt.Errorf("unknown route %q", name)
+	}
+	if len(candidates) == 1 && method == "" {
+		return candidates[0], nil
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	for _, route := range candidates {
+		if route.Method == method {
+			return route, nil
+		}
+	}
+	for _, route := range candidates {
+		if route.Method == "" {
+			return route, nil
+		}
+	}
+	return routerx.RouteInfo{}, fmt.Errorf("route %q has no %s method", name, method)
+}
+
+// request generates a request of the step.
+func (step *Step) request(ctx context.Context, random *rand.Rand) *http.Request {
+	path := parameterPattern.ReplaceAllStringFunc(step.path, func(match string) string {
+		name := match[1 : len(match)-1]
+		if name == "$" {
+			return ""
+		}
+		if rest, found := strings.CutSuffix(name, "..."); found {
+			return step.Params[rest](random)
+		}
+		return url.PathEscape(step.Params[name](random))
+	})
+	if len(step.Query) > 0 {
+		query := make(url.Values, len(step.Query))
+		for _, name := range slices.Sorted(maps.Keys(step.Query)) {
+			query.Set(name, step.Query[name](random))
+		}
+		path += "?" + query.Encode()
+	}
+	var body io.Reader
+	if step.Body != nil {
+		body = strings.NewReader(step.Body(random))
+	}
+	request, err := http.NewRequestWithContext(ctx, step.method, "http://loadgen"+path, body)
+	if err != nil {
+		// The method and path were checked by resolve and the parameters
+		// are escaped, so only a broken generator gets here.
+		panic("loadgen: " + err.Error())
+	}
+	for name, values := range step.Header {
+		request.Header[name] = values
+	}
+	return request
+}
+
+// succeeded reports whether status is the response the step expects.
+func (step *Step) succeeded(status int) bool {
+	if step.Status != 0 {
+		return status == step.Status
+	}
+	return status < http.StatusBadRequest
+}
+
+// sender sends a request and returns the response status once the body was
+// read.
+type sender func(request *http.Request) (int, error)
+
+func serveInProcess(handler http.Handler) sender {
+	return func(request *http.Request) (int, error) {
+		request.RequestURI = request.URL.RequestURI()
+		request.RemoteAddr = "192.0.2.1:1234"
+		writer := &discardWriter{header: make(http.Header)}
+		handler.ServeHTTP(writer, request)
+		if writer.status == 0 {
+			writer.status = http.StatusOK
+		}
+		return writer.status, nil
+	}
+}
+
+func sendTo(client *http.Client, target string) sender {
+	return func(request *http.Request) (int, error) {
+		targetURL, err := url.Parse(target + request.URL.RequestURI())
+		if err != nil {
+			return 0, err
+		}
+		request.URL, request.Host = targetURL, ""
+		response, err := client.Do(request)
+		if err != nil {
+			return 0, err
+		}
+		defer response.Body.Close()
+		_, err = io.Copy(io.Discard, response.Body)
+		return response.StatusCode, err
+	}
+}
+
+// discardWriter is the ResponseWriter of in-process requests.
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+func (writer *discardWriter) Header() http.Header {
+	return writer.header
+}
+
+func (writer *discardWriter) WriteHeader(status int) {
+	if writer.status == 0 {
+		writer.status = status
+	}
+}
+
+func (writer *discardWriter) Write(data []byte) (int, error) {
+	writer.WriteHeader(http.StatusOK)
+	return len(data), nil
+}
+
+// worker is a virtual user. It records its samples without locking and the
+// samples of all workers are merged once the run ends.
+type worker struct {
+	index     int64
+	random    *rand.Rand
+	scenarios []Scenario
+	weight    int
+	send      sender
+
+	samples  map[string][]sample
+	statuses map[int]int
+	errors   map[string]int
+}
+
+type sample struct {
+	latency time.Duration
+	failed  bool
+}
+
+// run repeats scenarios while the worker is among the active users.
+func (worker *worker) run(ctx context.Context, active *atomic.Int64) {
+	for ctx.Err() == nil {
+		if worker.index >= active.Load() {
+			select {
+			case <-ctx.Done():
+			case <-time.After(10 * time.Millisecond):
+			}
+			continue
+		}
+		scenario := worker.pick()
+		for index := range scenario.Steps {
+			step := &scenario.Steps[index]
+			start := time.Now()
+			status, err := worker.send(step.request(ctx, worker.random))
+			latency := time.Since(start)
+			if ctx.Err() != nil {
+				// The run ended while the request was in flight.
+				return
+			}
+			if err != nil {
+				worker.errors[err.Error()]++
+			} else {
+				worker.statuses[status]++
+			}
+			worker.samples[step.Name] = append(worker.samples[step.Name], sample{latency: latency, failed: err != nil || !step.succeeded(status)})
+		}
+	}
+}
+
+// pick returns a scenario at random by weight.
+func (worker *worker) pick() *Scenario {
+	choice := worker.random.IntN(worker.weight)
+	for index := range worker.scenarios {
+		if choice < worker.scenarios[index].Weight {
+			return &worker.scenarios[index]
+		}
+		choice -= worker.scenarios[index].Weight
+	}
+	return &worker.scenarios[len(worker.scenarios)-1]
+}
+
+// buildReport merges the samples of the workers.
+func buildReport(scenarios []Scenario, workers []*worker, duration time.Duration) Report {
+	report := Report{Duration: duration, Statuses: make(map[int]int), Errors: make(map[string]int)}
+	var names []string
+	for _, scenario := range scenarios {
+		for _, step := range scenario.Steps {
+			if !slices.Contains(names, step.Name) {
+				names = append(names, step.Name)
+			}
+		}
+	}
+	var all []sample
+	for _, name := range names {
+		var samples []sample
+		for _, worker := range workers {
+			samples = append(samples, worker.samples[name]...)
+		}
+		all = append(all, samples...)
+		report.Steps = append(report.Steps, summarize(name, samples))
+	}
+	report.Total = summarize("total", all)
+	for _, worker := range workers {
+		for status, count := range worker.statuses {
+			report.Statuses[status] += count
+		}
+		for message, count := range worker.errors {
+			report.Errors[message] += count
+		}
+	}
+	return report
+}
+
+// summarize computes the stats of samples.
+func summarize(name string, samples []sample) Stats {
+	stats := Stats{Name: name, Requests: len(samples)}
+	if len(samples) == 0 {
+		return stats
+	}
+	latencies := make([]time.Duration, len(samples))
+	var sum time.Duration
+	for index, sample := range samples {
+		latencies[index] = sample.latency
+		sum += sample.latency
+		if sample.failed {
+			stats.Failures++
+		}
+	}
+	slices.Sort(latencies)
+	percentile := func(rank float64) time.Duration {
+		return latencies[int(rank*float64(len(latencies)-1)+0.5)]
+	}
+	stats.Mean = sum / time.Duration(len(samples))
+	stats.P50, stats.P90, stats.P95, stats.P99 = percentile(0.50), percentile(0.90), percentile(0.95), percentile(0.99)
+	stats.Max = latencies[len(latencies)-1]
+	return stats
+}
+
+// round rounds a latency for display.
+func round(duration time.Duration) time.Duration {
+	switch {
+	case duration >= time.Second:
+		return duration.Round(time.Millisecond)
+	case duration >= time.Millisecond:
+		return duration.Round(10 * time.Microsecond)
+	default:
+		return duration.Round(time.Microsecond)
+	}
+}
@@ -0,0 +1,123 @@
+package routerx
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// LoggerConfig configures the Logger middleware.
+type LoggerConfig struct {
+	// RequestIDHeader is the request header carrying the request ID.
+	// Defaults to "X-Request-ID".
+	RequestIDHeader string
+
+	// Headers lists request headers to log in addition to the standard
+	// fields, as "header.<name>" attributes.
+	Headers []string
+
+	// Redact lists fields whose values are replaced with "[REDACTED]", e.g.
+	// "remote_ip" or "header.Authorization".
+	Redact []string
+
+	// SampleRate is the fraction of successful requests that are logged,
+	// between 0 and 1. Responses with a status of 400 or above are always
+	// logged. Zero logs every request.
+	SampleRate float64
+}
+
+// Logger returns a Middleware that writes one structured record per request
+// to handler once the response is complete. Records carry the method, the
+// matched route pattern, the path, the status, the latency, the bytes
+// written, the request ID, and the remote IP, and are logged at Info, Warn
+// for 4xx, or Error for 5xx responses.
+//
+// Example:
+//
+//	router := routerx.New().
+//	    Use(routerx.Logger(slog.NewJSONHandler(os.Stderr, nil), routerx.LoggerConfig{
+//	        Redact:     []string{"remote_ip"},
+//	        SampleRate: 0.1,
+//	    }))
+func Logger(handler slog.Handler, configs ...LoggerConfig) Middleware {
+	var config LoggerConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.RequestIDHeader == "" {
+		config.RequestIDHeader = "X-Request-ID"
+	}
+	logger := slog.New(handler)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			clock := GetClock(request)
+			start := clock.Now()
+			writer := &statusWriter{ResponseWriter: responseWriter}
+			defer func() {
+				status := writer.status()
+				if !config.sampled(status, GetRandom(request)) {
+					return
+				}
+				level := slog.LevelInfo
+				switch {
+				case status >= http.StatusInternalServerError:
+					level = slog.LevelError
+				case status >= http.StatusBadRequest:
+					level = slog.LevelWarn
+				}
+				if !logger.Enabled(request.Context(), level) {
+					return
+				}
+				attributes := []slog.Attr{
+					slog.String("method", request.Method),
+					slog.String("route", routePattern(request.Pattern)),
+					slog.String("path", request.URL.Path),
+					slog.Int("status", status),
+					slog.Duration("latency", clock.Now().Sub(start)),
+					slog.Int64("bytes", writer.written),
+					slog.String("request_id", request.Header.Get(config.RequestIDHeader)),
+					slog.String("remote_ip", remoteIP(request)),
+				}
+				for _, name := range config.Headers {
+					attributes = append(attributes, slog.String("header."+name, request.Header.Get(name)))
+				}
+				for index, attribute := range attributes {
+					if slices.Contains(config.Redact, attribute.Key) {
+						attributes[index].Value = slog.StringValue("[REDACTED]")
+					}
+				}
+				logger.LogAttrs(request.Context(), level, "request", attributes...)
+			}()
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// sampled reports whether a response with status is logged.
+func (config LoggerConfig) sampled(status int, random io.Reader) bool {
+	if config.SampleRate <= 0 || config.SampleRate >= 1 || status >= http.StatusBadRequest {
+		return true
+	}
+	return randomFloat(random) < config.SampleRate
+}
+
+// routePattern returns the path of a matched ServeMux pattern, without its
+// method.
+func routePattern(pattern string) string {
+	if _, path, found := strings.Cut(pattern, " "); found {
+		return path
+	}
+	return pattern
+}
+
+// remoteIP returns the IP address of the client connection.
+func remoteIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,179 @@
+package routerx
+
+import (
+	"net/http"
+	"runtime/metrics"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// MemoryGuardConfig configures a MemoryGuard.
+type MemoryGuardConfig struct {
+	// Budget is the memory, in bytes, that the requests in flight may be
+	// estimated to use together. Required.
+	Budget int64
+
+	// ExpansionFactor is how much larger than its body a request is
+	// estimated to grow in memory once decoded, e.g. JSON into maps and
+	// strings. Defaults to 4.
+	ExpansionFactor float64
+
+	// BaseCost is the estimate for a request without a body, covering
+	// headers, buffers, and the handler's own allocations. Defaults to 64
+	// KiB.
+	BaseCost int64
+
+	// UnknownLength is the body size assumed for requests without a
+	// Content-Length, such as chunked uploads. Defaults to 1 MiB.
+	UnknownLength int64
+
+	// HeapLimit additionally sheds requests while the live heap plus their
+	// estimate exceeds it, e.g. set a little below GOMEMLIMIT or the
+	// container limit. Zero disables the check.
+	HeapLimit int64
+
+	// RetryAfter is announced to shed clients. Defaults to 1 second.
+	RetryAfter time.Duration
+}
+
+// MemoryGuard sheds load before a server runs out of memory. It is
+// experimental: it estimates the memory of every request from the size of
+// its body and reserves the estimate from a global budget while the request
+// is served, answering requests that do not fit with 503 Service
+// Unavailable. This protects multi-tenant servers from a burst of large
+// untrusted payloads; combine it with BodyLimit, since a body larger than
+// its Content-Length announced is not accounted.
+//
+// Example:
+//
+//	guard := routerx.NewMemoryGuard(routerx.MemoryGuardConfig{
+//	    Budget:    512 << 20,
+//	    HeapLimit: 900 << 20,
+//	})
+//	router := routerx.New().Use(guard.Middleware(), routerx.BodyLimit(10<<20))
+//	admin.Get("/memory-guard", guard.Handler())
+type MemoryGuard struct {
+	config MemoryGuardConfig
+
+	reserved       atomic.Int64
+	inFlight       atomic.Int64
+	admitted       atomic.Uint64
+	rejectedBudget atomic.Uint64
+	rejectedHeap   atomic.Uint64
+}
+
+// MemoryGuardStats is a snapshot of a MemoryGuard.
+type MemoryGuardStats struct {
+	Budget   int64  `json:"budget_bytes"`
+	Reserved int64  `json:"reserved_bytes"`
+	InFlight int64  `json:"in_flight"`
+	Admitted uint64 `json:"admitted"`
+
+	// RejectedBudget counts the requests shed because the budget was
+	// exhausted, RejectedHeap those shed because of the heap limit.
+	RejectedBudget uint64 `json:"rejected_budget"`
+	RejectedHeap   uint64 `json:"rejected_heap"`
+
+	// LiveHeap is the heap memory in use after the last garbage collection.
+	LiveHeap uint64 `json:"live_heap_bytes"`
+}
+
+// NewMemoryGuard creates a MemoryGuard from config.
+func NewMemoryGuard(config MemoryGuardConfig) *MemoryGuard {
+	if config.Budget <= 0 {
+		panic("routerx: NewMemoryGuard needs a positive budget")
+	}
+	if config.ExpansionFactor <= 0 {
+		config.ExpansionFactor = 4
+	}
+	if config.BaseCost <= 0 {
+		config.BaseCost = 64 << 10
+	}
+	if config.UnknownLength <= 0 {
+		config.UnknownLength = 1 << 20
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+	return &MemoryGuard{config: config}
+}
+
+// Middleware returns a Middleware admitting the requests whose estimate
+// fits the budget.
+func (guard *MemoryGuard) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			estimate := guard.estimate(request)
+			if guard.config.HeapLimit > 0 && int64(liveHeap())+estimate > guard.config.HeapLimit {
+				guard.rejectedHeap.Add(1)
+				guard.reject(responseWriter)
+				return
+			}
+			if guard.reserved.Add(estimate) > guard.config.Budget {
+				guard.reserved.Add(-estimate)
+				guard.rejectedBudget.Add(1)
+				guard.reject(responseWriter)
+				return
+			}
+			guard.admitted.Add(1)
+			guard.inFlight.Add(1)
+			defer func() {
+				guard.inFlight.Add(-1)
+				guard.reserved.Add(-estimate)
+			}()
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
+
+// estimate returns the memory a request is expected to use. Estimates
+// above the budget are capped just past it, so that a huge Content-Length
+// is rejected instead of overflowing the reservation.
+func (guard *MemoryGuard) estimate(request *http.Request) int64 {
+	length := request.ContentLength
+	if length < 0 {
+		length = guard.config.UnknownLength
+	}
+	estimate := float64(guard.config.BaseCost) + float64(length)*guard.config.ExpansionFactor
+	if estimate > float64(guard.config.Budget) {
+		return guard.config.Budget + 1
+	}
+	return int64(estimate)
+}
+
+func (guard *MemoryGuard) reject(responseWriter http.ResponseWriter) {
+	responseWriter.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(guard.config.RetryAfter), 1)))
+	writeError(responseWriter, http.StatusServiceUnavailable, "server is over its memory budget")
+}
+
+// Stats returns the current reservations and the rejection counters.
+func (guard *MemoryGuard) Stats() MemoryGuardStats {
+	return MemoryGuardStats{
+		Budget:         guard.config.Budget,
+		Reserved:       guard.reserved.Load(),
+		InFlight:       guard.inFlight.Load(),
+		Admitted:       guard.admitted.Load(),
+		RejectedBudget: guard.rejectedBudget.Load(),
+		RejectedHeap:   guard.rejectedHeap.Load(),
+		LiveHeap:       liveHeap(),
+	}
+}
+
+// Handler returns a handler serving Stats as JSON.
+func (guard *MemoryGuard) Handler() http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusOK, guard.Stats())
+	}
+}
+
+// liveHeap returns the heap memory marked live by the last garbage
+// collection.
+func liveHeap() uint64 {
+	sample := []metrics.Sample{{Name: "/gc/heap/live:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,91 @@
+package routerx
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+type routeContextKey struct{}
+
+// Meta attaches the metadata key and value to all methods registered on the
+// builder after calling Meta. Middlewares read it with GetRouteInfo, which
+// lets generic middlewares, such as permission checks or per-route rate
+// limits, be configured where the route is registered.
+//
+// Example:
+//
+//	router.Path("/users").
+//	    Meta("permission", "users:write").
+//	    Tag("admin").
+//	    Post(createUser)
+func (builder *PathBuilder) Meta(key string, value string) *PathBuilder {
+	meta := maps.Clone(builder.meta)
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+	meta[key] = value
+	builder.meta = meta
+	return builder
+}
+
+// Tag adds tags to all methods registered on the builder after calling Tag.
+// Tags are readable with GetRouteInfo and group the operations of the
+// OpenAPI document.
+func (builder *PathBuilder) Tag(tags ...string) *PathBuilder {
+	merged := slices.Clone(builder.tags)
+	for _, tag := range tags {
+		if !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	builder.tags = merged
+	return builder
+}
+
+// GetRouteInfo returns the route that matched request, with its metadata and
+// tags, and whether a route matched. It is available to the middlewares of
+// the route and to its handler.
+//
+// Example:
+//
+//	func requirePermission(next http.Handler) http.Handler {
+//	    return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+//	        info, _ := routerx.GetRouteInfo(request)
+//	        if permission := info.Meta["permission"]; permission != "" && !can(request, permission) {
+//	            routerx.HandleError(responseWriter, request, routerx.NewHTTPError(http.StatusForbidden, "missing permission "+permission))
+//	            return
+//	        }
+//	        next.ServeHTTP(responseWriter, request)
+//	    })
+//	}
+func GetRouteInfo(request *http.Request) (RouteInfo, bool) {
+	matched, ok := request.Context().Value(routeContextKey{}).(*route)
+	if !ok {
+		return RouteInfo{}, false
+	}
+	return matched.info(), true
+}
+
+// HasTag reports whether the route has tag.
+func (info RouteInfo) HasTag(tag string) bool {
+	return slices.Contains(info.Tags, tag)
+}
+
+// info describes the route. The metadata and tags are shared, so callers
+// must not modify them.
+func (registered *route) info() RouteInfo {
+	return RouteInfo{
+		Method: registered.method,
+		Path:   registered.path,
+		Name:   registered.name,
+		Meta:   registered.meta,
+		Tags:   registered.tags,
+	}
+}
+
+// withRoute exposes the matched route to GetRouteInfo.
+func withRoute(request *http.Request, matched *route) *http.Request {
+	return request.WithContext(context.WithValue(request.Context(), routeContextKey{}, matched))
+}
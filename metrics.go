@@ -0,0 +1,319 @@
+package routerx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsConfig configures Metrics.
+type MetricsConfig struct {
+	// Namespace prefixes the metric names. Defaults to "http".
+	Namespace string
+
+	// Buckets are the upper bounds in seconds of the request duration
+	// histogram. Defaults to the Prometheus client defaults, from 5ms to
+	// 10s.
+	Buckets []float64
+
+	// DeviceLabel adds a "device" label with the class attached by
+	// ClassifyDevices, which must run before the metrics middleware.
+	DeviceLabel bool
+
+	// TraceID returns the ID of the trace a request belongs to, or "" when
+	// the trace is not sampled. Duration observations of traced requests
+	// carry it as an exemplar, which lets dashboards jump from a latency
+	// bucket to a matching trace. Defaults to the trace ID of a sampled W3C
+	// traceparent request header; tracing.TraceID also covers traces that
+	// start in this service, when the tracing middleware runs first.
+	TraceID func(request *http.Request) string
+}
+
+// Metrics collects request counts, request durations, and in-flight requests
+// per method and route pattern and serves them in the Prometheus text
+// exposition format. Requests are labeled with the pattern they matched,
+// such as "/users/{id}", never with the raw URL, so the number of series
+// stays bounded; requests that matched no route share the route label
+// "unmatched".
+//
+// The metrics are served in the OpenMetrics format, with exemplars, to
+// scrapers that accept it.
+//
+// The exported metrics are, for the default namespace:
+//
+//   - http_requests_total{method, route, status}
+//   - http_request_duration_seconds{method, route} histogram
+//   - http_requests_in_flight{method, route}
+//
+// Example:
+//
+//	metrics := routerx.NewMetrics(routerx.MetricsConfig{})
+//	router := routerx.New().Use(metrics.Middleware())
+//	router.Get("/metrics", metrics.Handler())
+type Metrics struct {
+	config MetricsConfig
+
+	mutex     sync.Mutex
+	requests  map[metricsKey]uint64
+	durations map[metricsKey]*histogram
+	inFlight  map[metricsKey]int64
+}
+
+// metricsKey identifies a series. status is empty for the duration and
+// in-flight series.
+type metricsKey struct {
+	method string
+	route  string
+	device string
+	status string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+
+	// exemplars holds the latest exemplar of every bucket, and of +Inf
+	// last.
+	exemplars []*exemplar
+}
+
+// exemplar links an observation to the trace of its request.
+type exemplar struct {
+	traceID string
+	value   float64
+	time    time.Time
+}
+
+var defaultMetricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// NewMetrics creates Metrics from config.
+func NewMetrics(config MetricsConfig) *Metrics {
+	if config.Namespace == "" {
+		config.Namespace = "http"
+	}
+	if len(config.Buckets) == 0 {
+		config.Buckets = defaultMetricsBuckets
+	}
+	config.Buckets = slices.Sorted(slices.Values(config.Buckets))
+	if config.TraceID == nil {
+		config.TraceID = traceParentID
+	}
+	return &Metrics{
+		config:    config,
+		requests:  make(map[metricsKey]uint64),
+		durations: make(map[metricsKey]*histogram),
+		inFlight:  make(map[metricsKey]int64),
+	}
+}
+
+// Middleware returns a Middleware recording the metrics of every request it
+// wraps. Use it on the router to cover all routes and a NotFound handler set
+// with Router.NotFound.
+func (metrics *Metrics) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			key := metricsKey{method: metricsMethod(request.Method), route: metricsRoute(request.Pattern)}
+			if metrics.config.DeviceLabel {
+				key.device = string(GetDeviceClass(request))
+			}
+			metrics.mutex.Lock()
+			metrics.inFlight[key]++
+			metrics.mutex.Unlock()
+
+			clock := GetClock(request)
+			start := clock.Now()
+			writer := &statusWriter{ResponseWriter: responseWriter}
+			defer func() {
+				now := clock.Now()
+				metrics.observe(key, writer.status(), now.Sub(start), metrics.config.TraceID(request), now)
+			}()
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// observe records a request finished at now, with an exemplar when traceID
+// is set.
+func (metrics *Metrics) observe(key metricsKey, status int, duration time.Duration, traceID string, now time.Time) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.inFlight[key]--
+
+	counted := key
+	counted.status = strconv.Itoa(status)
+	metrics.requests[counted]++
+
+	durations := metrics.durations[key]
+	if durations == nil {
+		durations = &histogram{
+			counts:    make([]uint64, len(metrics.config.Buckets)),
+			exemplars: make([]*exemplar, len(metrics.config.Buckets)+1),
+		}
+		metrics.durations[key] = durations
+	}
+	seconds := duration.Seconds()
+	bucket := len(metrics.config.Buckets)
+	for index, bound := range metrics.config.Buckets {
+		if seconds <= bound {
+			durations.counts[index]++
+			bucket = min(bucket, index)
+		}
+	}
+	if traceID != "" {
+		durations.exemplars[bucket] = &exemplar{traceID: traceID, value: seconds, time: now}
+	}
+	durations.sum += seconds
+	durations.count++
+}
+
+// Handler returns a handler serving the collected metrics in the Prometheus
+// text exposition format, or in the OpenMetrics format with exemplars when
+// the scraper accepts it.
+func (metrics *Metrics) Handler() http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		openMetrics := strings.Contains(request.Header.Get("Accept"), "application/openmetrics-text")
+		if openMetrics {
+			responseWriter.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		} else {
+			responseWriter.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		}
+		addVary(responseWriter.Header(), "Accept")
+		writer := bufio.NewWriter(responseWriter)
+		metrics.write(writer, openMetrics)
+		_ = writer.Flush()
+	}
+}
+
+// write writes the collected metrics in the Prometheus text exposition
+// format, or in the OpenMetrics format with exemplars.
+func (metrics *Metrics) write(writer io.Writer, openMetrics bool) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	namespace := metrics.config.Namespace
+
+	// OpenMetrics names counters without their _total suffix.
+	counter := namespace + "_requests_total"
+	if openMetrics {
+		counter = namespace + "_requests"
+	}
+	fmt.Fprintf(writer, "# HELP %s Total number of HTTP requests by method, route, and status.\n", counter)
+	fmt.Fprintf(writer, "# TYPE %s counter\n", counter)
+	for _, key := range sortedMetricsKeys(metrics.requests) {
+		fmt.Fprintf(writer, "%s_requests_total{%s} %d\n", namespace, key.labels(), metrics.requests[key])
+	}
+
+	fmt.Fprintf(writer, "# HELP %s_request_duration_seconds Duration of HTTP requests by method and route.\n", namespace)
+	fmt.Fprintf(writer, "# TYPE %s_request_duration_seconds histogram\n", namespace)
+	for _, key := range sortedMetricsKeys(metrics.durations) {
+		durations := metrics.durations[key]
+		labels := key.labels()
+		for index, bound := range metrics.config.Buckets {
+			fmt.Fprintf(writer, "%s_request_duration_seconds_bucket{%s,le=\"%s\"} %d%s\n",
+				namespace, labels, formatBound(bound, openMetrics), durations.counts[index],
+				durations.exemplars[index].format(openMetrics))
+		}
+		fmt.Fprintf(writer, "%s_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d%s\n", namespace, labels, durations.count,
+			durations.exemplars[len(metrics.config.Buckets)].format(openMetrics))
+		fmt.Fprintf(writer, "%s_request_duration_seconds_sum{%s} %s\n", namespace, labels, strconv.FormatFloat(durations.sum, 'g', -1, 64))
+		fmt.Fprintf(writer, "%s_request_duration_seconds_count{%s} %d\n", namespace, labels, durations.count)
+	}
+
+	fmt.Fprintf(writer, "# HELP %s_requests_in_flight Number of HTTP requests being served by method and route.\n", namespace)
+	fmt.Fprintf(writer, "# TYPE %s_requests_in_flight gauge\n", namespace)
+	for _, key := range sortedMetricsKeys(metrics.inFlight) {
+		fmt.Fprintf(writer, "%s_requests_in_flight{%s} %d\n", namespace, key.labels(), metrics.inFlight[key])
+	}
+	if openMetrics {
+		fmt.Fprint(writer, "# EOF\n")
+	}
+}
+
+// formatBound formats a bucket bound, which OpenMetrics requires to be a
+// float such as "1.0".
+func formatBound(bound float64, openMetrics bool) string {
+	formatted := strconv.FormatFloat(bound, 'g', -1, 64)
+	if openMetrics && !strings.ContainsAny(formatted, ".e") {
+		formatted += ".0"
+	}
+	return formatted
+}
+
+// format returns the exemplar suffix of a bucket sample, or "" when there is
+// no exemplar or the format has none.
+func (exemplar *exemplar) format(openMetrics bool) string {
+	if exemplar == nil || !openMetrics {
+		return ""
+	}
+	return fmt.Sprintf(` # {trace_id="%s"} %s %s`, escapeLabel(exemplar.traceID),
+		strconv.FormatFloat(exemplar.value, 'g', -1, 64),
+		strconv.FormatFloat(float64(exemplar.time.UnixMilli())/1000, 'f', 3, 64))
+}
+
+// traceParentID returns the trace ID of a sampled W3C traceparent header,
+// "00-<trace ID>-<parent ID>-<flags>", or "".
+func traceParentID(request *http.Request) string {
+	fields := strings.Split(request.Header.Get("Traceparent"), "-")
+	if len(fields) != 4 || len(fields[1]) != 32 || fields[1] == strings.Repeat("0", 32) || len(fields[3]) != 2 {
+		return ""
+	}
+	flags, err := strconv.ParseUint(fields[3], 16, 8)
+	if err != nil || flags&0x01 == 0 {
+		return ""
+	}
+	return fields[1]
+}
+
+// labels formats the key as Prometheus labels.
+func (key metricsKey) labels() string {
+	labels := `method="` + escapeLabel(key.method) + `",route="` + escapeLabel(key.route) + `"`
+	if key.device != "" {
+		labels += `,device="` + escapeLabel(key.device) + `"`
+	}
+	if key.status != "" {
+		labels += `,status="` + key.status + `"`
+	}
+	return labels
+}
+
+func sortedMetricsKeys[V any](series map[metricsKey]V) []metricsKey {
+	keys := make([]metricsKey, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(first metricsKey, second metricsKey) int {
+		return strings.Compare(first.route+" "+first.method+" "+first.device+" "+first.status,
+			second.route+" "+second.method+" "+second.device+" "+second.status)
+	})
+	return keys
+}
+
+// metricsRoute returns the route label for a matched ServeMux pattern.
+func metricsRoute(pattern string) string {
+	if pattern == "" {
+		return "unmatched"
+	}
+	return routePattern(pattern)
+}
+
+// metricsMethod bounds the method label to the standard methods.
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
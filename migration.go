@@ -0,0 +1,141 @@
+package routerx
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"sync/atomic"
+)
+
+// MigrationConfig configures a Migration.
+type MigrationConfig struct {
+	// Name identifies the migration. It salts the cohort hash so that
+	// different migrations select different cohorts, and names the cookie
+	// of anonymous cohorts.
+	Name string
+
+	// Percent is the share of eligible requests, from 0 to 100, served by
+	// the new handler. It can be changed later with SetPercent.
+	Percent float64
+
+	// Eligible selects the requests taking part in the migration, e.g. by
+	// header or tenant. Other requests always reach the legacy handler.
+	// Defaults to every request.
+	Eligible func(request *http.Request) bool
+
+	// Cohort returns the key that keeps a caller in the same cohort, e.g. a
+	// user ID. Requests without a key are assigned a random key kept in a
+	// cookie. Defaults to the cookie alone.
+	Cohort func(request *http.Request) string
+}
+
+// Migration routes a percentage of requests to a new handler while the rest
+// reach the legacy one. Callers are assigned by hashing their cohort key, so
+// a caller keeps seeing the same implementation across requests, and raising
+// the percentage only moves callers from the legacy to the new handler.
+//
+// Example:
+//
+//	migration := routerx.NewMigration(routerx.MigrationConfig{
+//	    Name:     "orders-v2",
+//	    Percent:  10,
+//	    Eligible: func(request *http.Request) bool { return request.Header.Get("X-Region") == "eu" },
+//	    Cohort:   func(request *http.Request) string { return request.Header.Get("X-User-ID") },
+//	})
+//	router.Get("/orders/{id}", migration.Handler(legacyOrders, ordersService))
+//	migration.SetPercent(50)
+type Migration struct {
+	config MigrationConfig
+
+	// basisPoints is the percentage in hundredths of a percent.
+	basisPoints atomic.Int64
+}
+
+// NewMigration creates a Migration from config.
+func NewMigration(config MigrationConfig) *Migration {
+	migration := &Migration{config: config}
+	migration.SetPercent(config.Percent)
+	return migration
+}
+
+// SetPercent changes the share of eligible requests served by the new
+// handler. Values are clamped to the range 0 to 100.
+func (migration *Migration) SetPercent(percent float64) {
+	migration.basisPoints.Store(int64(math.Round(min(max(percent, 0), 100) * 100)))
+}
+
+// Percent returns the share of eligible requests served by the new handler.
+func (migration *Migration) Percent() float64 {
+	return float64(migration.basisPoints.Load()) / 100
+}
+
+// Migrated reports whether request belongs to the cohort served by the new
+// handler. It may set the cohort cookie on responseWriter.
+func (migration *Migration) Migrated(responseWriter http.ResponseWriter, request *http.Request) bool {
+	if migration.config.Eligible != nil && !migration.config.Eligible(request) {
+		return false
+	}
+	basisPoints := migration.basisPoints.Load()
+	if basisPoints <= 0 {
+		return false
+	}
+	if basisPoints >= 10000 {
+		return true
+	}
+	return migration.bucket(migration.cohortKey(responseWriter, request)) < uint64(basisPoints)
+}
+
+// Handler returns a handler serving migrated requests with next and the
+// others with legacy. The chosen side is reported in the X-Migration-Cohort
+// response header as "new" or "legacy".
+func (migration *Migration) Handler(legacy http.Handler, next http.Handler) http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		if migration.Migrated(responseWriter, request) {
+			responseWriter.Header().Set("X-Migration-Cohort", "new")
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		responseWriter.Header().Set("X-Migration-Cohort", "legacy")
+		legacy.ServeHTTP(responseWriter, request)
+	}
+}
+
+// cohortKey returns the cohort key of request, assigning a random one kept
+// in a cookie when the configured Cohort function yields none.
+func (migration *Migration) cohortKey(responseWriter http.ResponseWriter, request *http.Request) string {
+	if migration.config.Cohort != nil {
+		if key := migration.config.Cohort(request); key != "" {
+			return key
+		}
+	}
+	cookieName := "routerx_cohort"
+	if migration.config.Name != "" {
+		cookieName += "_" + migration.config.Name
+	}
+	if cookie, err := request.Cookie(cookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	buffer := make([]byte, 16)
+	_, _ = io.ReadFull(GetRandom(request), buffer)
+	key := hex.EncodeToString(buffer)
+	http.SetCookie(responseWriter, &http.Cookie{
+		Name:     cookieName,
+		Value:    key,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return key
+}
+
+// bucket maps a cohort key to one of 10000 buckets.
+func (migration *Migration) bucket(key string) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(migration.config.Name))
+	_, _ = hash.Write([]byte{0})
+	_, _ = hash.Write([]byte(key))
+	return hash.Sum64() % 10000
+}
@@ -0,0 +1,88 @@
+package routerx
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// SoftDeleter is implemented by models that are soft-deleted, e.g. from a
+// deleted_at column. A non-zero DeletedAt marks the model as deleted.
+type SoftDeleter interface {
+	DeletedAt() time.Time
+}
+
+// GoneError reports that a resource existed but was deleted. Its status code
+// is 410 Gone, and DefaultErrorHandler answers it with
+// {"error": "user was deleted", "deleted_at": time}, telling clients that
+// retrying or searching for the resource is pointless.
+type GoneError struct {
+	// Resource names the deleted resource, e.g. "user".
+	Resource string
+
+	// DeletedAt is when the resource was deleted, if known.
+	DeletedAt time.Time
+}
+
+func (err *GoneError) Error() string {
+	if err.Resource == "" {
+		return "resource was deleted"
+	}
+	return err.Resource + " was deleted"
+}
+
+// StatusCode returns 410 Gone.
+func (err *GoneError) StatusCode() int {
+	return http.StatusGone
+}
+
+type modelContextKey struct {
+	parameter string
+}
+
+// BindModel returns a Middleware that loads the model identified by the
+// named path parameter with load and attaches it to the request, where
+// handlers read it with Model. Errors returned by load are answered by
+// HandleError, so a loader returns an error implementing StatusCode() int,
+// such as NewHTTPError(http.StatusNotFound, ...), for missing models.
+// Loaders distinguish deleted models either by returning a *GoneError or by
+// returning a model implementing SoftDeleter; both are answered with 410
+// Gone instead of 404 Not Found.
+//
+// Example:
+//
+//	func (user User) DeletedAt() time.Time { return user.Deleted }
+//
+//	loadUser := routerx.BindModel("user", func(ctx context.Context, id string) (User, error) {
+//	    user, err := store.UserIncludingDeleted(ctx, id)
+//	    if errors.Is(err, sql.ErrNoRows) {
+//	        return User{}, routerx.NewHTTPError(http.StatusNotFound, "user not found")
+//	    }
+//	    return user, err
+//	})
+//	router.Path("/users/{user}").Use(loadUser).Get(func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    routerx.Render(responseWriter, request, http.StatusOK, routerx.Model[User](request, "user"))
+//	})
+func BindModel[T any](parameter string, load func(ctx context.Context, id string) (T, error)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			model, err := load(request.Context(), request.PathValue(parameter))
+			if err != nil {
+				HandleError(responseWriter, request, err)
+				return
+			}
+			if deleted, ok := any(model).(SoftDeleter); ok && !deleted.DeletedAt().IsZero() {
+				HandleError(responseWriter, request, &GoneError{Resource: parameter, DeletedAt: deleted.DeletedAt()})
+				return
+			}
+			next.ServeHTTP(responseWriter, request.WithContext(context.WithValue(request.Context(), modelContextKey{parameter}, model)))
+		})
+	}
+}
+
+// Model returns the model loaded by BindModel for the named path parameter,
+// or the zero value when none was loaded.
+func Model[T any](request *http.Request, parameter string) T {
+	model, _ := request.Context().Value(modelContextKey{parameter}).(T)
+	return model
+}
@@ -0,0 +1,42 @@
+package routerx
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Mount serves every request under prefix, for any method, with handler. The
+// prefix is stripped from the request path before handler runs, so handler
+// sees "/users" for a request to "/admin/users" mounted at "/admin". The
+// router's middleware chain is applied. This makes it easy to embed another
+// Router, a third-party admin UI, or net/http/pprof.
+//
+// Example:
+//
+//	admin := routerx.New()
+//	admin.Get("/users", listUsers)
+//	router.Mount("/admin", admin) // GET /admin/users
+func (router *Router) Mount(prefix string, handler http.Handler) {
+	router.mount(cleanPath(prefix), handler, router.middlewares)
+}
+
+// Mount serves every request under the group's prefix joined with prefix
+// with handler, stripping the full prefix and applying the group's
+// middleware chain. See Router.Mount.
+//
+// Example:
+//
+//	internal := router.Group("/internal").Use(RequireAdmin)
+//	internal.Mount("/debug", debugHandler) // /internal/debug/...
+func (group *RouteGroup) Mount(prefix string, handler http.Handler) {
+	group.seal()
+	group.router.mount(joinPath(group.prefix, prefix), handler, group.middlewares)
+}
+
+func (router *Router) mount(prefix string, handler http.Handler, middlewares []Middleware) {
+	stripped := http.StripPrefix(strings.TrimSuffix(prefix, "/"), handler)
+	if prefix == "/" {
+		stripped = handler
+	}
+	router.register("", strings.TrimSuffix(prefix, "/")+"/", stripped, middlewares, nil)
+}
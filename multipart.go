@@ -0,0 +1,304 @@
+package routerx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path"
+	"slices"
+	"strings"
+)
+
+// PartSpec declares one part of a multipart request.
+type PartSpec struct {
+	// Name is the form field name of the part.
+	Name string
+
+	// Required rejects requests without the part.
+	Required bool
+
+	// Multiple allows the part to appear more than once.
+	Multiple bool
+
+	// MaxBytes limits the size of each occurrence. Defaults to 10 MiB.
+	MaxBytes int64
+
+	// MaxCount limits how often a Multiple part may appear. Defaults to 10.
+	MaxCount int
+
+	// ContentTypes lists the accepted media types, which may use wildcards
+	// such as "image/*". Empty accepts any type.
+	ContentTypes []string
+}
+
+// MultipartSchema declares the parts of a mixed multipart request, e.g. a
+// JSON metadata part followed by binary attachments.
+type MultipartSchema struct {
+	Parts []PartSpec
+
+	// AllowUnknown accepts and ignores parts not listed in Parts instead of
+	// rejecting the request.
+	AllowUnknown bool
+
+	// MaxBytes limits the total size of the parts buffered for a request,
+	// since every part is held in memory. Defaults to 32 MiB.
+	MaxBytes int64
+}
+
+// Part is one received part of a multipart request.
+type Part struct {
+	Name        string
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// Reader returns a reader over the part contents.
+func (part *Part) Reader() io.Reader {
+	return bytes.NewReader(part.Data)
+}
+
+// MultipartForm is a multipart request validated against a MultipartSchema.
+type MultipartForm struct {
+	parts map[string][]*Part
+}
+
+// Part returns the first part with the given name, or nil.
+func (form *MultipartForm) Part(name string) *Part {
+	if parts := form.parts[name]; len(parts) > 0 {
+		return parts[0]
+	}
+	return nil
+}
+
+// Parts returns every part with the given name in request order.
+func (form *MultipartForm) Parts(name string) []*Part {
+	return form.parts[name]
+}
+
+// JSON decodes the first part with the given name into destination.
+func (form *MultipartForm) JSON(name string, destination any) error {
+	part := form.Part(name)
+	if part == nil {
+		return &MultipartError{Part: name, Message: "part is missing"}
+	}
+	if err := json.Unmarshal(part.Data, destination); err != nil {
+		return &MultipartError{Part: name, Message: "invalid JSON: " + err.Error()}
+	}
+	return nil
+}
+
+// MultipartError describes why a multipart request does not satisfy its
+// schema.
+type MultipartError struct {
+	Part    string
+	Message string
+	Status  int
+}
+
+// StatusCode returns the HTTP status code appropriate for the error.
+func (err *MultipartError) StatusCode() int {
+	if err.Status == 0 {
+		return http.StatusBadRequest
+	}
+	return err.Status
+}
+
+func (err *MultipartError) Error() string {
+	if err.Part == "" {
+		return "routerx: multipart: " + err.Message
+	}
+	return fmt.Sprintf("routerx: multipart part %q: %s", err.Part, err.Message)
+}
+
+// ParseMultipart streams a multipart/form-data or multipart/mixed request and
+// validates every part against schema while reading it, so oversized or
+// unexpected parts are rejected without buffering them. Errors are of type
+// *MultipartError, whose StatusCode is 413 for size violations, 415 for
+// content type violations, and 400 otherwise.
+//
+// Example:
+//
+//	schema := routerx.MultipartSchema{MaxBytes: 200 << 20, Parts: []routerx.PartSpec{
+//	    {Name: "metadata", Required: true, MaxBytes: 64 << 10, ContentTypes: []string{"application/json"}},
+//	    {Name: "file", Required: true, Multiple: true, MaxCount: 4, MaxBytes: 50 << 20, ContentTypes: []string{"application/pdf", "image/*"}},
+//	}}
+//	form, err := routerx.ParseMultipart(request, schema)
+//	if err != nil { ... }
+//	var metadata DocumentMetadata
+//	err = form.JSON("metadata", &metadata)
+//	for _, file := range form.Parts("file") { ... }
+func ParseMultipart(request *http.Request, schema MultipartSchema) (*MultipartForm, error) {
+	reader, err := request.MultipartReader()
+	if err != nil {
+		// MultipartReader only accepts multipart/form-data and
+		// multipart/mixed bodies.
+		return nil, &MultipartError{Message: err.Error(), Status: http.StatusUnsupportedMediaType}
+	}
+
+	specs := make(map[string]PartSpec, len(schema.Parts))
+	for _, spec := range schema.Parts {
+		specs[spec.Name] = spec
+	}
+	totalBytes := schema.MaxBytes
+	if totalBytes <= 0 {
+		totalBytes = 32 << 20
+	}
+	remaining := totalBytes
+	form := &MultipartForm{parts: make(map[string][]*Part)}
+	for {
+		multipartPart, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, &MultipartError{Message: err.Error(), Status: http.StatusBadRequest}
+		}
+		name := multipartPart.FormName()
+		spec, known := specs[name]
+		if !known {
+			multipartPart.Close()
+			if schema.AllowUnknown {
+				continue
+			}
+			return nil, &MultipartError{Part: name, Message: "unexpected part", Status: http.StatusBadRequest}
+		}
+		if !spec.Multiple && len(form.parts[name]) > 0 {
+			return nil, &MultipartError{Part: name, Message: "part must appear only once", Status: http.StatusBadRequest}
+		}
+		maxCount := spec.MaxCount
+		if maxCount <= 0 {
+			maxCount = 10
+		}
+		if len(form.parts[name]) >= maxCount {
+			return nil, &MultipartError{Part: name, Message: fmt.Sprintf("part must appear at most %d times", maxCount), Status: http.StatusRequestEntityTooLarge}
+		}
+
+		contentType := multipartPart.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "text/plain"
+		}
+		if !acceptsContentType(spec.ContentTypes, contentType) {
+			return nil, &MultipartError{Part: name, Message: "content type " + contentType + " is not allowed", Status: http.StatusUnsupportedMediaType}
+		}
+
+		maxBytes := spec.MaxBytes
+		if maxBytes <= 0 {
+			maxBytes = 10 << 20
+		}
+		data, err := io.ReadAll(io.LimitReader(multipartPart, min(maxBytes, remaining)+1))
+		multipartPart.Close()
+		if err != nil {
+			return nil, &MultipartError{Part: name, Message: err.Error(), Status: http.StatusBadRequest}
+		}
+		if int64(len(data)) > maxBytes {
+			return nil, &MultipartError{Part: name, Message: fmt.Sprintf("part exceeds %d bytes", maxBytes), Status: http.StatusRequestEntityTooLarge}
+		}
+		if int64(len(data)) > remaining {
+			return nil, &MultipartError{Message: fmt.Sprintf("parts exceed %d bytes in total", totalBytes), Status: http.StatusRequestEntityTooLarge}
+		}
+		remaining -= int64(len(data))
+		form.parts[name] = append(form.parts[name], &Part{
+			Name:        name,
+			FileName:    multipartPart.FileName(),
+			ContentType: contentType,
+			Data:        data,
+		})
+	}
+
+	for _, spec := range schema.Parts {
+		if spec.Required && len(form.parts[spec.Name]) == 0 {
+			return nil, &MultipartError{Part: spec.Name, Message: "part is missing", Status: http.StatusBadRequest}
+		}
+	}
+	return form, nil
+}
+
+// acceptsContentType reports whether contentType matches one of the accepted
+// media types, which may be wildcards such as "image/*".
+func acceptsContentType(accepted []string, contentType string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(accepted, func(pattern string) bool {
+		matched, _ := path.Match(pattern, mediaType)
+		return matched
+	})
+}
+
+// MultipartWriter builds a multipart/form-data body of the shape
+// ParseMultipart reads, e.g. for clients and tests of upload endpoints.
+//
+// Example:
+//
+//	var body bytes.Buffer
+//	writer := routerx.NewMultipartWriter(&body)
+//	err := writer.JSON("metadata", DocumentMetadata{Title: "Invoice"})
+//	err = writer.File("file", "invoice.pdf", "application/pdf", pdf)
+//	err = writer.Close()
+//	request, _ := http.NewRequest(http.MethodPost, url, &body)
+//	request.Header.Set("Content-Type", writer.ContentType())
+type MultipartWriter struct {
+	writer *multipart.Writer
+}
+
+// NewMultipartWriter returns a MultipartWriter writing the body to output.
+func NewMultipartWriter(output io.Writer) *MultipartWriter {
+	return &MultipartWriter{writer: multipart.NewWriter(output)}
+}
+
+// ContentType returns the Content-Type of the body, including its boundary.
+func (writer *MultipartWriter) ContentType() string {
+	return writer.writer.FormDataContentType()
+}
+
+// JSON writes value encoded as an application/json part.
+func (writer *MultipartWriter) JSON(name string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return writer.Part(name, "", "application/json", bytes.NewReader(data))
+}
+
+// File writes the contents of data as a part with a file name.
+func (writer *MultipartWriter) File(name, fileName, contentType string, data io.Reader) error {
+	return writer.Part(name, fileName, contentType, data)
+}
+
+// Part writes the contents of data as a part of the given content type,
+// with a file name unless fileName is empty.
+func (writer *MultipartWriter) Part(name, fileName, contentType string, data io.Reader) error {
+	disposition := `form-data; name="` + multipartEscaper.Replace(name) + `"`
+	if fileName != "" {
+		disposition += `; filename="` + multipartEscaper.Replace(fileName) + `"`
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", disposition)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	part, err := writer.writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, data)
+	return err
+}
+
+// Close writes the closing boundary. It does not close the output.
+func (writer *MultipartWriter) Close() error {
+	return writer.writer.Close()
+}
+
+var multipartEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
@@ -0,0 +1,481 @@
+package routerx
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouteDoc documents the routes registered on a PathBuilder for the OpenAPI
+// document generated by Router.OpenAPI.
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+
+	// OperationID defaults to the lowercase method followed by the name
+	// given with PathBuilder.Name, e.g. "getUser".
+	OperationID string
+
+	// Request is an example of the request type, typically its zero value.
+	// Fields tagged path or query become parameters, fields tagged form a
+	// form body, and the remaining fields a JSON body, matching Bind.
+	Request any
+
+	// Responses declares response types by status code like
+	// PathBuilder.Returns, so they are also checked by CheckResponses.
+	Responses map[int]any
+
+	Deprecated bool
+}
+
+// Doc documents the methods registered on the builder after calling Doc.
+// Doc returns the builder to support chaining.
+//
+// Example:
+//
+//	router.Path("/users/{id:[0-9]+}").
+//	    Name("User").
+//	    Doc(routerx.RouteDoc{
+//	        Summary:   "Get a user",
+//	        Tags:      []string{"users"},
+//	        Request:   GetUserRequest{},
+//	        Responses: map[int]any{http.StatusOK: User{}, http.StatusNotFound: nil},
+//	    }).
+//	    GetE(routerx.Handle(getUser))
+func (builder *PathBuilder) Doc(doc RouteDoc) *PathBuilder {
+	builder.doc = &doc
+	for status, value := range doc.Responses {
+		builder.Returns(status, value)
+	}
+	return builder
+}
+
+// OpenAPIInfo describes the API in the OpenAPI document.
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+
+	// Servers lists the base URLs of the API.
+	Servers []string
+
+	// Examples, when set, adds the captured fixtures of every route as
+	// request and response examples.
+	Examples *FixtureRecorder
+}
+
+// OpenAPI returns an OpenAPI 3.1 document describing the registered routes,
+// ready to be encoded as JSON. Paths and path parameters come from the route
+// table, including regular expression constraints as patterns; summaries,
+// request types, and response types come from PathBuilder.Doc and
+// PathBuilder.Returns; security requirements come from PathBuilder.Security.
+// Named struct types are emitted as reusable component schemas, and validate
+// tags become schema constraints. Routes matching every method, such as
+// mounted handlers, and CORS preflight routes are omitted.
+//
+// Example:
+//
+//	document := router.OpenAPI(routerx.OpenAPIInfo{Title: "Users API", Version: "1.0.0"})
+//	router.Get("/openapi.json", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    routerx.JSON(responseWriter, http.StatusOK, document)
+//	})
+func (router *Router) OpenAPI(info OpenAPIInfo) map[string]any {
+	generator := &schemaGenerator{components: make(map[string]any), names: make(map[reflect.Type]string)}
+	paths := make(map[string]any)
+	securitySchemes := make(map[string]any)
+	for _, registered := range router.routes {
+		if registered.preflight || registered.undocumented || registered.method == "" {
+			continue
+		}
+		path := openAPIPath(registered.path)
+		item, _ := paths[path].(map[string]any)
+		if item == nil {
+			item = make(map[string]any)
+			paths[path] = item
+		}
+		operation := generator.operation(registered)
+		if info.Examples != nil {
+			openAPIExamples(operation, info.Examples.route(registered.pattern))
+		}
+		if len(registered.security) > 0 {
+			operation["security"] = openAPISecurity(registered.security, securitySchemes)
+		}
+		item[strings.ToLower(registered.method)] = operation
+	}
+
+	infoObject := map[string]any{"title": info.Title, "version": info.Version}
+	if info.Description != "" {
+		infoObject["description"] = info.Description
+	}
+	document := map[string]any{
+		"openapi": "3.1.0",
+		"info":    infoObject,
+		"paths":   paths,
+	}
+	if len(info.Servers) > 0 {
+		servers := make([]any, 0, len(info.Servers))
+		for _, server := range info.Servers {
+			servers = append(servers, map[string]any{"url": server})
+		}
+		document["servers"] = servers
+	}
+	components := make(map[string]any)
+	if len(generator.components) > 0 {
+		components["schemas"] = generator.components
+	}
+	if len(securitySchemes) > 0 {
+		components["securitySchemes"] = securitySchemes
+	}
+	if len(components) > 0 {
+		document["components"] = components
+	}
+	return document
+}
+
+// OpenAPIHandler returns a handler serving the OpenAPI document as JSON. The
+// document is generated on each request, so it includes routes registered
+// after the call.
+//
+// Example:
+//
+//	router.Get("/openapi.json", router.OpenAPIHandler(routerx.OpenAPIInfo{Title: "Users API", Version: "1.0.0"}))
+func (router *Router) OpenAPIHandler(info OpenAPIInfo) http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusOK, router.OpenAPI(info))
+	}
+}
+
+// openAPIPath converts a ServeMux path to an OpenAPI path template.
+func openAPIPath(muxPath string) string {
+	path := strings.ReplaceAll(muxPath, "{$}", "")
+	return strings.ReplaceAll(path, "...}", "}")
+}
+
+// operation returns the OpenAPI operation object of a route.
+func (generator *schemaGenerator) operation(registered *route) map[string]any {
+	operation := make(map[string]any)
+	doc := registered.doc
+	if doc == nil {
+		doc = &RouteDoc{}
+	}
+	if doc.Summary != "" {
+		operation["summary"] = doc.Summary
+	}
+	if doc.Description != "" {
+		operation["description"] = doc.Description
+	}
+	tags := slices.Clone(doc.Tags)
+	for _, tag := range registered.tags {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > 0 {
+		operation["tags"] = tags
+	}
+	if doc.Deprecated {
+		operation["deprecated"] = true
+	}
+	switch {
+	case doc.OperationID != "":
+		operation["operationId"] = doc.OperationID
+	case registered.name != "":
+		operation["operationId"] = strings.ToLower(registered.method) + registered.name
+	}
+
+	var requestType reflect.Type
+	if doc.Request != nil {
+		requestType = reflect.TypeOf(doc.Request)
+		for requestType.Kind() == reflect.Pointer {
+			requestType = requestType.Elem()
+		}
+	}
+	if parameters := generator.parameters(registered, requestType); len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+	if body := generator.requestBody(requestType); body != nil {
+		operation["requestBody"] = body
+	}
+
+	if len(registered.responses) > 0 {
+		responses := make(map[string]any)
+		for status, responseType := range registered.responses {
+			response := map[string]any{"description": http.StatusText(status)}
+			if responseType != nil {
+				response["content"] = map[string]any{
+					"application/json": map[string]any{"schema": generator.schema(responseType)},
+				}
+			}
+			responses[strconv.Itoa(status)] = response
+		}
+		operation["responses"] = responses
+	}
+	return operation
+}
+
+// parameters returns the path parameters of a route and the path and query
+// parameters declared by the request type.
+func (generator *schemaGenerator) parameters(registered *route, requestType reflect.Type) []any {
+	fields := make(map[string]reflect.StructField)
+	var queryFields []reflect.StructField
+	if requestType != nil && requestType.Kind() == reflect.Struct {
+		for _, field := range reflect.VisibleFields(requestType) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+			if name := field.Tag.Get("path"); name != "" {
+				fields[name] = field
+			} else if field.Tag.Get("query") != "" {
+				queryFields = append(queryFields, field)
+			}
+		}
+	}
+
+	var parameters []any
+	for _, name := range registered.wildcards {
+		schema := map[string]any{"type": "string"}
+		if field, found := fields[name]; found {
+			schema = generator.fieldSchema(field)
+		}
+		if expression, found := registered.constraints[name]; found {
+			schema["pattern"] = expression.String()
+		}
+		parameters = append(parameters, map[string]any{"name": name, "in": "path", "required": true, "schema": schema})
+	}
+	for _, field := range queryFields {
+		parameter := map[string]any{"name": field.Tag.Get("query"), "in": "query", "schema": generator.fieldSchema(field)}
+		if hasRule(field, "required") {
+			parameter["required"] = true
+		}
+		if field.Type.Kind() == reflect.Slice {
+			parameter["explode"] = true
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
+
+// requestBody returns the request body object for the request type, or nil
+// when it has no body fields.
+func (generator *schemaGenerator) requestBody(requestType reflect.Type) map[string]any {
+	if requestType == nil {
+		return nil
+	}
+	if requestType.Kind() != reflect.Struct {
+		return map[string]any{"content": map[string]any{"application/json": map[string]any{"schema": generator.schema(requestType)}}}
+	}
+
+	form := map[string]any{"type": "object"}
+	formProperties := make(map[string]any)
+	var formRequired []string
+	mediaType := "application/x-www-form-urlencoded"
+	for _, field := range reflect.VisibleFields(requestType) {
+		name := field.Tag.Get("form")
+		if !field.IsExported() || name == "" {
+			continue
+		}
+		if field.Type == fileHeaderType || field.Type == fileHeaderSliceType {
+			mediaType = "multipart/form-data"
+		}
+		formProperties[name] = generator.fieldSchema(field)
+		if hasRule(field, "required") {
+			formRequired = append(formRequired, name)
+		}
+	}
+	if len(formProperties) > 0 {
+		form["properties"] = formProperties
+		if len(formRequired) > 0 {
+			form["required"] = formRequired
+		}
+		return map[string]any{"content": map[string]any{mediaType: map[string]any{"schema": form}}}
+	}
+	if len(generator.properties(requestType)) == 0 {
+		return nil
+	}
+	return map[string]any{"content": map[string]any{"application/json": map[string]any{"schema": generator.schema(requestType)}}}
+}
+
+// schemaGenerator converts Go types to JSON schemas, collecting named struct
+// types as component schemas.
+type schemaGenerator struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	componentNameFilter = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// schema returns the JSON schema of goType.
+func (generator *schemaGenerator) schema(goType reflect.Type) map[string]any {
+	for goType.Kind() == reflect.Pointer {
+		goType = goType.Elem()
+	}
+	switch {
+	case goType == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case goType == fileHeaderType.Elem():
+		return map[string]any{"type": "string", "format": "binary"}
+	case goType.Implements(jsonMarshalerType) || reflect.PointerTo(goType).Implements(jsonMarshalerType):
+		return map[string]any{}
+	case goType.Implements(textMarshalerType) || reflect.PointerTo(goType).Implements(textMarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch goType.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		if goType.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": generator.schema(goType.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": generator.schema(goType.Elem())}
+	case reflect.Struct:
+		if goType.Name() == "" {
+			return generator.objectSchema(goType)
+		}
+		name, found := generator.names[goType]
+		if !found {
+			name = componentNameFilter.ReplaceAllString(goType.Name(), "_")
+			for index := 2; generator.components[name] != nil; index++ {
+				name = componentNameFilter.ReplaceAllString(goType.Name(), "_") + strconv.Itoa(index)
+			}
+			generator.names[goType] = name
+			generator.components[name] = map[string]any{}
+			generator.components[name] = generator.objectSchema(goType)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+// objectSchema returns the object schema of a struct type.
+func (generator *schemaGenerator) objectSchema(structType reflect.Type) map[string]any {
+	schema := map[string]any{"type": "object"}
+	properties := generator.properties(structType)
+	if len(properties) > 0 {
+		values := make(map[string]any, len(properties))
+		var required []string
+		for _, property := range properties {
+			values[property.name] = generator.fieldSchema(property.field)
+			if hasRule(property.field, "required") {
+				required = append(required, property.name)
+			}
+		}
+		schema["properties"] = values
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+	return schema
+}
+
+type schemaProperty struct {
+	name  string
+	field reflect.StructField
+}
+
+// properties returns the JSON properties of a struct type. Fields bound from
+// the path, query, or form are not part of the JSON body and are skipped.
+func (generator *schemaGenerator) properties(structType reflect.Type) []schemaProperty {
+	var properties []schemaProperty
+	for _, field := range reflect.VisibleFields(structType) {
+		if !field.IsExported() || field.Anonymous && field.Tag.Get("json") == "" {
+			continue
+		}
+		if field.Tag.Get("path") != "" || field.Tag.Get("query") != "" || field.Tag.Get("form") != "" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties = append(properties, schemaProperty{name: name, field: field})
+	}
+	return properties
+}
+
+// fieldSchema returns the schema of a struct field, including the
+// constraints of its validate tag.
+func (generator *schemaGenerator) fieldSchema(field reflect.StructField) map[string]any {
+	schema := generator.schema(field.Type)
+	rules := field.Tag.Get("validate")
+	if rules == "" || schema["$ref"] != nil {
+		return schema
+	}
+	constrained := make(map[string]any, len(schema)+2)
+	for key, value := range schema {
+		constrained[key] = value
+	}
+	schemaType, _ := schema["type"].(string)
+	for _, rule := range strings.Split(rules, ",") {
+		name, argument, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		limit, err := strconv.ParseFloat(argument, 64)
+		switch {
+		case name == "email":
+			constrained["format"] = "email"
+		case name == "oneof":
+			var options []any
+			for _, option := range strings.Fields(argument) {
+				if number, err := strconv.ParseFloat(option, 64); err == nil && (schemaType == "integer" || schemaType == "number") {
+					options = append(options, number)
+				} else {
+					options = append(options, option)
+				}
+			}
+			constrained["enum"] = options
+		case err != nil:
+		case schemaType == "string":
+			setLimits(constrained, name, limit, "minLength", "maxLength")
+		case schemaType == "array":
+			setLimits(constrained, name, limit, "minItems", "maxItems")
+		case schemaType == "object":
+			setLimits(constrained, name, limit, "minProperties", "maxProperties")
+		case schemaType == "integer" || schemaType == "number":
+			setLimits(constrained, name, limit, "minimum", "maximum")
+		}
+	}
+	return constrained
+}
+
+// setLimits maps a min, max, or len rule to the given schema keywords.
+func setLimits(schema map[string]any, rule string, limit float64, minimum string, maximum string) {
+	if rule == "min" || rule == "len" {
+		schema[minimum] = limit
+	}
+	if rule == "max" || rule == "len" {
+		schema[maximum] = limit
+	}
+}
+
+// hasRule reports whether the validate tag of field lists rule.
+func hasRule(field reflect.StructField, rule string) bool {
+	return slices.ContainsFunc(strings.Split(field.Tag.Get("validate"), ","), func(candidate string) bool {
+		return strings.TrimSpace(candidate) == rule
+	})
+}
@@ -0,0 +1,232 @@
+package routerx
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Option configures a Router created by New.
+type Option func(router *Router)
+
+// routerSetup collects the middlewares configured by options while New runs.
+// They are installed once all options were applied, in a fixed order, so
+// that a later option replaces the middleware of an earlier one, e.g. one
+// set by a Profile, instead of adding a second one.
+type routerSetup struct {
+	requestID     Middleware
+	logger        Middleware
+	recovery      Middleware
+	secureHeaders Middleware
+	maxBody       Middleware
+	middlewares   []Middleware
+}
+
+// install adds the collected middlewares to router, outermost first: the
+// request ID, so that every log record carries it, the logger, so that it
+// records the 500 answered for a panic, the recovery, the secure headers,
+// the body limit, and the middlewares of WithMiddleware.
+func (setup *routerSetup) install(router *Router) {
+	for _, middleware := range []Middleware{setup.requestID, setup.logger, setup.recovery, setup.secureHeaders, setup.maxBody} {
+		if middleware != nil {
+			router.middlewares = append(router.middlewares, middleware)
+		}
+	}
+	router.Use(setup.middlewares...)
+}
+
+// TrailingSlashPolicy decides how a Router answers a request whose path has
+// a trailing slash that no route has, such as "/users/" when "/users" is
+// registered.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashStrict answers such requests with 404 Not Found. It is
+	// the default.
+	TrailingSlashStrict TrailingSlashPolicy = iota
+
+	// TrailingSlashRedirect redirects them to the path without the slash
+	// with 308 Permanent Redirect, which keeps the method and body.
+	TrailingSlashRedirect
+
+	// TrailingSlashIgnore serves them by the route of the path without the
+	// slash.
+	TrailingSlashIgnore
+)
+
+// WithErrorHandler sets the error handler, see Router.ErrorHandler.
+func WithErrorHandler(handler ErrorHandlerFunc) Option {
+	return func(router *Router) {
+		router.ErrorHandler(handler)
+	}
+}
+
+// WithNotFound sets the handler of requests matching no route, see
+// Router.NotFound.
+func WithNotFound(handler http.HandlerFunc) Option {
+	return func(router *Router) {
+		router.NotFound(handler)
+	}
+}
+
+// WithMethodNotAllowed sets the handler of requests whose method matches no
+// route of their path, see Router.MethodNotAllowed.
+func WithMethodNotAllowed(handler http.HandlerFunc) Option {
+	return func(router *Router) {
+		router.MethodNotAllowed(handler)
+	}
+}
+
+// WithStrict turns registration warnings into panics, see Router.Strict.
+func WithStrict() Option {
+	return func(router *Router) {
+		router.Strict()
+	}
+}
+
+// WithMiddleware adds middlewares for every route, see Router.Use. They run
+// inside the middlewares configured by the other options.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(router *Router) {
+		router.setup.middlewares = append(router.setup.middlewares, middlewares...)
+	}
+}
+
+// WithLogger logs every request to handler, see Logger. A nil handler turns
+// logging off.
+func WithLogger(handler slog.Handler, configs ...LoggerConfig) Option {
+	return func(router *Router) {
+		router.setup.logger = nil
+		if handler != nil {
+			router.setup.logger = Logger(handler, configs...)
+		}
+	}
+}
+
+// WithMaxBody limits request bodies to maxBytes, see BodyLimit. Routes may
+// raise the limit with their own BodyLimit. A non-positive maxBytes removes
+// the limit.
+func WithMaxBody(maxBytes int64) Option {
+	return func(router *Router) {
+		router.setup.maxBody = nil
+		if maxBytes > 0 {
+			router.setup.maxBody = BodyLimit(maxBytes)
+		}
+	}
+}
+
+// WithRecovery recovers panics in handlers, see Recovery.
+func WithRecovery(configs ...RecoveryConfig) Option {
+	return func(router *Router) {
+		router.setup.recovery = Recovery(configs...)
+	}
+}
+
+// WithoutRecovery removes the Recovery middleware configured by an earlier
+// option.
+func WithoutRecovery() Option {
+	return func(router *Router) {
+		router.setup.recovery = nil
+	}
+}
+
+// WithSecureHeaders sets the hardening response headers, see SecureHeaders.
+func WithSecureHeaders(configs ...SecureHeadersConfig) Option {
+	return func(router *Router) {
+		router.setup.secureHeaders = SecureHeaders(configs...)
+	}
+}
+
+// WithoutSecureHeaders removes the SecureHeaders middleware configured by an
+// earlier option.
+func WithoutSecureHeaders() Option {
+	return func(router *Router) {
+		router.setup.secureHeaders = nil
+	}
+}
+
+// WithRequestID gives every request an ID, see RequestID.
+func WithRequestID(configs ...RequestIDConfig) Option {
+	return func(router *Router) {
+		router.setup.requestID = RequestID(configs...)
+	}
+}
+
+// WithTrailingSlashPolicy sets how requests with an unexpected trailing slash
+// are answered. Defaults to TrailingSlashStrict.
+func WithTrailingSlashPolicy(policy TrailingSlashPolicy) Option {
+	return func(router *Router) {
+		router.trailingSlash = policy
+	}
+}
+
+// WithMatcher names a regular expression so that route paths can constrain
+// wildcards with the name instead of repeating the expression.
+//
+// Example:
+//
+//	router := routerx.New(routerx.WithMatcher("uuid", "[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}"))
+//	router.Get("/orders/{id:uuid}", getOrder)
+func WithMatcher(name string, expression string) Option {
+	regexp.MustCompile(expression)
+	return func(router *Router) {
+		if router.matchers == nil {
+			router.matchers = make(map[string]string)
+		}
+		router.matchers[name] = expression
+	}
+}
+
+// expandMatchers replaces the matcher names used as constraints in path with
+// their expressions.
+func (router *Router) expandMatchers(path string) string {
+	if len(router.matchers) == 0 || !strings.Contains(path, "{") {
+		return path
+	}
+	var builder strings.Builder
+	for index := 0; index < len(path); index++ {
+		end := -1
+		if path[index] == '{' {
+			end = matchingBrace(path, index)
+		}
+		if end < 0 {
+			builder.WriteByte(path[index])
+			continue
+		}
+		token := path[index+1 : end]
+		if name, matcher, constrained := strings.Cut(token, ":"); constrained {
+			if expression, found := router.matchers[matcher]; found {
+				token = name + ":" + expression
+			}
+		}
+		builder.WriteString("{" + token + "}")
+		index = end
+	}
+	return builder.String()
+}
+
+// trailingSlashRequest applies the trailing slash policy: it reports whether
+// the request was redirected, or returns the request to serve instead.
+func (router *Router) trailingSlashRequest(responseWriter http.ResponseWriter, request *http.Request) (*http.Request, bool) {
+	path := request.URL.Path
+	if len(path) < 2 || !strings.HasSuffix(path, "/") {
+		return request, false
+	}
+	if _, pattern := router.mux.Handler(request); pattern != "" {
+		return request, false
+	}
+	probe := request.Clone(request.Context())
+	probe.URL.Path = strings.TrimRight(path, "/")
+	probe.URL.RawPath = ""
+	if _, pattern := router.mux.Handler(probe); pattern == "" {
+		return request, false
+	}
+	if router.trailingSlash == TrailingSlashRedirect {
+		target := *probe.URL
+		target.Scheme, target.Host = "", ""
+		http.Redirect(responseWriter, request, target.String(), http.StatusPermanentRedirect)
+		return nil, true
+	}
+	return probe, false
+}
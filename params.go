@@ -0,0 +1,61 @@
+package routerx
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Param returns the value of the named path parameter of the matched route,
+// or "" when the route declares no such parameter. Trailing wildcards such
+// as {filepath...} yield the remaining path without a leading slash.
+//
+// Example:
+//
+//	router.Get("/files/{filepath...}", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    name := routerx.Param(request, "filepath") // "docs/readme.md" for /files/docs/readme.md
+//	    ...
+//	})
+func Param(request *http.Request, name string) string {
+	return request.PathValue(name)
+}
+
+// Params returns all path parameters of the matched route keyed by name. It
+// returns nil for routes without parameters.
+func Params(request *http.Request) map[string]string {
+	return pathParams(request)
+}
+
+// patternWildcards returns the wildcard names declared in a ServeMux pattern
+// such as "GET /users/{id}/files/{path...}". The special {$} marker is skipped.
+func patternWildcards(pattern string) []string {
+	var names []string
+	for {
+		start := strings.IndexByte(pattern, '{')
+		if start < 0 {
+			return names
+		}
+		end := strings.IndexByte(pattern[start:], '}')
+		if end < 0 {
+			return names
+		}
+		name := strings.TrimSuffix(pattern[start+1:start+end], "...")
+		if name != "$" && name != "" {
+			names = append(names, name)
+		}
+		pattern = pattern[start+end+1:]
+	}
+}
+
+// pathParams collects the values of all wildcards in the matched pattern of
+// the request.
+func pathParams(request *http.Request) map[string]string {
+	names := patternWildcards(request.Pattern)
+	if len(names) == 0 {
+		return nil
+	}
+	params := make(map[string]string, len(names))
+	for _, name := range names {
+		params[name] = request.PathValue(name)
+	}
+	return params
+}
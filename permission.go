@@ -0,0 +1,96 @@
+package routerx
+
+import (
+	"net/http"
+	"strings"
+)
+
+// PermissionConfig configures the RequirePermission middleware.
+type PermissionConfig struct {
+	// MetaKey is the route metadata key holding the required permission.
+	// Defaults to "permission".
+	MetaKey string
+}
+
+// RequirePermission returns a Middleware that checks the permission a route
+// requires, as set with PathBuilder.Meta, against the principal attached with
+// SetPrincipal. resolve reports whether a principal has been granted a
+// permission, e.g. with RolePermissions. Routes without a permission are
+// served as usual. Anonymous requests to the other routes are answered with
+// 401 Unauthorized, and principals lacking the permission with 403 Forbidden,
+// through the router's error handler, see HandleError.
+//
+// The route is only known once it matched, so use the middleware after the
+// authenticating middleware, on the router or on a group.
+//
+// Example:
+//
+//	router.Use(
+//	    routerx.JWT(routerx.JWTConfig{Secret: secret}),
+//	    routerx.RequirePermission(routerx.RolePermissions(map[string][]string{
+//	        "reader": {"users:read"},
+//	        "admin":  {"users:*"},
+//	    })),
+//	)
+//	router.Path("/users").Meta("permission", "users:read").Get(listUsers)
+//	router.Path("/users").Meta("permission", "users:write").Post(createUser)
+func RequirePermission(resolve func(principal *Principal, permission string) bool, configs ...PermissionConfig) Middleware {
+	var config PermissionConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.MetaKey == "" {
+		config.MetaKey = "permission"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			info, _ := GetRouteInfo(request)
+			permission := info.Meta[config.MetaKey]
+			if permission == "" {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			principal := GetPrincipal(request)
+			switch {
+			case principal == nil:
+				HandleError(responseWriter, request, NewHTTPError(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized)))
+			case !resolve(principal, permission):
+				HandleError(responseWriter, request, NewHTTPError(http.StatusForbidden, "missing permission "+permission))
+			default:
+				next.ServeHTTP(responseWriter, request)
+			}
+		})
+	}
+}
+
+// RolePermissions returns a resolver for RequirePermission granting the
+// permissions listed for each role of the principal. The principal's scopes
+// are granted as permissions too, so that tokens issued with scopes work
+// unchanged. A permission ending in "*" grants every permission with that
+// prefix, and "*" alone grants all of them.
+func RolePermissions(roles map[string][]string) func(principal *Principal, permission string) bool {
+	return func(principal *Principal, permission string) bool {
+		for _, scope := range principal.Scopes {
+			if grantsPermission(scope, permission) {
+				return true
+			}
+		}
+		for _, role := range principal.Roles {
+			for _, granted := range roles[role] {
+				if grantsPermission(granted, permission) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// grantsPermission reports whether the granted permission, which may end in
+// a "*" wildcard, covers permission.
+func grantsPermission(granted string, permission string) bool {
+	if prefix, wildcard := strings.CutSuffix(granted, "*"); wildcard {
+		return strings.HasPrefix(permission, prefix)
+	}
+	return granted == permission
+}
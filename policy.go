@@ -0,0 +1,325 @@
+package routerx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+)
+
+// PolicyInput is the document a Policy evaluates for every request. It is
+// also the JSON "input" sent to an OPA server by OPAPolicy.
+type PolicyInput struct {
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Route     string            `json:"route"`
+	Principal *Principal        `json:"principal,omitempty"`
+	Params    map[string]string `json:"params,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// Policy decides whether a request is allowed. Implementations must be safe
+// for concurrent use. A non-nil error is treated as a failure to evaluate the
+// policy rather than as a denial.
+type Policy interface {
+	Evaluate(ctx context.Context, input PolicyInput) (bool, error)
+}
+
+// PolicyFunc adapts an ordinary function to the Policy interface.
+type PolicyFunc func(ctx context.Context, input PolicyInput) (bool, error)
+
+// Evaluate calls policyFunc(ctx, input).
+func (policyFunc PolicyFunc) Evaluate(ctx context.Context, input PolicyInput) (bool, error) {
+	return policyFunc(ctx, input)
+}
+
+// AuthorizeConfig customizes the Authorize middleware.
+type AuthorizeConfig struct {
+	// Metadata is passed to the policy for every route using the middleware,
+	// e.g. {"resource": "users"}.
+	Metadata map[string]string
+
+	// Denied writes the response for rejected requests. The default responds
+	// 401 for anonymous requests and 403 otherwise.
+	Denied http.HandlerFunc
+
+	// Failed writes the response when the policy returns an error. The
+	// default responds 500.
+	Failed func(responseWriter http.ResponseWriter, request *http.Request, err error)
+}
+
+// Authorize returns a Middleware that evaluates policy for every request and
+// only calls the next handler when the policy allows it. The policy receives
+// the matched route pattern, the principal attached with SetPrincipal, the
+// path parameters, and the configured metadata.
+//
+// Example:
+//
+//	policy := routerx.MustParseRules(`
+//	    allow GET /api/** role=reader
+//	    allow *   /api/** role=admin
+//	`)
+//	api := router.Group("/api").Use(routerx.Authorize(policy))
+func Authorize(policy Policy, config ...AuthorizeConfig) Middleware {
+	var settings AuthorizeConfig
+	if len(config) > 0 {
+		settings = config[0]
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			input := PolicyInput{
+				Method:    request.Method,
+				Path:      request.URL.Path,
+				Route:     request.Pattern,
+				Principal: GetPrincipal(request),
+				Params:    pathParams(request),
+				Metadata:  maps.Clone(settings.Metadata),
+			}
+			allowed, err := policy.Evaluate(request.Context(), input)
+			switch {
+			case err != nil && settings.Failed != nil:
+				settings.Failed(responseWriter, request, err)
+			case err != nil:
+				writeError(responseWriter, http.StatusInternalServerError, "authorization failed")
+			case allowed:
+				next.ServeHTTP(responseWriter, request)
+			case settings.Denied != nil:
+				settings.Denied(responseWriter, request)
+			case input.Principal == nil:
+				writeError(responseWriter, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+			default:
+				writeError(responseWriter, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+			}
+		})
+	}
+}
+
+// OPAPolicy evaluates requests against an Open Policy Agent server using its
+// REST data API. The PolicyInput is posted as {"input": ...} and the decision
+// is read from "result", which may either be a boolean or an object with a
+// boolean "allow" field.
+//
+// Example:
+//
+//	policy := &routerx.OPAPolicy{URL: "http://localhost:8181/v1/data/httpapi/authz"}
+//	router.Use(routerx.Authorize(policy))
+type OPAPolicy struct {
+	// URL is the full data API URL of the rule or package to evaluate.
+	URL string
+
+	// Client is used to reach the OPA server. http.DefaultClient is used
+	// when nil.
+	Client *http.Client
+}
+
+// Evaluate posts the input to the OPA server and interprets the result.
+func (policy *OPAPolicy) Evaluate(ctx context.Context, input PolicyInput) (bool, error) {
+	body, err := json.Marshal(map[string]any{"input": input})
+	if err != nil {
+		return false, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, policy.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := policy.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("routerx: opa returned %s", response.Status)
+	}
+
+	var decision struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&decision); err != nil {
+		return false, err
+	}
+	if len(decision.Result) == 0 {
+		// An undefined decision means the policy did not allow the request.
+		return false, nil
+	}
+	var allowed bool
+	if err := json.Unmarshal(decision.Result, &allowed); err == nil {
+		return allowed, nil
+	}
+	var document struct {
+		Allow bool `json:"allow"`
+	}
+	if err := json.Unmarshal(decision.Result, &document); err != nil {
+		return false, fmt.Errorf("routerx: unexpected opa result: %s", decision.Result)
+	}
+	return document.Allow, nil
+}
+
+// RulePolicy is a Policy built from a small line-oriented rule language.
+// Rules are evaluated top to bottom and the first matching rule decides;
+// requests matching no rule are denied.
+//
+// Every rule has the form
+//
+//	allow|deny METHODS PATH [CONDITION...]
+//
+// where METHODS is "*" or a comma-separated list such as "GET,HEAD", and PATH
+// is matched segment by segment against the request path: "*" or a {param}
+// segment matches exactly one segment and a trailing "**" matches the rest of
+// the path. Conditions are key=value pairs that must all hold:
+//
+//	role=admin        the principal has the role
+//	scope=users:read  the principal has the scope
+//	id=42             the principal ID equals the value
+//	attr.tenant=acme  the principal attribute equals the value
+//	param.id=42       the path parameter equals the value
+//	meta.tier=gold    the metadata value equals the value
+//
+// A value of "*" only requires the key to be present. Lines starting with "#"
+// are comments.
+type RulePolicy struct {
+	rules []policyRule
+}
+
+type policyRule struct {
+	allow      bool
+	methods    []string
+	segments   []string
+	conditions [][2]string
+}
+
+// ParseRules parses the rule language described on RulePolicy.
+func ParseRules(source string) (*RulePolicy, error) {
+	policy := &RulePolicy{}
+	scanner := bufio.NewScanner(strings.NewReader(source))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("routerx: rule %d: expected \"allow|deny METHODS PATH\"", lineNumber)
+		}
+		rule := policyRule{}
+		switch fields[0] {
+		case "allow":
+			rule.allow = true
+		case "deny":
+		default:
+			return nil, fmt.Errorf("routerx: rule %d: unknown effect %q", lineNumber, fields[0])
+		}
+		if fields[1] != "*" {
+			rule.methods = strings.Split(strings.ToUpper(fields[1]), ",")
+		}
+		rule.segments = strings.Split(strings.Trim(fields[2], "/"), "/")
+		for _, condition := range fields[3:] {
+			key, value, found := strings.Cut(condition, "=")
+			if !found || key == "" {
+				return nil, fmt.Errorf("routerx: rule %d: malformed condition %q", lineNumber, condition)
+			}
+			rule.conditions = append(rule.conditions, [2]string{key, value})
+		}
+		policy.rules = append(policy.rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// MustParseRules is like ParseRules but panics if the source cannot be parsed.
+// It simplifies initialization of package-level policies.
+func MustParseRules(source string) *RulePolicy {
+	policy, err := ParseRules(source)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+// Evaluate returns the effect of the first rule matching the input.
+func (policy *RulePolicy) Evaluate(_ context.Context, input PolicyInput) (bool, error) {
+	for _, rule := range policy.rules {
+		if rule.matches(input) {
+			return rule.allow, nil
+		}
+	}
+	return false, nil
+}
+
+func (rule policyRule) matches(input PolicyInput) bool {
+	if rule.methods != nil && !containsFold(rule.methods, input.Method) {
+		return false
+	}
+	if !matchSegments(rule.segments, strings.Split(strings.Trim(input.Path, "/"), "/")) {
+		return false
+	}
+	for _, condition := range rule.conditions {
+		if !conditionHolds(condition[0], condition[1], input) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchSegments(pattern []string, path []string) bool {
+	for index, segment := range pattern {
+		if segment == "**" {
+			return true
+		}
+		if index >= len(path) {
+			return false
+		}
+		if segment == "*" || strings.HasPrefix(segment, "{") {
+			continue
+		}
+		if segment != path[index] {
+			return false
+		}
+	}
+	return len(pattern) == len(path)
+}
+
+func conditionHolds(key string, value string, input PolicyInput) bool {
+	principal := input.Principal
+	switch {
+	case key == "role":
+		return principal != nil && (value == "*" && len(principal.Roles) > 0 || principal.HasRole(value))
+	case key == "scope":
+		return principal != nil && (value == "*" && len(principal.Scopes) > 0 || principal.HasScope(value))
+	case key == "id":
+		return principal != nil && (value == "*" || principal.ID == value)
+	case strings.HasPrefix(key, "attr."):
+		if principal == nil {
+			return false
+		}
+		actual, found := principal.Attributes[strings.TrimPrefix(key, "attr.")]
+		return found && (value == "*" || actual == value)
+	case strings.HasPrefix(key, "param."):
+		actual, found := input.Params[strings.TrimPrefix(key, "param.")]
+		return found && (value == "*" || actual == value)
+	case strings.HasPrefix(key, "meta."):
+		actual, found := input.Metadata[strings.TrimPrefix(key, "meta.")]
+		return found && (value == "*" || actual == value)
+	}
+	return false
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package routerx
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// Principal describes the authenticated caller of a request. Authentication
+// middlewares attach it to the request with SetPrincipal so that later
+// middlewares, policies, and handlers can read it with GetPrincipal.
+type Principal struct {
+	ID string `json:"id"`
+
+	// Scheme records how the principal was authenticated, e.g.
+	// SchemeBearer, so that a route documented for one scheme is not
+	// satisfied by another. Custom authentication middlewares set their own.
+	Scheme string `json:"scheme,omitempty"`
+
+	Roles      []string          `json:"roles,omitempty"`
+	Scopes     []string          `json:"scopes,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// The schemes set by the built-in authentication middlewares.
+const (
+	SchemeBearer = "bearer"
+	SchemeBasic  = "basic"
+	SchemeAPIKey = "apiKey"
+)
+
+// HasRole reports whether the principal has been granted the given role.
+func (principal *Principal) HasRole(role string) bool {
+	return principal != nil && slices.Contains(principal.Roles, role)
+}
+
+// HasScope reports whether the principal has been granted the given scope.
+func (principal *Principal) HasScope(scope string) bool {
+	return principal != nil && slices.Contains(principal.Scopes, scope)
+}
+
+type principalContextKey struct{}
+
+// SetPrincipal returns a shallow copy of the request carrying the given
+// principal in its context.
+//
+// Example:
+//
+//	request = routerx.SetPrincipal(request, &routerx.Principal{ID: "42", Roles: []string{"admin"}})
+//	next.ServeHTTP(responseWriter, request)
+func SetPrincipal(request *http.Request, principal *Principal) *http.Request {
+	return request.WithContext(context.WithValue(request.Context(), principalContextKey{}, principal))
+}
+
+// GetPrincipal returns the principal attached to the request by SetPrincipal,
+// or nil when the request is unauthenticated.
+func GetPrincipal(request *http.Request) *Principal {
+	principal, _ := request.Context().Value(principalContextKey{}).(*Principal)
+	return principal
+}
@@ -0,0 +1,161 @@
+package routerx
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"maps"
+	"net/http"
+)
+
+// Problem is an RFC 9457 (formerly RFC 7807) problem details object. It
+// implements error, so handlers can return it, or an error wrapping it, to
+// have it rendered as application/problem+json by DefaultErrorHandler and
+// ProblemErrorHandler.
+type Problem struct {
+	// Type is a URI reference identifying the problem type. It defaults to
+	// "about:blank" when rendered.
+	Type string
+
+	// Title is a short summary of the problem type. It defaults to the
+	// status text when rendered.
+	Title string
+
+	// Status is the HTTP status code.
+	Status int
+
+	// Detail explains this occurrence of the problem.
+	Detail string
+
+	// Instance is a URI reference identifying this occurrence.
+	Instance string
+
+	// Extensions holds additional members, rendered next to the standard
+	// ones.
+	Extensions map[string]any
+
+	// Err is the underlying error. It is not rendered.
+	Err error
+}
+
+// NewProblem returns a Problem with the given status code and detail.
+//
+// Example:
+//
+//	problem := routerx.NewProblem(http.StatusForbidden, "Your account has insufficient credit.")
+//	problem.Type = "https://example.com/probs/out-of-credit"
+//	problem.Extensions = map[string]any{"balance": 30}
+//	return problem
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Status: status, Detail: detail}
+}
+
+func (problem *Problem) Error() string {
+	if problem.Detail != "" {
+		return problem.Detail
+	}
+	if problem.Title != "" {
+		return problem.Title
+	}
+	return http.StatusText(problem.StatusCode())
+}
+
+// StatusCode returns the status code of the problem, 500 Internal Server
+// Error when unset or outside 100 to 599.
+func (problem *Problem) StatusCode() int {
+	if problem.Status < 100 || problem.Status > 599 {
+		return http.StatusInternalServerError
+	}
+	return problem.Status
+}
+
+// Unwrap returns the underlying error.
+func (problem *Problem) Unwrap() error {
+	return problem.Err
+}
+
+// MarshalJSON renders the standard members and the extensions as one
+// object. Extensions cannot replace standard members.
+func (problem *Problem) MarshalJSON() ([]byte, error) {
+	object := make(map[string]any, len(problem.Extensions)+5)
+	maps.Copy(object, problem.Extensions)
+	object["type"] = problem.Type
+	if problem.Type == "" {
+		object["type"] = "about:blank"
+	}
+	object["title"] = problem.Title
+	if problem.Title == "" {
+		object["title"] = http.StatusText(problem.StatusCode())
+	}
+	object["status"] = problem.StatusCode()
+	delete(object, "detail")
+	if problem.Detail != "" {
+		object["detail"] = problem.Detail
+	}
+	delete(object, "instance")
+	if problem.Instance != "" {
+		object["instance"] = problem.Instance
+	}
+	return json.Marshal(object)
+}
+
+// WriteProblem writes problem as application/problem+json with its status
+// code.
+//
+// Example:
+//
+//	routerx.WriteProblem(responseWriter, &routerx.Problem{
+//	    Type:     "https://example.com/probs/out-of-credit",
+//	    Status:   http.StatusForbidden,
+//	    Detail:   "Your current balance is 30, but that costs 50.",
+//	    Instance: request.URL.Path,
+//	})
+func WriteProblem(responseWriter http.ResponseWriter, problem *Problem) {
+	body, err := json.Marshal(problem)
+	if err != nil {
+		log.Printf("routerx: encode problem: %v", err)
+		body = []byte(`{"type":"about:blank","status":500}`)
+	}
+	responseWriter.Header().Set("Content-Type", "application/problem+json")
+	responseWriter.WriteHeader(problem.StatusCode())
+	_, _ = responseWriter.Write(append(body, '\n'))
+}
+
+// ProblemErrorHandler is an ErrorHandlerFunc for Router.ErrorHandler that
+// answers every error as application/problem+json. A returned *Problem is
+// rendered as is; other errors implementing StatusCode() int become a problem
+// with that status and the error message as detail, validation errors list
+// their field errors under "errors", and remaining errors and server errors
+// are logged and answered with a generic 500 problem.
+//
+// Example:
+//
+//	router.ErrorHandler(routerx.ProblemErrorHandler)
+func ProblemErrorHandler(responseWriter http.ResponseWriter, request *http.Request, err error) {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		if problem.StatusCode() >= 500 {
+			log.Printf("routerx: %s %s: %v", request.Method, request.URL.Path, err)
+		}
+		WriteProblem(responseWriter, problem)
+		return
+	}
+
+	status := errorStatus(err)
+	if status >= 500 {
+		log.Printf("routerx: %s %s: %v", request.Method, request.URL.Path, err)
+		WriteProblem(responseWriter, &Problem{Status: status})
+		return
+	}
+	problem = &Problem{Status: status, Detail: err.Error()}
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		problem.Detail = "validation failed"
+		problem.Extensions = map[string]any{"errors": validationError.Fields}
+	}
+	var goneError *GoneError
+	if errors.As(err, &goneError) && !goneError.DeletedAt.IsZero() {
+		problem.Extensions = map[string]any{"deleted_at": goneError.DeletedAt}
+	}
+	WriteProblem(responseWriter, problem)
+}
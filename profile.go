@@ -0,0 +1,112 @@
+package routerx
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Profile returns an Option configuring a Router with the preset bundle of
+// an environment, followed by overrides:
+//
+//   - "production": RequestID, Logger writing JSON to stdout, Recovery, and
+//     SecureHeaders.
+//   - "development": RequestID, Logger writing text to stderr at debug level,
+//     Recovery, response checks, see Router.CheckResponses, and a NotFound
+//     handler serving the debug pages: the route table at /_routerx/routes,
+//     Router.ExplainHandler at /_routerx/explain, and the pprof profiles
+//     under /debug/pprof/. Other unmatched requests are answered with the
+//     closest routes, see Router.SuggestionsHandler. Routes registered at
+//     those paths take precedence.
+//   - "test": RequestID with deterministic IDs, "test-000001",
+//     "test-000002", and so on, per router, and Recovery, without logging.
+//
+// Options after the Profile, or in overrides, replace its choices. Profile
+// panics on an unknown name.
+//
+// Example:
+//
+//	router := routerx.New(
+//	    routerx.Profile("production", routerx.WithoutSecureHeaders()),
+//	    routerx.WithMaxBody(1<<20),
+//	)
+func Profile(name string, overrides ...Option) Option {
+	var preset []Option
+	switch name {
+	case "production":
+		preset = []Option{
+			WithRequestID(),
+			WithLogger(slog.NewJSONHandler(os.Stdout, nil)),
+			WithRecovery(),
+			WithSecureHeaders(),
+		}
+	case "development":
+		preset = []Option{
+			WithRequestID(),
+			WithLogger(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})),
+			WithRecovery(),
+			func(router *Router) {
+				router.CheckResponses()
+				router.NotFound(router.developmentNotFound())
+			},
+		}
+	case "test":
+		preset = []Option{
+			WithRequestID(RequestIDConfig{Generate: sequentialRequestIDs("test")}),
+			WithRecovery(),
+		}
+	default:
+		panic(fmt.Sprintf("routerx: unknown profile %q, want production, development, or test", name))
+	}
+	return func(router *Router) {
+		for _, option := range append(preset, overrides...) {
+			option(router)
+		}
+	}
+}
+
+// ProfileFromEnv returns the Profile named by the environment variable, or
+// the production profile when the variable is not set, so that a forgotten
+// variable does not expose the debug pages.
+//
+// Example:
+//
+//	router := routerx.New(routerx.ProfileFromEnv("APP_ENV"))
+func ProfileFromEnv(variable string, overrides ...Option) Option {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv(variable)))
+	if name == "" {
+		name = "production"
+	}
+	return Profile(name, overrides...)
+}
+
+// developmentNotFound returns the NotFound handler of the development
+// profile.
+func (router *Router) developmentNotFound() http.HandlerFunc {
+	pages := http.NewServeMux()
+	pages.HandleFunc("GET /_routerx/routes", func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusOK, router.Diagnose())
+	})
+	pages.Handle("GET /_routerx/explain", router.ExplainHandler())
+	pages.Handle("/debug/pprof/{profile...}", pprofHandler())
+	suggestions := router.SuggestionsHandler()
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		if handler, pattern := pages.Handler(request); pattern != "" {
+			handler.ServeHTTP(responseWriter, request)
+			return
+		}
+		suggestions(responseWriter, request)
+	}
+}
+
+// sequentialRequestIDs returns a generator of the request IDs prefix-000001,
+// prefix-000002, and so on.
+func sequentialRequestIDs(prefix string) func() string {
+	var counter atomic.Uint64
+	return func() string {
+		return fmt.Sprintf("%s-%06d", prefix, counter.Add(1))
+	}
+}
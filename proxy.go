@@ -0,0 +1,154 @@
+package routerx
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProxyConfig configures Router.Proxy and RouteGroup.Proxy.
+type ProxyConfig struct {
+	// StripPrefix removes the proxied prefix from the request path, so
+	// that "/api/users" proxied at "/api" to "http://users:8080/v1" is
+	// forwarded to "/v1/users" instead of "/v1/api/users".
+	StripPrefix bool
+
+	// PreserveHost forwards the client's Host header instead of the host
+	// of the target.
+	PreserveHost bool
+
+	// Rewrite adjusts the outgoing request after the default rewriting,
+	// e.g. to add headers or rewrite the path further.
+	Rewrite func(proxyRequest *httputil.ProxyRequest)
+
+	// ModifyResponse is called with every upstream response before it is
+	// copied to the client. Returning an error answers 502 Bad Gateway.
+	ModifyResponse func(response *http.Response) error
+
+	// Transport reaches the upstream. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// FlushInterval is how often the response is flushed to the client
+	// while it is copied; a negative value flushes after every write.
+	// Streamed responses such as server-sent events are always flushed
+	// immediately.
+	FlushInterval time.Duration
+}
+
+// Proxy forwards every request under prefix, for any method, to target with
+// httputil.ReverseProxy, so that routerx can double as a lightweight
+// gateway. The request path is appended to the path of target, optionally
+// without prefix, see ProxyConfig.StripPrefix. The forwarded request carries
+// X-Forwarded-For, X-Forwarded-Host, and X-Forwarded-Proto. Upstream
+// failures are answered with 502 Bad Gateway, or 504 Gateway Timeout, through
+// the router's error handler, see HandleError. The router's middlewares
+// apply. Proxy panics if target is not an absolute URL.
+//
+// Example:
+//
+//	router.Proxy("/api/users", "http://users.internal:8080", routerx.ProxyConfig{
+//	    StripPrefix: true,
+//	    Rewrite: func(proxyRequest *httputil.ProxyRequest) {
+//	        proxyRequest.Out.Header.Set("X-Gateway", "routerx")
+//	    },
+//	})
+func (router *Router) Proxy(prefix string, target string, configs ...ProxyConfig) {
+	router.proxy(cleanPath(prefix), target, configs, router.middlewares)
+}
+
+// Proxy forwards every request under the group's prefix joined with prefix
+// to target, applying the group's middleware chain. See Router.Proxy.
+//
+// Example:
+//
+//	legacy := router.Group("/legacy").Use(requireLogin)
+//	legacy.Proxy("/", "http://monolith.internal", routerx.ProxyConfig{StripPrefix: true})
+func (group *RouteGroup) Proxy(prefix string, target string, configs ...ProxyConfig) {
+	group.seal()
+	group.router.proxy(joinPath(group.prefix, prefix), target, configs, group.middlewares)
+}
+
+func (router *Router) proxy(prefix string, target string, configs []ProxyConfig, middlewares []Middleware) {
+	var config ProxyConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	targetURL := parseUpstream(target)
+	prefix = proxyPrefix(prefix)
+	handler := newReverseProxy(prefix, config, func(*http.Request) *url.URL { return targetURL })
+	router.registerProxy(prefix, handler, middlewares)
+}
+
+// registerProxy serves prefix and everything below it with handler.
+func (router *Router) registerProxy(prefix string, handler http.Handler, middlewares []Middleware) {
+	router.register("", prefix, handler, middlewares, nil).undocumented = true
+	if prefix != "/" {
+		router.register("", prefix+"/", handler, middlewares, nil).undocumented = true
+	}
+}
+
+// proxyPrefix returns prefix without a trailing slash, or "/".
+func proxyPrefix(prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return "/"
+	}
+	return prefix
+}
+
+// parseUpstream parses the URL of an upstream, which must be absolute.
+func parseUpstream(target string) *url.URL {
+	targetURL, err := url.Parse(target)
+	if err != nil || targetURL.Scheme == "" || targetURL.Host == "" {
+		panic("routerx: Proxy needs an absolute target URL, got " + target)
+	}
+	return targetURL
+}
+
+// newReverseProxy creates the reverse proxy of a prefix, forwarding every
+// request to the upstream chosen by upstream.
+func newReverseProxy(prefix string, config ProxyConfig, upstream func(request *http.Request) *url.URL) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(proxyRequest *httputil.ProxyRequest) {
+			if config.StripPrefix && prefix != "/" {
+				out := proxyRequest.Out.URL
+				out.Path = "/" + strings.TrimLeft(strings.TrimPrefix(out.Path, prefix), "/")
+				out.RawPath = ""
+			}
+			proxyRequest.SetURL(upstream(proxyRequest.In))
+			proxyRequest.SetXForwarded()
+			if config.PreserveHost {
+				proxyRequest.Out.Host = proxyRequest.In.Host
+			}
+			if config.Rewrite != nil {
+				config.Rewrite(proxyRequest)
+			}
+		},
+		ModifyResponse: config.ModifyResponse,
+		Transport:      config.Transport,
+		FlushInterval:  config.FlushInterval,
+		ErrorHandler:   proxyErrorHandler,
+	}
+}
+
+// proxyErrorHandler answers failed upstream requests through the router's
+// error handler.
+func proxyErrorHandler(responseWriter http.ResponseWriter, request *http.Request, err error) {
+	if errors.Is(err, context.Canceled) && request.Context().Err() != nil {
+		// The client went away; nobody reads the response.
+		return
+	}
+	log.Printf("routerx: proxy %s %s: %v", request.Method, request.URL.Path, err)
+	var timeout net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &timeout) && timeout.Timeout() {
+		HandleError(responseWriter, request, NewHTTPError(http.StatusGatewayTimeout, "upstream timed out"))
+		return
+	}
+	HandleError(responseWriter, request, NewHTTPError(http.StatusBadGateway, "upstream unavailable"))
+}
@@ -0,0 +1,169 @@
+package routerx
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// QueueConfig configures the Queue middleware.
+type QueueConfig struct {
+	// Workers is the number of requests executed at the same time.
+	// Defaults to 1.
+	Workers int
+
+	// MaxQueue is the number of requests allowed to wait for a worker.
+	// Further requests are rejected with 503. Defaults to 100.
+	MaxQueue int
+
+	// MaxWait rejects requests with 503 once they waited this long without
+	// getting a worker. Zero means requests wait until the client gives up.
+	MaxWait time.Duration
+
+	// ExpectedDuration seeds the estimate of how long a request takes. The
+	// estimate adapts to observed durations. Defaults to one second.
+	ExpectedDuration time.Duration
+}
+
+// Queue returns a Middleware that admits at most config.Workers concurrent
+// requests and lets up to config.MaxQueue more wait for a worker, instead of
+// failing them under burst load. It is meant for routes guarding scarce
+// resources such as GPU inference.
+//
+// Responses carry the position the request had in the queue, the wait
+// estimated when it joined the queue, and the time it actually waited in the
+// X-Queue-Position, X-Queue-Estimate, and X-Queue-Wait headers, the latter
+// two in milliseconds. Requests that have to wait are sent a 103 Early Hints
+// interim response carrying X-Queue-Position and X-Queue-Estimate as soon as
+// they join the queue, so that clients reading interim responses, such as
+// those using net/http/httptrace's Got1xxResponse, can show progress before
+// the request runs. HTTP/1.0 clients, which cannot receive interim
+// responses, only learn them from the final response. Requests rejected
+// because the queue is full receive 503 with a Retry-After header estimated
+// from the queue length and the observed request duration.
+//
+// Example:
+//
+//	inference := router.Group("/infer").Use(routerx.Queue(routerx.QueueConfig{
+//	    Workers:  2,
+//	    MaxQueue: 50,
+//	    MaxWait:  30 * time.Second,
+//	}))
+func Queue(config QueueConfig) Middleware {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.MaxQueue <= 0 {
+		config.MaxQueue = 100
+	}
+	if config.ExpectedDuration <= 0 {
+		config.ExpectedDuration = time.Second
+	}
+	queue := &requestQueue{
+		config:  config,
+		workers: make(chan struct{}, config.Workers),
+		average: config.ExpectedDuration,
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			queue.serve(next, responseWriter, request)
+		})
+	}
+}
+
+// requestQueue is the state shared by all requests passing one Queue
+// middleware.
+type requestQueue struct {
+	config  QueueConfig
+	workers chan struct{}
+
+	mutex   sync.Mutex
+	waiting int
+	average time.Duration
+}
+
+func (queue *requestQueue) serve(next http.Handler, responseWriter http.ResponseWriter, request *http.Request) {
+	// Fast path: a worker is free.
+	select {
+	case queue.workers <- struct{}{}:
+		queue.execute(next, responseWriter, request, 0, 0, 0)
+		return
+	default:
+	}
+
+	queue.mutex.Lock()
+	if queue.waiting >= queue.config.MaxQueue {
+		estimate := queue.estimate(queue.waiting + 1)
+		queue.mutex.Unlock()
+		queue.reject(responseWriter, estimate, "queue is full")
+		return
+	}
+	queue.waiting++
+	position := queue.waiting
+	estimate := queue.estimate(position)
+	queue.mutex.Unlock()
+
+	if request.ProtoAtLeast(1, 1) {
+		responseWriter.Header().Set("X-Queue-Position", strconv.Itoa(position))
+		responseWriter.Header().Set("X-Queue-Estimate", strconv.FormatInt(estimate.Milliseconds(), 10))
+		responseWriter.WriteHeader(http.StatusEarlyHints)
+	}
+
+	clock := GetClock(request)
+	var timeout <-chan time.Time
+	if queue.config.MaxWait > 0 {
+		timeout = clock.After(queue.config.MaxWait)
+	}
+	start := clock.Now()
+	select {
+	case queue.workers <- struct{}{}:
+		queue.leave()
+		queue.execute(next, responseWriter, request, position, estimate, clock.Now().Sub(start))
+	case <-timeout:
+		queue.leave()
+		queue.mutex.Lock()
+		retryAfter := queue.estimate(position)
+		queue.mutex.Unlock()
+		queue.reject(responseWriter, retryAfter, "timed out waiting in queue")
+	case <-request.Context().Done():
+		queue.leave()
+	}
+}
+
+func (queue *requestQueue) execute(next http.Handler, responseWriter http.ResponseWriter, request *http.Request, position int, estimate time.Duration, waited time.Duration) {
+	defer func() { <-queue.workers }()
+	responseWriter.Header().Set("X-Queue-Position", strconv.Itoa(position))
+	responseWriter.Header().Set("X-Queue-Estimate", strconv.FormatInt(estimate.Milliseconds(), 10))
+	responseWriter.Header().Set("X-Queue-Wait", strconv.FormatInt(waited.Milliseconds(), 10))
+
+	clock := GetClock(request)
+	start := clock.Now()
+	next.ServeHTTP(responseWriter, request)
+	elapsed := clock.Now().Sub(start)
+
+	queue.mutex.Lock()
+	// Exponentially weighted moving average favouring recent requests.
+	queue.average = (queue.average*4 + elapsed) / 5
+	queue.mutex.Unlock()
+}
+
+func (queue *requestQueue) leave() {
+	queue.mutex.Lock()
+	queue.waiting--
+	queue.mutex.Unlock()
+}
+
+// estimate returns the expected wait for the given queue position. The
+// caller must hold the mutex.
+func (queue *requestQueue) estimate(position int) time.Duration {
+	rounds := math.Ceil(float64(position) / float64(queue.config.Workers))
+	return time.Duration(rounds) * queue.average
+}
+
+func (queue *requestQueue) reject(responseWriter http.ResponseWriter, estimate time.Duration, message string) {
+	seconds := int(math.Ceil(estimate.Seconds()))
+	responseWriter.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	writeError(responseWriter, http.StatusServiceUnavailable, message)
+}
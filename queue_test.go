@@ -0,0 +1,64 @@
+package routerx
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"testing"
+)
+
+func TestQueueEarlyHints(t *testing.T) {
+	release := make(chan struct{})
+	running := make(chan struct{})
+	router := New(WithLogger(slog.NewTextHandler(io.Discard, nil)))
+	router.Use(Queue(QueueConfig{Workers: 1}))
+	router.Get("/", func(responseWriter http.ResponseWriter, request *http.Request) {
+		if request.URL.Query().Get("block") != "" {
+			close(running)
+			<-release
+		}
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	go http.Get(server.URL + "/?block=1")
+	<-running
+
+	hints := make(chan textproto.MIMEHeader, 1)
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusEarlyHints {
+				hints <- header
+			}
+			return nil
+		},
+	}
+	done := make(chan *http.Response, 1)
+	go func() {
+		request, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, server.URL+"/", nil)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Error(err)
+			close(done)
+			return
+		}
+		response.Body.Close()
+		done <- response
+	}()
+
+	header := <-hints
+	if got := header.Get("X-Queue-Position"); got != "1" {
+		t.Errorf("interim X-Queue-Position = %q, want %q", got, "1")
+	}
+	if got := header.Get("X-Queue-Estimate"); got != "1000" {
+		t.Errorf("interim X-Queue-Estimate = %q, want %q", got, "1000")
+	}
+	close(release)
+	if response := <-done; response != nil && response.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusOK)
+	}
+}
@@ -0,0 +1,170 @@
+package routerx
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig configures the RateLimit middleware.
+type RateLimitConfig struct {
+	// Key identifies the client a request is counted against, e.g. by API
+	// key or authenticated principal. Defaults to the remote IP. Requests
+	// with an empty key are not limited.
+	Key func(request *http.Request) string
+
+	// Store holds the buckets. Defaults to a MemoryRateLimitStore private
+	// to the middleware; share a store such as a Redis-backed one between
+	// instances to enforce the limit across a deployment.
+	Store RateLimitStore
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	// Allowed reports whether a token was available.
+	Allowed bool
+
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+
+	// RetryAfter is the time until the next token is available when the
+	// request was not allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps token buckets by key. A bucket holds up to limit
+// tokens and refills at limit tokens per window. Implementations must be
+// safe for concurrent use; stores shared between instances should take the
+// token atomically, e.g. with a Lua script in Redis.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error)
+}
+
+// RateLimit returns a Middleware that limits every client to limit requests
+// per window with a token bucket, so that short bursts up to limit are
+// allowed while the sustained rate is bounded. Responses carry
+// X-RateLimit-Limit, X-RateLimit-Remaining, and X-RateLimit-Reset, the
+// seconds until the bucket is full; rejected requests are answered with 429
+// Too Many Requests and Retry-After. Requests are let through when the store
+// fails, so that an outage of a shared store does not take the API down.
+//
+// Example:
+//
+//	api := router.Group("/api").Use(routerx.RateLimit(100, time.Minute, routerx.RateLimitConfig{
+//	    Key: func(request *http.Request) string { return request.Header.Get("X-API-Key") },
+//	}))
+func RateLimit(limit int, window time.Duration, configs ...RateLimitConfig) Middleware {
+	if limit <= 0 || window <= 0 {
+		panic("routerx: RateLimit needs a positive limit and window")
+	}
+	var config RateLimitConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.Key == nil {
+		config.Key = remoteIP
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			key := config.Key(request)
+			if key == "" {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			result, err := config.Store.Take(request.Context(), key, limit, window)
+			if err != nil {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			header := responseWriter.Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			if !result.Allowed {
+				header.Set("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+				writeError(responseWriter, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
+
+// MemoryRateLimitStore is a RateLimitStore keeping the buckets in memory,
+// for single instance deployments. Full buckets are dropped periodically,
+// so memory use is bounded by the number of active clients.
+type MemoryRateLimitStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+// Take takes a token from the bucket of key.
+func (store *MemoryRateLimitStore) Take(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	now := clockFrom(ctx).Now()
+	rate := float64(limit) / window.Seconds()
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if now.Sub(store.lastSweep) > window {
+		store.sweep(now)
+	}
+	bucket := store.buckets[key]
+	if bucket == nil {
+		bucket = &tokenBucket{tokens: float64(limit), updated: now}
+		store.buckets[key] = bucket
+	}
+	bucket.tokens = min(float64(limit), bucket.tokens+now.Sub(bucket.updated).Seconds()*rate)
+	bucket.updated = now
+
+	result := RateLimitResult{}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsDuration((1 - bucket.tokens) / rate)
+	}
+	result.Remaining = int(bucket.tokens)
+	result.Reset = secondsDuration((float64(limit) - bucket.tokens) / rate)
+	bucket.full = now.Add(result.Reset)
+	return result, nil
+}
+
+// sweep drops the buckets that have refilled completely, since a new bucket
+// is equivalent.
+func (store *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, bucket := range store.buckets {
+		if !now.Before(bucket.full) {
+			delete(store.buckets, key)
+		}
+	}
+	store.lastSweep = now
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,94 @@
+package routerx
+
+import (
+	"net/http"
+	"slices"
+	"sync/atomic"
+)
+
+// ReadOnly makes the Router reject unsafe methods (POST, PUT, PATCH, DELETE)
+// with 503 Service Unavailable while enabled reports true. GET, HEAD,
+// OPTIONS, and TRACE requests keep working. enabled is called for every
+// request, so it can be backed by an atomic flag that is flipped during
+// database failovers or migrations.
+//
+// Unlike Use, ReadOnly is checked before any route is matched, so it covers
+// every route however late it is called. Several calls combine: the router
+// is read-only while any enabled reports true.
+//
+// Example:
+//
+//	var maintenance atomic.Bool
+//	router := routerx.New().ReadOnly(maintenance.Load)
+func (router *Router) ReadOnly(enabled func() bool) *Router {
+	router.readOnly.add(enabled)
+	return router
+}
+
+// ReadOnly makes the RouteGroup reject unsafe methods with 503 Service
+// Unavailable while enabled reports true, covering the routes of the group
+// and its nested groups registered before and after the call. See
+// Router.ReadOnly.
+func (group *RouteGroup) ReadOnly(enabled func() bool) *RouteGroup {
+	group.readOnly.add(enabled)
+	return group
+}
+
+// readOnlyChecks holds the enabled functions of ReadOnly. The middleware of
+// a group, see readOnly, is installed when the group is created and reads
+// the functions added later, so that ReadOnly does not depend on the order
+// of registration.
+type readOnlyChecks struct {
+	enabled atomic.Pointer[[]func() bool]
+}
+
+func (checks *readOnlyChecks) add(enabled func() bool) {
+	for {
+		current := checks.enabled.Load()
+		var updated []func() bool
+		if current != nil {
+			updated = slices.Clone(*current)
+		}
+		updated = append(updated, enabled)
+		if checks.enabled.CompareAndSwap(current, &updated) {
+			return
+		}
+	}
+}
+
+// rejects reports whether request must be rejected, answering it if so.
+func (checks *readOnlyChecks) rejects(responseWriter http.ResponseWriter, request *http.Request) bool {
+	enabled := checks.enabled.Load()
+	if enabled == nil || isSafeMethod(request.Method) {
+		return false
+	}
+	for _, check := range *enabled {
+		if check() {
+			writeError(responseWriter, http.StatusServiceUnavailable, "service is in read-only mode")
+			return true
+		}
+	}
+	return false
+}
+
+// readOnly returns the middleware of a group enforcing checks.
+func readOnly(checks *readOnlyChecks) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			if checks.rejects(responseWriter, request) {
+				return
+			}
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
+
+// isSafeMethod reports whether method is one of the HTTP methods that do not
+// modify server state.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
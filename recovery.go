@@ -0,0 +1,175 @@
+package routerx
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// RecoveryConfig configures the Recovery middleware.
+type RecoveryConfig struct {
+	// CrashDumpDir enables crash dumps: every panic writes a JSON file to
+	// the directory with a summary of the request, the panic value and
+	// stack, the stacks of all goroutines, and the build information, for
+	// postmortems when logs are sampled or lost.
+	CrashDumpDir string
+
+	// CrashDumpInterval is the minimum time between two crash dumps, so
+	// that a panic on a hot path does not fill the disk. Defaults to one
+	// minute.
+	CrashDumpInterval time.Duration
+}
+
+// CrashDump is the content of a crash dump file.
+type CrashDump struct {
+	Time    time.Time         `json:"time"`
+	Panic   string            `json:"panic"`
+	Request CrashDumpRequest  `json:"request"`
+	Stack   string            `json:"stack"`
+	Build   map[string]string `json:"build,omitempty"`
+
+	// Goroutines holds the stacks of all goroutines when the panic was
+	// recovered.
+	Goroutines string `json:"goroutines"`
+}
+
+// CrashDumpRequest summarizes the request that panicked. The values of
+// credential headers are left out.
+type CrashDumpRequest struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Route    string      `json:"route,omitempty"`
+	RemoteIP string      `json:"remote_ip"`
+	Header   http.Header `json:"header"`
+}
+
+// crashDumpRedacted lists the headers whose values crash dumps leave out.
+var crashDumpRedacted = []string{"Authorization", "Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// Recovery returns a Middleware that recovers panics in handlers, logs them
+// with their stack, and answers 500 Internal Server Error through the
+// router's error handler, see HandleError, unless the response was already
+// started. http.ErrAbortHandler is re-raised so that net/http aborts the
+// response as intended. Use it first on the router so that it covers the
+// other middlewares.
+//
+// Example:
+//
+//	router := routerx.New().Use(routerx.Recovery(routerx.RecoveryConfig{
+//	    CrashDumpDir: "/var/crash/orders",
+//	}), routerx.Logger(slog.Default().Handler()))
+func Recovery(configs ...RecoveryConfig) Middleware {
+	var config RecoveryConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.CrashDumpInterval <= 0 {
+		config.CrashDumpInterval = time.Minute
+	}
+	var mutex sync.Mutex
+	var lastDump time.Time
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			writer := &statusWriter{ResponseWriter: responseWriter}
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+				stack := debug.Stack()
+				log.Printf("routerx: panic serving %s %s: %v\n%s", request.Method, request.URL.Path, recovered, stack)
+
+				if config.CrashDumpDir != "" {
+					mutex.Lock()
+					now := GetClock(request).Now()
+					due := now.Sub(lastDump) >= config.CrashDumpInterval
+					if due {
+						lastDump = now
+					}
+					mutex.Unlock()
+					if due {
+						path, err := writeCrashDump(config.CrashDumpDir, request, recovered, stack)
+						if err != nil {
+							log.Printf("routerx: write crash dump: %v", err)
+						} else {
+							log.Printf("routerx: crash dump written to %s", path)
+						}
+					}
+				}
+
+				if writer.statusCode == 0 {
+					HandleError(writer, request, NewHTTPError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)))
+				}
+			}()
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// writeCrashDump writes the crash dump of a panic to dir and returns the
+// path of the file.
+func writeCrashDump(dir string, request *http.Request, recovered any, stack []byte) (string, error) {
+	header := request.Header.Clone()
+	for _, name := range crashDumpRedacted {
+		if header.Get(name) != "" {
+			header.Set(name, "[REDACTED]")
+		}
+	}
+	goroutines := make([]byte, 1<<20)
+	goroutines = goroutines[:runtime.Stack(goroutines, true)]
+	now := GetClock(request).Now().UTC()
+	dump := CrashDump{
+		Time:  now,
+		Panic: fmt.Sprint(recovered),
+		Request: CrashDumpRequest{
+			Method:   request.Method,
+			URL:      request.URL.String(),
+			Route:    routePattern(request.Pattern),
+			RemoteIP: remoteIP(request),
+			Header:   header,
+		},
+		Stack:      string(stack),
+		Build:      buildSettings(),
+		Goroutines: string(goroutines),
+	}
+	encoded, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "crash-"+now.Format("20060102T150405Z")+"-"+randomText(GetRandom(request))[:8]+".json")
+	return path, os.WriteFile(path, encoded, 0o640)
+}
+
+// buildSettings returns the Go version, main module, and VCS settings the
+// binary was built with.
+func buildSettings() map[string]string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	settings := map[string]string{
+		"go_version": info.GoVersion,
+		"path":       info.Main.Path,
+		"version":    info.Main.Version,
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision", "vcs.time", "vcs.modified":
+			settings[setting.Key] = setting.Value
+		}
+	}
+	return settings
+}
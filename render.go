@@ -0,0 +1,160 @@
+package routerx
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagger is implemented by response values that know their entity tag,
+// e.g. from a version column. The returned tag may omit the surrounding
+// quotes; a weak tag keeps its W/ prefix.
+type ETagger interface {
+	ETag() string
+}
+
+// LastModifier is implemented by response values that know when they last
+// changed, e.g. from an updated_at column.
+type LastModifier interface {
+	LastModified() time.Time
+}
+
+// Render writes value as JSON with the given status code. When value
+// implements ETagger or LastModifier, Render sets the ETag and Last-Modified
+// headers and answers conditional GET and HEAD requests with 304 Not
+// Modified, so handlers returning such values need no cache logic of their
+// own.
+//
+// Example:
+//
+//	func (user User) ETag() string { return strconv.Itoa(user.Version) }
+//	func (user User) LastModified() time.Time { return user.UpdatedAt }
+//
+//	router.Get("/users/{id}", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    user := loadUser(request.PathValue("id"))
+//	    routerx.Render(responseWriter, request, http.StatusOK, user)
+//	})
+func Render(responseWriter http.ResponseWriter, request *http.Request, statusCode int, value any) {
+	if statusCode >= 200 && statusCode < 300 {
+		setValidators(responseWriter.Header(), value)
+		if (request.Method == http.MethodGet || request.Method == http.MethodHead) && !Preconditions(responseWriter, request, value) {
+			return
+		}
+	}
+	writeJSON(responseWriter, statusCode, value)
+}
+
+// Preconditions evaluates the conditional request headers (If-Match,
+// If-Unmodified-Since, If-None-Match, If-Modified-Since) against the
+// validators of current, the resource's present state, following RFC 9110
+// section 13.2.2. When a condition fails it writes 304 Not Modified or 412
+// Precondition Failed and returns false. Call it before modifying a resource
+// to implement optimistic concurrency with If-Match.
+//
+// Example:
+//
+//	router.Put("/users/{id}", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    user := loadUser(request.PathValue("id"))
+//	    if !routerx.Preconditions(responseWriter, request, user) {
+//	        return
+//	    }
+//	    updated := saveUser(user, request.Body)
+//	    routerx.Render(responseWriter, request, http.StatusOK, updated)
+//	})
+func Preconditions(responseWriter http.ResponseWriter, request *http.Request, current any) bool {
+	etag, lastModified := validators(current)
+	safe := request.Method == http.MethodGet || request.Method == http.MethodHead
+
+	if ifMatch := request.Header.Get("If-Match"); ifMatch != "" {
+		if !matchETag(ifMatch, etag, current != nil, false) {
+			writeError(responseWriter, http.StatusPreconditionFailed, "precondition failed")
+			return false
+		}
+	} else if since, err := http.ParseTime(request.Header.Get("If-Unmodified-Since")); err == nil && !lastModified.IsZero() {
+		if lastModified.Truncate(time.Second).After(since) {
+			writeError(responseWriter, http.StatusPreconditionFailed, "precondition failed")
+			return false
+		}
+	}
+
+	if ifNoneMatch := request.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if matchETag(ifNoneMatch, etag, current != nil, true) {
+			if safe {
+				notModified(responseWriter)
+			} else {
+				writeError(responseWriter, http.StatusPreconditionFailed, "precondition failed")
+			}
+			return false
+		}
+	} else if since, err := http.ParseTime(request.Header.Get("If-Modified-Since")); err == nil && safe && !lastModified.IsZero() {
+		if !lastModified.Truncate(time.Second).After(since) {
+			notModified(responseWriter)
+			return false
+		}
+	}
+	return true
+}
+
+// validators returns the entity tag and modification time of value.
+func validators(value any) (string, time.Time) {
+	var etag string
+	var lastModified time.Time
+	if tagger, ok := value.(ETagger); ok {
+		etag = quoteETag(tagger.ETag())
+	}
+	if modifier, ok := value.(LastModifier); ok {
+		lastModified = modifier.LastModified()
+	}
+	return etag, lastModified
+}
+
+// setValidators sets the ETag and Last-Modified headers for value.
+func setValidators(header http.Header, value any) {
+	etag, lastModified := validators(value)
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified writes 304 Not Modified, dropping the representation headers
+// that must not accompany it.
+func notModified(responseWriter http.ResponseWriter) {
+	header := responseWriter.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	responseWriter.WriteHeader(http.StatusNotModified)
+}
+
+// quoteETag adds the quotes an entity tag requires when they are missing.
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// matchETag reports whether etag matches one of the tags in an If-Match or
+// If-None-Match header. weak selects the weak comparison used by
+// If-None-Match; If-Match requires strong tags. "*" matches any existing
+// representation.
+func matchETag(header string, etag string, exists bool, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return exists
+	}
+	if etag == "" || (!weak && strings.HasPrefix(etag, "W/")) {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if !weak && strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package routerx
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+)
+
+type requestIDContextKey struct{}
+
+// RequestIDConfig configures the RequestID middleware.
+type RequestIDConfig struct {
+	// Header carries the request ID on the request and the response.
+	// Defaults to "X-Request-ID", the header Logger reads.
+	Header string
+
+	// Generate returns the ID of a request that arrives without one.
+	// Defaults to 16 random bytes in hex, read from GetRandom.
+	Generate func() string
+}
+
+// RequestID returns a Middleware that gives every request an ID: the one in
+// the request header, set by a proxy or the client, or a generated one. The
+// ID is set on the request header, so that Logger and downstream services
+// see it, echoed in the response header, and available with GetRequestID.
+//
+// Example:
+//
+//	router := routerx.New().Use(routerx.RequestID(), routerx.Logger(handler))
+func RequestID(configs ...RequestIDConfig) Middleware {
+	var config RequestIDConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.Header == "" {
+		config.Header = "X-Request-ID"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			id := request.Header.Get(config.Header)
+			if id == "" || len(id) > 128 {
+				if config.Generate != nil {
+					id = config.Generate()
+				} else {
+					id = randomRequestID(GetRandom(request))
+				}
+				request.Header.Set(config.Header, id)
+			}
+			responseWriter.Header().Set(config.Header, id)
+			next.ServeHTTP(responseWriter, request.WithContext(context.WithValue(request.Context(), requestIDContextKey{}, id)))
+		})
+	}
+}
+
+// GetRequestID returns the ID given to the request by RequestID, or "".
+func GetRequestID(request *http.Request) string {
+	id, _ := request.Context().Value(requestIDContextKey{}).(string)
+	return id
+}
+
+func randomRequestID(random io.Reader) string {
+	var id [16]byte
+	_, _ = io.ReadFull(random, id[:])
+	return hex.EncodeToString(id[:])
+}
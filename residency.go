@@ -0,0 +1,80 @@
+package routerx
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// residencyForwardedHeader marks requests that were already forwarded to
+// their home region so that misconfigured deployments cannot loop.
+const residencyForwardedHeader = "X-Routerx-Residency-Forwarded"
+
+// ResidencyConfig configures the Residency middleware.
+type ResidencyConfig struct {
+	// Region returns the region in which the data of the request's tenant
+	// must be processed. Returning false means the request is not subject
+	// to residency rules and is served locally.
+	Region func(request *http.Request) (region string, restricted bool)
+
+	// LocalRegion is the region of the current deployment.
+	LocalRegion string
+
+	// Regions maps region names to the base URL of the deployment serving
+	// that region. Requests for regions missing from the map are rejected.
+	Regions map[string]*url.URL
+
+	// Transport is used to reach remote deployments. http.DefaultTransport
+	// is used when nil.
+	Transport http.RoundTripper
+}
+
+// Residency returns a Middleware that enforces data-residency rules. Requests
+// whose tenant belongs to LocalRegion are served locally, requests for a known
+// remote region are proxied to that region's deployment, and all other
+// requests are rejected with 421 Misdirected Request and a descriptive error.
+//
+// Example:
+//
+//	router.Use(routerx.Residency(routerx.ResidencyConfig{
+//	    Region:      tenantRegion,
+//	    LocalRegion: "us",
+//	    Regions: map[string]*url.URL{
+//	        "eu": mustParse("https://eu.api.example.com"),
+//	    },
+//	}))
+func Residency(config ResidencyConfig) Middleware {
+	proxies := make(map[string]*httputil.ReverseProxy, len(config.Regions))
+	for region, target := range config.Regions {
+		proxies[region] = &httputil.ReverseProxy{
+			Rewrite: func(proxyRequest *httputil.ProxyRequest) {
+				proxyRequest.SetURL(target)
+				proxyRequest.SetXForwarded()
+				proxyRequest.Out.Header.Set(residencyForwardedHeader, config.LocalRegion)
+			},
+			Transport: config.Transport,
+			ErrorHandler: func(responseWriter http.ResponseWriter, request *http.Request, err error) {
+				writeError(responseWriter, http.StatusBadGateway, fmt.Sprintf("region %q is unavailable", region))
+			},
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			region, restricted := config.Region(request)
+			if !restricted || region == config.LocalRegion {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			proxy, found := proxies[region]
+			if !found || request.Header.Get(residencyForwardedHeader) != "" {
+				writeJSON(responseWriter, http.StatusMisdirectedRequest, map[string]string{
+					"error":  fmt.Sprintf("tenant data must be processed in region %q", region),
+					"region": region,
+				})
+				return
+			}
+			proxy.ServeHTTP(responseWriter, request)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package routerx
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// JSONEncoderFunc writes value to writer as JSON.
+type JSONEncoderFunc func(writer io.Writer, value any) error
+
+var (
+	jsonEncoderMutex sync.RWMutex
+	jsonEncoder      JSONEncoderFunc = func(writer io.Writer, value any) error {
+		return json.NewEncoder(writer).Encode(value)
+	}
+)
+
+// SetJSONEncoder replaces the encoder used by JSON, Render, and every JSON
+// response written by routerx, which defaults to encoding/json, e.g. to use
+// a faster serializer.
+//
+// Example:
+//
+//	routerx.SetJSONEncoder(func(writer io.Writer, value any) error {
+//	    return sonic.ConfigDefault.NewEncoder(writer).Encode(value)
+//	})
+func SetJSONEncoder(encode JSONEncoderFunc) {
+	jsonEncoderMutex.Lock()
+	defer jsonEncoderMutex.Unlock()
+	jsonEncoder = encode
+}
+
+// JSON writes data as JSON with the given status code. Encoding errors are
+// logged, since the status code has already been sent.
+//
+// Example:
+//
+//	routerx.JSON(responseWriter, http.StatusOK, map[string]string{"message": "hello"})
+func JSON(responseWriter http.ResponseWriter, statusCode int, data any) {
+	jsonEncoderMutex.RLock()
+	encode := jsonEncoder
+	jsonEncoderMutex.RUnlock()
+	responseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	responseWriter.WriteHeader(statusCode)
+	if err := encode(responseWriter, data); err != nil {
+		log.Printf("routerx: encode JSON response: %v", err)
+	}
+}
+
+// Text writes text as a plain text response with the given status code.
+//
+// Example:
+//
+//	routerx.Text(responseWriter, http.StatusOK, "pong")
+func Text(responseWriter http.ResponseWriter, statusCode int, text string) {
+	responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	responseWriter.WriteHeader(statusCode)
+	_, _ = io.WriteString(responseWriter, text)
+}
+
+// XML writes data as XML, preceded by the XML header, with the given status
+// code. Encoding errors are logged.
+//
+// Example:
+//
+//	routerx.XML(responseWriter, http.StatusOK, feed)
+func XML(responseWriter http.ResponseWriter, statusCode int, data any) {
+	responseWriter.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	responseWriter.WriteHeader(statusCode)
+	_, _ = io.WriteString(responseWriter, xml.Header)
+	if err := xml.NewEncoder(responseWriter).Encode(data); err != nil {
+		log.Printf("routerx: encode XML response: %v", err)
+	}
+}
+
+// NoContent writes an empty 204 No Content response.
+//
+// Example:
+//
+//	routerx.NoContent(responseWriter)
+func NoContent(responseWriter http.ResponseWriter) {
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// Blob writes data with the given content type and status code.
+//
+// Example:
+//
+//	routerx.Blob(responseWriter, http.StatusOK, "image/png", thumbnail)
+func Blob(responseWriter http.ResponseWriter, statusCode int, contentType string, data []byte) {
+	responseWriter.Header().Set("Content-Type", contentType)
+	responseWriter.WriteHeader(statusCode)
+	_, _ = responseWriter.Write(data)
+}
+
+// Stream copies reader to the response with the given content type and
+// status code, flushing after every chunk so that clients receive data as
+// it is produced. Copy errors are returned; the status code has already been
+// sent by then.
+//
+// Example:
+//
+//	routerx.Stream(responseWriter, http.StatusOK, "text/csv", exportReader)
+func Stream(responseWriter http.ResponseWriter, statusCode int, contentType string, reader io.Reader) error {
+	responseWriter.Header().Set("Content-Type", contentType)
+	responseWriter.WriteHeader(statusCode)
+	controller := http.NewResponseController(responseWriter)
+	buffer := make([]byte, 32*1024)
+	for {
+		count, readErr := reader.Read(buffer)
+		if count > 0 {
+			if _, err := responseWriter.Write(buffer[:count]); err != nil {
+				return err
+			}
+			_ = controller.Flush()
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// writeJSON encodes data as JSON and writes it with the given status code.
+func writeJSON(responseWriter http.ResponseWriter, statusCode int, data any) {
+	JSON(responseWriter, statusCode, data)
+}
+
+// writeError writes a JSON error body of the form {"error": message}.
+func writeError(responseWriter http.ResponseWriter, statusCode int, message string) {
+	writeJSON(responseWriter, statusCode, map[string]string{"error": message})
+}
+
+// addVary adds field names to the Vary header of header unless they are
+// already listed, so that independent middlewares can each contribute the
+// request headers their response depends on.
+func addVary(header http.Header, names ...string) {
+	existing := strings.Join(header.Values("Vary"), ",")
+	if strings.TrimSpace(existing) == "*" {
+		return
+	}
+	for _, name := range names {
+		listed := false
+		for _, field := range strings.Split(existing, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), name) {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			header.Add("Vary", name)
+			existing += "," + name
+		}
+	}
+}
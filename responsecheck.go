@@ -0,0 +1,220 @@
+package routerx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxCheckedResponse is the largest response body CheckResponses inspects.
+const maxCheckedResponse = 1 << 20
+
+// Returns declares the type of the JSON body answered with status for all
+// methods registered on the builder after calling Returns. value is an
+// example of the type, typically its zero value, or nil for responses
+// without a body. With CheckResponses enabled, responses are validated
+// against the declared types.
+//
+// Example:
+//
+//	router.Path("/users/{id}").
+//	    Returns(http.StatusOK, User{}).
+//	    Returns(http.StatusNotFound, ErrorBody{}).
+//	    Get(getUser)
+func (builder *PathBuilder) Returns(status int, value any) *PathBuilder {
+	responses := maps.Clone(builder.responses)
+	if responses == nil {
+		responses = make(map[int]reflect.Type)
+	}
+	responses[status] = reflect.TypeOf(value)
+	builder.responses = responses
+	return builder
+}
+
+// CheckResponses validates the responses of routes declaring their response
+// types with PathBuilder.Returns: the status code must be declared, and a
+// JSON body must decode into the declared type without unknown fields and
+// contain every field not marked omitempty. Mismatches are passed to
+// CheckResponsesConfig.OnMismatch, logged by default, and the most recent
+// ones are listed by Router.Diagnose; the response itself is sent
+// unchanged. This catches drift between the documented and the actual
+// responses; enable it in development and tests only, since every checked
+// response is buffered and decoded. CheckResponses returns the Router to
+// support chaining.
+//
+// Example:
+//
+//	router := routerx.New().CheckResponses(routerx.CheckResponsesConfig{
+//	    OnMismatch: func(request *http.Request, mismatch error) { t.Error(mismatch) },
+//	})
+func (router *Router) CheckResponses(configs ...CheckResponsesConfig) *Router {
+	router.checkResponses = true
+	if len(configs) > 0 {
+		router.responseMismatches.onMismatch = configs[0].OnMismatch
+	}
+	return router
+}
+
+// CheckResponsesConfig configures Router.CheckResponses.
+type CheckResponsesConfig struct {
+	// OnMismatch is called from the goroutine serving the request with
+	// every response that does not match its declaration, e.g. to fail a
+	// test. Defaults to logging the mismatch.
+	OnMismatch func(request *http.Request, mismatch error)
+}
+
+// maxResponseMismatches is how many recent mismatches Diagnose lists.
+const maxResponseMismatches = 100
+
+// responseMismatches collects the mismatches found by CheckResponses while
+// requests are served, apart from the registration problems, which are
+// only written before serving starts.
+type responseMismatches struct {
+	onMismatch func(request *http.Request, mismatch error)
+
+	mutex  sync.Mutex
+	recent []string
+}
+
+func (mismatches *responseMismatches) report(request *http.Request, mismatch error) {
+	mismatches.mutex.Lock()
+	if len(mismatches.recent) == maxResponseMismatches {
+		mismatches.recent = slices.Delete(mismatches.recent, 0, 1)
+	}
+	mismatches.recent = append(mismatches.recent, "routerx: "+mismatch.Error())
+	mismatches.mutex.Unlock()
+	if mismatches.onMismatch != nil {
+		mismatches.onMismatch(request, mismatch)
+		return
+	}
+	log.Print("warning: routerx: " + mismatch.Error())
+}
+
+// list returns the recent mismatches, oldest first.
+func (mismatches *responseMismatches) list() []string {
+	mismatches.mutex.Lock()
+	defer mismatches.mutex.Unlock()
+	return slices.Clone(mismatches.recent)
+}
+
+// checkResponse wraps handler to validate its responses against the declared
+// types while CheckResponses is enabled.
+func (router *Router) checkResponse(handler http.Handler, method string, path string, responses map[int]reflect.Type) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if !router.checkResponses {
+			handler.ServeHTTP(responseWriter, request)
+			return
+		}
+		recorder := &responseRecorder{ResponseWriter: responseWriter}
+		handler.ServeHTTP(recorder, request)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		expected, declared := responses[recorder.status]
+		if !declared {
+			router.responseMismatches.report(request, fmt.Errorf("%s %s answered status %d, which is not declared with Returns (declared: %v)",
+				method, path, recorder.status, slices.Sorted(maps.Keys(responses))))
+			return
+		}
+		if recorder.overflow || !isJSONContentType(responseWriter.Header().Get("Content-Type")) && recorder.body.Len() > 0 {
+			return
+		}
+		if err := checkResponseBody(recorder.body.Bytes(), expected); err != nil {
+			router.responseMismatches.report(request, fmt.Errorf("%s %s answered status %d with a body that does not match %v: %w",
+				method, path, recorder.status, expected, err))
+		}
+	})
+}
+
+// checkResponseBody reports how body deviates from the JSON encoding of
+// expected, a nil expected type standing for an empty body.
+func checkResponseBody(body []byte, expected reflect.Type) error {
+	body = bytes.TrimSpace(body)
+	if expected == nil {
+		if len(body) > 0 {
+			return fmt.Errorf("expected no body")
+		}
+		return nil
+	}
+	if len(body) == 0 {
+		return fmt.Errorf("expected a body")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(reflect.New(expected).Interface()); err != nil {
+		return err
+	}
+
+	structType := expected
+	for structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil
+	}
+	var missing []string
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || strings.Contains(options, "omitempty") || strings.Contains(options, "omitzero") {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, found := object[name]; !found {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing fields %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// responseRecorder passes a response through while keeping its status code
+// and a copy of its body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (recorder *responseRecorder) WriteHeader(status int) {
+	if recorder.status == 0 && !(status >= 100 && status < 200 && status != http.StatusSwitchingProtocols) {
+		recorder.status = status
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	if recorder.body.Len()+len(data) > maxCheckedResponse {
+		recorder.overflow = true
+	} else if !recorder.overflow {
+		recorder.body.Write(data)
+	}
+	return recorder.ResponseWriter.Write(data)
+}
+
+func (recorder *responseRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
@@ -0,0 +1,43 @@
+package routerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheckResponsesConcurrentMismatches(t *testing.T) {
+	var mismatches atomic.Int32
+	router := New().Strict().CheckResponses(CheckResponsesConfig{
+		OnMismatch: func(request *http.Request, mismatch error) { mismatches.Add(1) },
+	})
+	router.Path("/users").Returns(http.StatusOK, []string{}).Get(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusTeapot)
+	})
+
+	const requests = 2 * maxResponseMismatches
+	var waitGroup sync.WaitGroup
+	for range requests {
+		waitGroup.Go(func() {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users", nil))
+			if recorder.Code != http.StatusTeapot {
+				t.Errorf("status = %d, want %d", recorder.Code, http.StatusTeapot)
+			}
+		})
+	}
+	waitGroup.Wait()
+
+	if got := mismatches.Load(); got != requests {
+		t.Errorf("OnMismatch called %d times, want %d", got, requests)
+	}
+	report := router.Diagnose()
+	if len(report.ResponseMismatches) != maxResponseMismatches {
+		t.Errorf("Diagnose lists %d mismatches, want %d", len(report.ResponseMismatches), maxResponseMismatches)
+	}
+	if len(report.Problems) != 0 {
+		t.Errorf("mismatches reported as registration problems: %v", report.Problems)
+	}
+}
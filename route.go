@@ -0,0 +1,365 @@
+package routerx
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// route is a single registered method and path.
+type route struct {
+	method      string
+	path        string
+	pattern     string
+	name        string
+	wildcards   []string
+	constraints map[string]*regexp.Regexp
+	middlewares []Middleware
+	handler     http.Handler
+	site        string
+
+	// responses maps status codes to the response types declared with
+	// PathBuilder.Returns.
+	responses map[int]reflect.Type
+
+	// doc documents the route for OpenAPI, see PathBuilder.Doc.
+	doc *RouteDoc
+
+	// security lists the requirements declared with PathBuilder.Security.
+	security []SecurityRequirement
+
+	// meta and tags are attached with PathBuilder.Meta and PathBuilder.Tag.
+	meta map[string]string
+	tags []string
+
+	// undocumented leaves the route out of the OpenAPI document, as for the
+	// routes added by DocsUI.
+	undocumented bool
+
+	// preflight marks the OPTIONS routes generated by CORS, which an
+	// explicit OPTIONS registration replaces.
+	preflight bool
+
+	// usage counts the requests served while TrackUsage is enabled.
+	usage routeUsage
+
+	// timeout overrides the duration of the Timeout middleware, see
+	// PathBuilder.Timeout.
+	timeout time.Duration
+}
+
+// routeShape groups the routes whose patterns only differ in wildcard names
+// and constraints, e.g. "GET /users/{id:[0-9]+}" and "GET /users/{slug}".
+// http.ServeMux cannot hold both, so the shape is registered once and
+// dispatches to the first route whose constraints match.
+type routeShape struct {
+	wildcards []string
+	routes    []*route
+}
+
+// register is the single entry point through which Router, RouteGroup, and
+// PathBuilder add routes. path may declare regular expression constraints
+// with the {name:regexp} syntax; constraints adds further ones by name. The
+// new route is returned.
+func (router *Router) register(method string, path string, handler http.Handler, middlewares []Middleware, constraints map[string]*regexp.Regexp) *route {
+	return router.insert(buildRoute(method, router.expandMatchers(path), handler, middlewares, constraints))
+}
+
+// buildRoute parses path and prepares a route without registering it.
+func buildRoute(method string, path string, handler http.Handler, middlewares []Middleware, constraints map[string]*regexp.Regexp) *route {
+	muxPath, wildcards, pathConstraints := parseRoutePath(path)
+	for name, expression := range constraints {
+		if pathConstraints == nil {
+			pathConstraints = make(map[string]*regexp.Regexp)
+		}
+		pathConstraints[name] = expression
+	}
+	for name := range pathConstraints {
+		if !slices.Contains(wildcards, name) {
+			panic(fmt.Sprintf("routerx: constraint on unknown parameter %q in %q", name, path))
+		}
+	}
+
+	pattern := muxPath
+	if method != "" {
+		pattern = method + " " + muxPath
+	}
+	return &route{
+		method:      method,
+		path:        muxPath,
+		pattern:     pattern,
+		wildcards:   wildcards,
+		constraints: pathConstraints,
+		middlewares: middlewares,
+		handler:     applyMiddlewares(handler, middlewares),
+		site:        callSite(),
+	}
+}
+
+// insert adds a built route to the ServeMux and the route table.
+func (router *Router) insert(newRoute *route) *route {
+	router.seal()
+	router.checkOverlaps(newRoute)
+
+	key := newRoute.method + " " + routeShapeOf(newRoute.path)
+	shape, found := router.shapes[key]
+	if !found {
+		shape = &routeShape{wildcards: newRoute.wildcards}
+		router.shapes[key] = shape
+		router.mux.Handle(newRoute.pattern, router.dispatch(shape))
+	}
+	if replaced := shape.add(newRoute); replaced != nil {
+		router.routes = slices.DeleteFunc(router.routes, func(existing *route) bool { return existing == replaced })
+	}
+	router.routes = append(router.routes, newRoute)
+	return newRoute
+}
+
+// add inserts a route into the shape. Constrained routes are tried before
+// unconstrained ones so that "/users/{id:[0-9]+}" is not shadowed by
+// "/users/{slug}" regardless of registration order. A generated preflight
+// route is replaced by an explicit one and returned.
+func (shape *routeShape) add(newRoute *route) *route {
+	if len(newRoute.constraints) == 0 {
+		for index, existing := range shape.routes {
+			if len(existing.constraints) > 0 {
+				continue
+			}
+			if existing.preflight {
+				shape.routes[index] = newRoute
+				return existing
+			}
+			panic(fmt.Sprintf("routerx: pattern %q conflicts with %q", newRoute.pattern, existing.pattern))
+		}
+		shape.routes = append(shape.routes, newRoute)
+		return nil
+	}
+	index := 0
+	for index < len(shape.routes) && len(shape.routes[index].constraints) > 0 {
+		index++
+	}
+	shape.routes = append(shape.routes[:index], append([]*route{newRoute}, shape.routes[index:]...)...)
+	return nil
+}
+
+// dispatch returns the handler registered on the ServeMux for a shape.
+func (router *Router) dispatch(shape *routeShape) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if len(shape.routes) == 1 && len(shape.routes[0].constraints) == 0 {
+			router.serveRoute(shape.routes[0], responseWriter, request)
+			return
+		}
+		// The ServeMux named the wildcards after the first registered route;
+		// read them by position and expose them under each route's names.
+		values := make([]string, len(shape.wildcards))
+		for index, name := range shape.wildcards {
+			values[index] = request.PathValue(name)
+		}
+		for _, candidate := range shape.routes {
+			if !candidate.matches(values) {
+				continue
+			}
+			for index, name := range candidate.wildcards {
+				request.SetPathValue(name, values[index])
+			}
+			request.Pattern = candidate.pattern
+			router.serveRoute(candidate, responseWriter, request)
+			return
+		}
+		router.serveNotFound(responseWriter, request)
+	})
+}
+
+// serveRoute serves request with a matched route, exposing the route to
+// GetRouteInfo and the router's error handler to HandleError, applying the
+// route's timeout override, and counting the request while TrackUsage is
+// enabled.
+func (router *Router) serveRoute(matched *route, responseWriter http.ResponseWriter, request *http.Request) {
+	request = withRoute(request, matched)
+	if router.errorHandler != nil {
+		request = request.WithContext(context.WithValue(request.Context(), errorHandlerContextKey{}, router.errorHandler))
+	}
+	if matched.timeout != 0 {
+		request = request.WithContext(context.WithValue(request.Context(), timeoutContextKey{}, matched.timeout))
+	}
+	if !router.trackUsage {
+		matched.handler.ServeHTTP(responseWriter, request)
+		return
+	}
+	writer := &statusWriter{ResponseWriter: responseWriter}
+	defer func() {
+		matched.usage.record(writer.status(), GetClock(request).Now())
+	}()
+	matched.handler.ServeHTTP(writer, request)
+}
+
+// matches reports whether the wildcard values satisfy the route constraints.
+func (candidate *route) matches(values []string) bool {
+	for index, name := range candidate.wildcards {
+		if expression, found := candidate.constraints[name]; found && !expression.MatchString(values[index]) {
+			return false
+		}
+	}
+	return true
+}
+
+// serveNotFound answers a request that matched no route.
+func (router *Router) serveNotFound(responseWriter http.ResponseWriter, request *http.Request) {
+	for _, spa := range router.spas {
+		if spa.serve(router, responseWriter, request) {
+			return
+		}
+	}
+	if router.notFound == nil {
+		http.NotFound(responseWriter, request)
+		return
+	}
+	applyMiddlewares(router.notFound, router.middlewares).ServeHTTP(responseWriter, request)
+}
+
+// Where constrains the named path parameter to values fully matching the
+// regular expression for all methods registered on the builder after calling
+// Where. Requests whose parameter does not match fall through to other routes
+// with the same shape or to the NotFound handler. Where panics if expression
+// is not a valid regular expression.
+//
+// The same constraint can be written inline as "/users/{id:[0-9]+}".
+//
+// Example:
+//
+//	router.Path("/users/{id}").Where("id", "[0-9]+").Get(getUserByID)
+//	router.Path("/users/{name}").Get(getUserByName)
+func (builder *PathBuilder) Where(name string, expression string) *PathBuilder {
+	constraints := maps.Clone(builder.constraints)
+	if constraints == nil {
+		constraints = make(map[string]*regexp.Regexp)
+	}
+	constraints[name] = regexp.MustCompile("^(?:" + expression + ")$")
+	builder.constraints = constraints
+	return builder
+}
+
+// Name names the path for all methods registered on the builder after calling
+// Name. Names identify routes in the route table, e.g. for the functions
+// emitted by GenerateRoutes.
+//
+// Example:
+//
+//	router.Path("/users/{id}").Name("UserShow").Get(showUser).Put(updateUser)
+func (builder *PathBuilder) Name(name string) *PathBuilder {
+	builder.name = name
+	return builder
+}
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	// Method is the HTTP method, or "" for routes matching every method
+	// such as those added by Mount.
+	Method string `json:"method"`
+
+	// Path is the ServeMux path of the route, e.g. "/users/{id}", without
+	// regular expression constraints.
+	Path string `json:"path"`
+
+	// Name is the name given with PathBuilder.Name, or "".
+	Name string `json:"name,omitempty"`
+
+	// Meta and Tags are attached with PathBuilder.Meta and PathBuilder.Tag.
+	Meta map[string]string `json:"meta,omitempty"`
+	Tags []string          `json:"tags,omitempty"`
+}
+
+// Routes returns the registered routes in registration order. The preflight
+// routes generated for CORS are omitted.
+func (router *Router) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(router.routes))
+	for _, registered := range router.routes {
+		if registered.preflight {
+			continue
+		}
+		routes = append(routes, registered.info())
+	}
+	return routes
+}
+
+// parseRoutePath strips {name:regexp} constraints from path and returns the
+// ServeMux compatible path, the wildcard names in order, and the compiled
+// constraints.
+func parseRoutePath(path string) (string, []string, map[string]*regexp.Regexp) {
+	var builder strings.Builder
+	var wildcards []string
+	var constraints map[string]*regexp.Regexp
+	for index := 0; index < len(path); index++ {
+		if path[index] != '{' {
+			builder.WriteByte(path[index])
+			continue
+		}
+		end := matchingBrace(path, index)
+		if end < 0 {
+			panic(fmt.Sprintf("routerx: unbalanced braces in %q", path))
+		}
+		token := path[index+1 : end]
+		index = end
+
+		name, expression, constrained := strings.Cut(token, ":")
+		baseName := strings.TrimSuffix(name, "...")
+		if baseName != "$" {
+			wildcards = append(wildcards, baseName)
+		}
+		if constrained {
+			if constraints == nil {
+				constraints = make(map[string]*regexp.Regexp)
+			}
+			constraints[baseName] = regexp.MustCompile("^(?:" + expression + ")$")
+		}
+		builder.WriteString("{" + name + "}")
+	}
+	return builder.String(), wildcards, constraints
+}
+
+// matchingBrace returns the index of the brace closing the one at start,
+// honouring nested braces such as {id:[0-9]{3}}.
+func matchingBrace(path string, start int) int {
+	depth := 0
+	for index := start; index < len(path); index++ {
+		switch path[index] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return index
+			}
+		}
+	}
+	return -1
+}
+
+// routeShapeOf replaces wildcard names in a ServeMux path with placeholders.
+func routeShapeOf(muxPath string) string {
+	var builder strings.Builder
+	for index := 0; index < len(muxPath); index++ {
+		if muxPath[index] != '{' {
+			builder.WriteByte(muxPath[index])
+			continue
+		}
+		end := strings.IndexByte(muxPath[index:], '}') + index
+		token := muxPath[index+1 : end]
+		switch {
+		case token == "$":
+			builder.WriteString("{$}")
+		case strings.HasSuffix(token, "..."):
+			builder.WriteString("{...}")
+		default:
+			builder.WriteString("{}")
+		}
+		index = end
+	}
+	return builder.String()
+}
@@ -0,0 +1,86 @@
+package routerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// constraintRoute is a route registered by TestRouteConstraints, answering
+// its name and path values, e.g. "id:42".
+type constraintRoute struct {
+	path  string
+	where [2]string
+}
+
+func TestRouteConstraints(t *testing.T) {
+	numericID := constraintRoute{path: "/users/{id:[0-9]+}"}
+	slug := constraintRoute{path: "/users/{slug}"}
+	newUser := constraintRoute{path: "/users/new"}
+	whereID := constraintRoute{path: "/users/{id}", where: [2]string{"id", "[0-9]+"}}
+	uuid := constraintRoute{path: "/users/{uuid:[0-9a-f]{8}-[0-9a-f-]+}"}
+	hex := constraintRoute{path: "/users/{hex:[0-9a-f]+}"}
+
+	tests := []struct {
+		name   string
+		routes []constraintRoute
+		path   string
+		want   string
+	}{
+		{"constrained first, matching", []constraintRoute{numericID, slug}, "/users/42", "id:42"},
+		{"constrained first, not matching", []constraintRoute{numericID, slug}, "/users/bob", "slug:bob"},
+		{"unconstrained first, matching", []constraintRoute{slug, numericID}, "/users/42", "id:42"},
+		{"unconstrained first, not matching", []constraintRoute{slug, numericID}, "/users/bob", "slug:bob"},
+		{"Where, matching", []constraintRoute{slug, whereID}, "/users/42", "id:42"},
+		{"Where, not matching", []constraintRoute{slug, whereID}, "/users/bob", "slug:bob"},
+		{"static route beside constrained", []constraintRoute{numericID, newUser}, "/users/new", "new"},
+		{"constrained beside static route", []constraintRoute{newUser, numericID}, "/users/7", "id:7"},
+		{"constrained only, not matching", []constraintRoute{numericID}, "/users/bob", "404"},
+		{"constraint is anchored", []constraintRoute{numericID}, "/users/4x2", "404"},
+		{"constrained in registration order", []constraintRoute{numericID, hex, slug}, "/users/42", "id:42"},
+		{"constrained in registration order, reversed", []constraintRoute{hex, numericID, slug}, "/users/42", "hex:42"},
+		{"second constrained", []constraintRoute{uuid, hex, slug}, "/users/beef", "hex:beef"},
+		{"later constrained before unconstrained", []constraintRoute{slug, uuid, hex}, "/users/beef", "hex:beef"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := New()
+			for _, registered := range test.routes {
+				_, wildcards, _ := parseRoutePath(registered.path)
+				handler := func(responseWriter http.ResponseWriter, request *http.Request) {
+					if len(wildcards) == 0 {
+						responseWriter.Write([]byte(strings.TrimPrefix(registered.path, "/users/")))
+						return
+					}
+					responseWriter.Write([]byte(wildcards[0] + ":" + request.PathValue(wildcards[0])))
+				}
+				builder := router.Path(registered.path)
+				if registered.where[0] != "" {
+					builder = builder.Where(registered.where[0], registered.where[1])
+				}
+				builder.Get(handler)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.path, nil))
+			got := recorder.Body.String()
+			if recorder.Code == http.StatusNotFound {
+				got = "404"
+			}
+			if got != test.want {
+				t.Errorf("GET %s = %q, want %q", test.path, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRouteConstraintsConflict(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering two unconstrained routes of the same shape did not panic")
+		}
+	}()
+	router := New()
+	router.Get("/users/{id}", func(http.ResponseWriter, *http.Request) {})
+	router.Get("/users/{slug}", func(http.ResponseWriter, *http.Request) {})
+}
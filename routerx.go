@@ -1,8 +1,16 @@
 package routerx
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // Middleware wraps an http.Handler and returns another http.Handler.
@@ -20,42 +28,203 @@ type Middleware func(http.Handler) http.Handler
 //
 // Router implements http.Handler and can be passed directly to http.ListenAndServe.
 type Router struct {
-	mux         *http.ServeMux
-	middlewares []Middleware
+	mux                *http.ServeMux
+	middlewares        []Middleware
+	notFound           http.Handler
+	methodNotAllowed   http.Handler
+	routes             []*route
+	shapes             map[string]*routeShape
+	cors               Middleware
+	spas               []*spaFallback
+	errorHandler       ErrorHandlerFunc
+	strict             bool
+	checkResponses     bool
+	responseMismatches responseMismatches
+	trackUsage         bool
+	draining           atomic.Bool
+	shutdownHooks      []func(ctx context.Context) error
+	problems           []string
+	trailingSlash      TrailingSlashPolicy
+	matchers           map[string]string
+	clock              Clock
+	random             io.Reader
+	readOnly           readOnlyChecks
+	setup              *routerSetup
+	sealedAt           string
 }
 
 // RouteGroup represents a group of routes that share a common path prefix
 // and a shared middleware chain. Nested groups inherit and extend the
 // middleware of their parent groups.
 type RouteGroup struct {
-	mux         *http.ServeMux
+	router      *Router
 	prefix      string
 	middlewares []Middleware
+	cors        Middleware
+	timeout     time.Duration
+	readOnly    *readOnlyChecks
+	sealedAt    string
 }
 
 // PathBuilder provides a fluent API for registering multiple HTTP methods
 // for a single path. It inherits middlewares from the router or group that
 // created it and applies them to each registered handler.
 type PathBuilder struct {
-	mux         *http.ServeMux
+	router      *Router
 	basePath    string
 	middlewares []Middleware
+	constraints map[string]*regexp.Regexp
+	cors        Middleware
+	timeout     time.Duration
+	name        string
+	responses   map[int]reflect.Type
+	doc         *RouteDoc
+	security    []SecurityRequirement
+	meta        map[string]string
+	tags        []string
 }
 
 // New creates a new Router using the standard library http.ServeMux as the
 // underlying multiplexer. The returned Router is empty and ready for route
-// registration.
-func New() *Router {
-	return &Router{
+// registration. Options are applied in order, and a later option replaces
+// what an earlier one configured.
+//
+// Example:
+//
+//	router := routerx.New(
+//	    routerx.WithLogger(slog.NewJSONHandler(os.Stdout, nil)),
+//	    routerx.WithMaxBody(1<<20),
+//	    routerx.WithTrailingSlashPolicy(routerx.TrailingSlashRedirect),
+//	)
+func New(options ...Option) *Router {
+	router := &Router{
 		mux:         http.NewServeMux(),
 		middlewares: nil,
+		shapes:      make(map[string]*routeShape),
+		setup:       &routerSetup{},
+	}
+	for _, option := range options {
+		option(router)
 	}
+	router.setup.install(router)
+	router.setup = nil
+	return router
 }
 
 // ServeHTTP makes Router implement http.Handler. Incoming requests are passed
 // directly to the underlying http.ServeMux after all routes have been registered.
+// Requests that match no route are answered by the NotFound handler, and
+// requests whose path matches but whose method does not are answered by the
+// MethodNotAllowed handler, when those are configured.
 func (router *Router) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
-	router.mux.ServeHTTP(responseWriter, request)
+	if router.clock != nil {
+		request = SetClock(request, router.clock)
+	}
+	if router.random != nil {
+		request = SetRandom(request, router.random)
+	}
+	if router.readOnly.rejects(responseWriter, request) {
+		return
+	}
+	if router.trailingSlash != TrailingSlashStrict {
+		var redirected bool
+		if request, redirected = router.trailingSlashRequest(responseWriter, request); redirected {
+			return
+		}
+	}
+	if router.notFound == nil && router.methodNotAllowed == nil && len(router.spas) == 0 {
+		router.mux.ServeHTTP(responseWriter, request)
+		return
+	}
+	if _, pattern := router.mux.Handler(request); pattern != "" {
+		router.mux.ServeHTTP(responseWriter, request)
+		return
+	}
+	if allowed := router.allowedMethods(request); len(allowed) > 0 {
+		responseWriter.Header().Set("Allow", strings.Join(allowed, ", "))
+		if router.methodNotAllowed == nil {
+			http.Error(responseWriter, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		applyMiddlewares(router.methodNotAllowed, router.middlewares).ServeHTTP(responseWriter, request)
+		return
+	}
+	router.serveNotFound(responseWriter, request)
+}
+
+// NotFound sets the handler used for requests that match no registered route,
+// replacing the plain text 404 of http.ServeMux. The router's middleware
+// chain is applied to the handler, including middlewares added with Use after
+// NotFound was called. NotFound returns the Router to support chaining.
+//
+// Example:
+//
+//	router.NotFound(func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    responseWriter.Header().Set("Content-Type", "application/json")
+//	    responseWriter.WriteHeader(http.StatusNotFound)
+//	    io.WriteString(responseWriter, `{"error":"route not found"}`)
+//	})
+func (router *Router) NotFound(handler http.HandlerFunc) *Router {
+	router.notFound = handler
+	return router
+}
+
+// MethodNotAllowed sets the handler used for requests whose path matches a
+// registered route but whose method does not. The Allow header listing the
+// registered methods is set before the handler runs, and the router's
+// middleware chain is applied to it. MethodNotAllowed returns the Router to
+// support chaining.
+//
+// Example:
+//
+//	router.MethodNotAllowed(func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    responseWriter.Header().Set("Content-Type", "application/json")
+//	    responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+//	    io.WriteString(responseWriter, `{"error":"method not allowed"}`)
+//	})
+func (router *Router) MethodNotAllowed(handler http.HandlerFunc) *Router {
+	router.methodNotAllowed = handler
+	return router
+}
+
+// standardMethods lists the methods probed when looking for the routes that
+// match a path regardless of the request method.
+var standardMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// allowedMethods returns the methods for which a route matching the request
+// path is registered.
+func (router *Router) allowedMethods(request *http.Request) []string {
+	var methods []string
+	probe := *request
+	for _, method := range router.routeMethods() {
+		probe.Method = method
+		if _, pattern := router.mux.Handler(&probe); pattern != "" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// routeMethods returns the standard methods followed by the custom methods
+// registered with Handle.
+func (router *Router) routeMethods() []string {
+	methods := standardMethods
+	for _, registered := range router.routes {
+		if registered.method != "" && !slices.Contains(methods, registered.method) {
+			methods = append(slices.Clip(methods), registered.method)
+		}
+	}
+	return methods
 }
 
 // Use appends one or more Middleware instances to the Router.
@@ -65,8 +234,9 @@ func (router *Router) ServeHTTP(responseWriter http.ResponseWriter, request *htt
 // Example:
 //
 //	router := routerx.New().
-//	    Use(LoggingMiddleware, RecoveryMiddleware)
+//	    Use(routerx.Recovery(), routerx.Logger(slog.Default().Handler()))
 func (router *Router) Use(middlewares ...Middleware) *Router {
+	router.checkUse("router", router.sealedAt)
 	router.middlewares = append(router.middlewares, middlewares...)
 	return router
 }
@@ -80,10 +250,14 @@ func (router *Router) Use(middlewares ...Middleware) *Router {
 //	api := router.Group("/api")
 //	api.Get("/status", statusHandler) // matches GET /api/status
 func (router *Router) Group(prefix string) *RouteGroup {
+	router.seal()
+	checks := &readOnlyChecks{}
 	return &RouteGroup{
-		mux:         router.mux,
+		router:      router,
 		prefix:      cleanPath(prefix),
-		middlewares: copyMiddlewares(router.middlewares),
+		middlewares: append(copyMiddlewares(router.middlewares), readOnly(checks)),
+		cors:        router.cors,
+		readOnly:    checks,
 	}
 }
 
@@ -97,11 +271,13 @@ func (router *Router) Group(prefix string) *RouteGroup {
 //	    Get(listUsers).
 //	    Post(createUser)
 func (router *Router) Path(path string) *PathBuilder {
+	router.seal()
 	fullPath := cleanPath(path)
 	return &PathBuilder{
-		mux:         router.mux,
+		router:      router,
 		basePath:    fullPath,
 		middlewares: copyMiddlewares(router.middlewares),
+		cors:        router.cors,
 	}
 }
 
@@ -142,10 +318,32 @@ func (router *Router) Trace(path string, handler http.HandlerFunc) {
 	router.handle("TRACE", cleanPath(path), handler, router.middlewares)
 }
 
-func (router *Router) handle(method string, path string, handler http.HandlerFunc, middlewares []Middleware) {
-	pattern := method + " " + path
-	finalHandler := applyMiddlewares(http.HandlerFunc(handler), middlewares)
-	router.mux.Handle(pattern, finalHandler)
+// Any registers a handler for every HTTP method at the specified path.
+// Routes registered for a specific method on the same path take precedence.
+//
+// Example:
+//
+//	router.Get("/files/{path...}", downloadFile)
+//	router.Any("/files/{path...}", webdavHandler) // every other method
+func (router *Router) Any(path string, handler http.HandlerFunc) {
+	router.handle("", cleanPath(path), handler, router.middlewares)
+}
+
+// Handle registers handler for method at the specified path. Use it for
+// less common or custom methods, such as PROPFIND or PURGE. Handle panics
+// if method is not a valid HTTP method token; use Any to match every
+// method.
+//
+// Example:
+//
+//	router.Handle("PURGE", "/cache/{key}", http.HandlerFunc(purgeCache))
+func (router *Router) Handle(method string, path string, handler http.Handler) {
+	router.handle(checkMethod(method), cleanPath(path), handler, router.middlewares)
+}
+
+func (router *Router) handle(method string, path string, handler http.Handler, middlewares []Middleware) {
+	router.register(method, path, handler, withCORS(router.cors, middlewares), nil)
+	router.registerPreflight(method, path, router.cors)
 }
 
 // Use appends one or more Middleware instances to the RouteGroup.
@@ -153,6 +351,7 @@ func (router *Router) handle(method string, path string, handler http.HandlerFun
 // but before any PathBuilder-specific middlewares. Use returns the group
 // to support chaining.
 func (group *RouteGroup) Use(middlewares ...Middleware) *RouteGroup {
+	group.router.checkUse("group "+group.prefix, group.sealedAt)
 	group.middlewares = append(group.middlewares, middlewares...)
 	return group
 }
@@ -166,10 +365,15 @@ func (group *RouteGroup) Use(middlewares ...Middleware) *RouteGroup {
 //	v1 := api.Group("/v1")
 //	v1.Get("/users", handler) // matches GET /api/v1/users
 func (group *RouteGroup) Group(prefix string) *RouteGroup {
+	group.seal()
+	checks := &readOnlyChecks{}
 	return &RouteGroup{
-		mux:         group.mux,
+		router:      group.router,
 		prefix:      joinPath(group.prefix, prefix),
-		middlewares: copyMiddlewares(group.middlewares),
+		middlewares: append(copyMiddlewares(group.middlewares), readOnly(checks)),
+		cors:        group.cors,
+		timeout:     group.timeout,
+		readOnly:    checks,
 	}
 }
 
@@ -183,11 +387,14 @@ func (group *RouteGroup) Group(prefix string) *RouteGroup {
 //	    Get(listUsers).
 //	    Post(createUser)
 func (group *RouteGroup) Path(path string) *PathBuilder {
+	group.seal()
 	fullPath := joinPath(group.prefix, path)
 	return &PathBuilder{
-		mux:         group.mux,
+		router:      group.router,
 		basePath:    fullPath,
 		middlewares: copyMiddlewares(group.middlewares),
+		cors:        group.cors,
+		timeout:     group.timeout,
 	}
 }
 
@@ -226,11 +433,40 @@ func (group *RouteGroup) Trace(path string, handler http.HandlerFunc) {
 	group.handle("TRACE", path, handler)
 }
 
-func (group *RouteGroup) handle(method string, path string, handler http.HandlerFunc) {
+// Any registers a handler for every HTTP method at the specified path under
+// the group's prefix, see Router.Any.
+func (group *RouteGroup) Any(path string, handler http.HandlerFunc) {
+	group.handle("", path, handler)
+}
+
+// Handle registers handler for method at the specified path under the
+// group's prefix, see Router.Handle.
+func (group *RouteGroup) Handle(method string, path string, handler http.Handler) {
+	group.handle(checkMethod(method), path, handler)
+}
+
+func (group *RouteGroup) handle(method string, path string, handler http.Handler) {
+	group.seal()
 	fullPath := joinPath(group.prefix, path)
-	pattern := method + " " + fullPath
-	finalHandler := applyMiddlewares(http.HandlerFunc(handler), group.middlewares)
-	group.mux.Handle(pattern, finalHandler)
+	registered := group.router.register(method, fullPath, handler, withCORS(group.cors, group.middlewares), nil)
+	registered.timeout = group.timeout
+	group.router.registerPreflight(method, fullPath, group.cors)
+}
+
+// Use appends one or more Middleware instances to the PathBuilder. They are
+// applied after the Router and RouteGroup middlewares, and only to the
+// methods registered on this builder after calling Use. Use returns the
+// builder to support chaining.
+//
+// Example:
+//
+//	router.Path("/uploads").
+//	    Get(listUploads).
+//	    Use(routerx.ExpectContinue(routerx.ContinueConfig{MaxContentLength: 1 << 30})).
+//	    Post(createUpload)
+func (builder *PathBuilder) Use(middlewares ...Middleware) *PathBuilder {
+	builder.middlewares = append(builder.middlewares, middlewares...)
+	return builder
 }
 
 func (builder *PathBuilder) Get(handler http.HandlerFunc) *PathBuilder {
@@ -253,10 +489,20 @@ func (builder *PathBuilder) Delete(handler http.HandlerFunc) *PathBuilder {
 	return builder
 }
 
-func (builder *PathBuilder) register(method string, handler http.HandlerFunc) {
-	pattern := method + " " + builder.basePath
-	finalHandler := applyMiddlewares(http.HandlerFunc(handler), builder.middlewares)
-	builder.mux.Handle(pattern, finalHandler)
+func (builder *PathBuilder) register(method string, handler http.Handler) {
+	wrapped := handler
+	if len(builder.responses) > 0 {
+		wrapped = builder.router.checkResponse(handler, method, builder.basePath, builder.responses)
+	}
+	registered := builder.router.register(method, builder.basePath, wrapped, withCORS(builder.cors, builder.middlewares), builder.constraints)
+	registered.name = builder.name
+	registered.responses = builder.responses
+	registered.doc = builder.doc
+	registered.timeout = builder.timeout
+	registered.security = builder.security
+	registered.meta = builder.meta
+	registered.tags = builder.tags
+	builder.router.registerPreflight(method, builder.basePath, builder.cors)
 }
 func (builder *PathBuilder) Head(handler http.HandlerFunc) *PathBuilder {
 	builder.register("HEAD", handler)
@@ -283,6 +529,41 @@ func (builder *PathBuilder) Trace(handler http.HandlerFunc) *PathBuilder {
 	return builder
 }
 
+// Any registers a handler for every HTTP method on the builder's path.
+// Methods registered on the same path with their own handler take
+// precedence.
+//
+// Example:
+//
+//	router.Path("/graphql").
+//	    Get(playground).
+//	    Any(graphqlHandler)
+func (builder *PathBuilder) Any(handler http.HandlerFunc) *PathBuilder {
+	builder.register("", handler)
+	return builder
+}
+
+// Handle registers handler for method on the builder's path, see
+// Router.Handle.
+func (builder *PathBuilder) Handle(method string, handler http.Handler) *PathBuilder {
+	builder.register(checkMethod(method), handler)
+	return builder
+}
+
+// checkMethod returns method, panicking if it is not a valid HTTP method
+// token.
+func checkMethod(method string) string {
+	if method == "" {
+		panic("routerx: Handle needs a method, use Any to match every method")
+	}
+	for _, char := range method {
+		if char <= ' ' || char > '~' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, char) {
+			panic(fmt.Sprintf("routerx: invalid HTTP method %q", method))
+		}
+	}
+	return method
+}
+
 // applyMiddlewares applies a slice of middlewares to the provided handler.
 // Middlewares are applied in the order they were added: the first middleware
 // in the slice becomes the outermost wrapper.
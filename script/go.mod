@@ -0,0 +1,12 @@
+module github.com/Mark-Bazylev/routerx/script
+
+go 1.25.1
+
+require (
+	github.com/Mark-Bazylev/routerx v0.0.0-00010101000000-000000000000
+	go.starlark.net v0.0.0-20260908191801-89a6a09411d5
+)
+
+require golang.org/x/sys v0.42.0 // indirect
+
+replace github.com/Mark-Bazylev/routerx => ../
@@ -0,0 +1,322 @@
+// Package script defines small HTTP handlers in Starlark, a sandboxed Python
+// dialect, so that operators of routerx gateways can tweak responses from
+// configuration without recompiling the server.
+//
+// A script must define a function handle(request) that returns either a
+// string, which is sent as a 200 text/plain response, or a dict with the
+// optional keys "status", "headers", and "body". The request argument is a
+// struct with the fields method, path, query, headers, params, and body.
+// Scripts can use the json module to encode and decode JSON.
+//
+//	def handle(request):
+//	    return {
+//	        "status": 200,
+//	        "headers": {"Content-Type": "application/json"},
+//	        "body": json.encode({"hello": request.params["name"]}),
+//	    }
+//
+// Scripts have no access to the file system or the network. Each request runs
+// in a fresh Starlark thread that is cancelled when Limits.Timeout elapses or
+// after Limits.MaxSteps computation steps, which also bounds the memory a
+// script can allocate in practice.
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// Limits bounds the resources a single script invocation may use.
+type Limits struct {
+	// Timeout cancels a script that runs longer. Defaults to 100ms.
+	Timeout time.Duration `json:"timeout"`
+
+	// MaxSteps cancels a script after this many Starlark computation steps.
+	// Defaults to 1,000,000.
+	MaxSteps uint64 `json:"max_steps"`
+
+	// MaxBodyBytes caps the request body exposed to the script and the
+	// response body it may produce. Defaults to 1 MiB.
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+}
+
+func (limits Limits) withDefaults() Limits {
+	if limits.Timeout <= 0 {
+		limits.Timeout = 100 * time.Millisecond
+	}
+	if limits.MaxSteps == 0 {
+		limits.MaxSteps = 1_000_000
+	}
+	if limits.MaxBodyBytes <= 0 {
+		limits.MaxBodyBytes = 1 << 20
+	}
+	return limits
+}
+
+// Route describes one scripted route in a Config. Exactly one of Source and
+// File must be set; relative files are resolved against the directory of the
+// configuration file.
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Source string `json:"source,omitempty"`
+	File   string `json:"file,omitempty"`
+}
+
+// Config is the JSON document read by Load and LoadFile.
+//
+//	{
+//	    "limits": {"timeout": 50000000, "max_steps": 100000},
+//	    "routes": [
+//	        {"method": "GET", "path": "/hello/{name}", "file": "hello.star"}
+//	    ]
+//	}
+type Config struct {
+	Limits Limits  `json:"limits"`
+	Routes []Route `json:"routes"`
+
+	baseDirectory string
+}
+
+// Load decodes a Config from reader.
+func Load(reader io.Reader) (*Config, error) {
+	config := &Config{}
+	if err := json.NewDecoder(reader).Decode(config); err != nil {
+		return nil, fmt.Errorf("script: decode config: %w", err)
+	}
+	return config, nil
+}
+
+// LoadFile reads a Config from the named file.
+func LoadFile(name string) (*Config, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	config, err := Load(file)
+	if err != nil {
+		return nil, err
+	}
+	config.baseDirectory = filepath.Dir(name)
+	return config, nil
+}
+
+// Registrar is implemented by *routerx.Router and *routerx.RouteGroup.
+type Registrar interface {
+	Get(path string, handler http.HandlerFunc)
+	Post(path string, handler http.HandlerFunc)
+	Put(path string, handler http.HandlerFunc)
+	Patch(path string, handler http.HandlerFunc)
+	Delete(path string, handler http.HandlerFunc)
+}
+
+// Register compiles every route of config and registers it on registrar.
+//
+// Example:
+//
+//	config, err := script.LoadFile("scripts.json")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := script.Register(router.Group("/edge"), config); err != nil {
+//	    log.Fatal(err)
+//	}
+func Register(registrar Registrar, config *Config) error {
+	for _, route := range config.Routes {
+		source := route.Source
+		name := route.Method + " " + route.Path
+		if route.File != "" {
+			filename := route.File
+			if !filepath.IsAbs(filename) {
+				filename = filepath.Join(config.baseDirectory, filename)
+			}
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				return fmt.Errorf("script: %s: %w", name, err)
+			}
+			source, name = string(content), filename
+		}
+		handler, err := Handler(name, source, config.Limits)
+		if err != nil {
+			return err
+		}
+		switch strings.ToUpper(route.Method) {
+		case http.MethodGet:
+			registrar.Get(route.Path, handler)
+		case http.MethodPost:
+			registrar.Post(route.Path, handler)
+		case http.MethodPut:
+			registrar.Put(route.Path, handler)
+		case http.MethodPatch:
+			registrar.Patch(route.Path, handler)
+		case http.MethodDelete:
+			registrar.Delete(route.Path, handler)
+		default:
+			return fmt.Errorf("script: %s: unsupported method %q", name, route.Method)
+		}
+	}
+	return nil
+}
+
+// predeclared holds the modules available to every script.
+var predeclared = starlark.StringDict{
+	"json":   starlarkjson.Module,
+	"struct": starlark.NewBuiltin("struct", starlarkstruct.Make),
+}
+
+// Handler compiles source, which must define handle(request), and returns an
+// http.HandlerFunc running it under limits. name is used in error messages.
+func Handler(name string, source string, limits Limits) (http.HandlerFunc, error) {
+	limits = limits.withDefaults()
+
+	thread := &starlark.Thread{Name: name}
+	thread.SetMaxExecutionSteps(limits.MaxSteps)
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, name, source, predeclared)
+	if err != nil {
+		return nil, fmt.Errorf("script: %w", err)
+	}
+	handle, ok := globals["handle"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("script: %s does not define handle(request)", name)
+	}
+	globals.Freeze()
+
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(request.Body, limits.MaxBodyBytes+1))
+		if err != nil {
+			http.Error(responseWriter, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		if int64(len(body)) > limits.MaxBodyBytes {
+			http.Error(responseWriter, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(request.Context(), limits.Timeout)
+		defer cancel()
+		thread := &starlark.Thread{Name: name}
+		thread.SetMaxExecutionSteps(limits.MaxSteps)
+		stop := context.AfterFunc(ctx, func() { thread.Cancel("timeout") })
+		defer stop()
+
+		result, err := starlark.Call(thread, handle, starlark.Tuple{requestValue(request, body)}, nil)
+		if err != nil {
+			http.Error(responseWriter, "script failed", http.StatusInternalServerError)
+			return
+		}
+		if err := writeResult(responseWriter, result, limits.MaxBodyBytes); err != nil {
+			http.Error(responseWriter, "script returned an invalid response", http.StatusInternalServerError)
+		}
+	}, nil
+}
+
+// requestValue converts the request into the struct passed to handle.
+func requestValue(request *http.Request, body []byte) starlark.Value {
+	query := starlark.NewDict(len(request.URL.Query()))
+	for key := range request.URL.Query() {
+		_ = query.SetKey(starlark.String(key), starlark.String(request.URL.Query().Get(key)))
+	}
+	headers := starlark.NewDict(len(request.Header))
+	for key := range request.Header {
+		_ = headers.SetKey(starlark.String(key), starlark.String(request.Header.Get(key)))
+	}
+	params := starlark.NewDict(0)
+	for _, name := range wildcardNames(request.Pattern) {
+		_ = params.SetKey(starlark.String(name), starlark.String(request.PathValue(name)))
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"method":  starlark.String(request.Method),
+		"path":    starlark.String(request.URL.Path),
+		"query":   query,
+		"headers": headers,
+		"params":  params,
+		"body":    starlark.String(body),
+	})
+}
+
+// writeResult writes the value returned by handle to the response. The
+// result is validated completely before anything is written, so that an
+// invalid result leaves the response untouched for the error response.
+func writeResult(responseWriter http.ResponseWriter, result starlark.Value, maxBodyBytes int64) error {
+	if text, ok := result.(starlark.String); ok {
+		if int64(len(text)) > maxBodyBytes {
+			return fmt.Errorf("response body too large")
+		}
+		responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err := io.WriteString(responseWriter, string(text))
+		return err
+	}
+	dict, ok := result.(*starlark.Dict)
+	if !ok {
+		return fmt.Errorf("handle returned %s", result.Type())
+	}
+
+	status := http.StatusOK
+	if value, found, _ := dict.Get(starlark.String("status")); found {
+		code, err := starlark.AsInt32(value)
+		if err != nil || code < 100 || code > 999 {
+			return fmt.Errorf("invalid status %s", value)
+		}
+		status = code
+	}
+	var body string
+	if value, found, _ := dict.Get(starlark.String("body")); found {
+		text, ok := starlark.AsString(value)
+		if !ok {
+			return fmt.Errorf("body must be a string")
+		}
+		body = text
+	}
+	if int64(len(body)) > maxBodyBytes {
+		return fmt.Errorf("response body too large")
+	}
+	header := make(http.Header)
+	if value, found, _ := dict.Get(starlark.String("headers")); found {
+		headers, ok := value.(*starlark.Dict)
+		if !ok {
+			return fmt.Errorf("headers must be a dict")
+		}
+		for _, item := range headers.Items() {
+			key, keyOK := starlark.AsString(item[0])
+			value, valueOK := starlark.AsString(item[1])
+			if !keyOK || !valueOK {
+				return fmt.Errorf("headers must map strings to strings")
+			}
+			header.Set(key, value)
+		}
+	}
+	for key, values := range header {
+		responseWriter.Header()[key] = values
+	}
+	responseWriter.WriteHeader(status)
+	_, err := io.WriteString(responseWriter, body)
+	return err
+}
+
+// wildcardNames returns the wildcard names of a ServeMux pattern.
+func wildcardNames(pattern string) []string {
+	var names []string
+	for _, segment := range strings.Split(pattern, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name := strings.TrimSuffix(segment[1:len(segment)-1], "...")
+			if name != "$" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
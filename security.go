@@ -0,0 +1,62 @@
+package routerx
+
+import (
+	"slices"
+)
+
+// SecurityRequirement is an authentication requirement of a route that is
+// both enforced and documented, so the two cannot diverge. The auth package
+// provides requirements for bearer tokens, API keys, and basic
+// authentication.
+type SecurityRequirement interface {
+	// Scheme returns the name of the security scheme in the OpenAPI
+	// components, e.g. "bearerAuth", and its security scheme object.
+	Scheme() (name string, object map[string]any)
+
+	// Scopes returns the scopes the route requires.
+	Scopes() []string
+
+	// Middleware returns the guard rejecting requests that do not satisfy
+	// the requirement.
+	Middleware() Middleware
+}
+
+// Security installs the guards of requirements on the methods registered on
+// the builder after calling Security and lists the requirements in their
+// OpenAPI operations. All requirements must be satisfied.
+//
+// Example:
+//
+//	router.Path("/users").
+//	    Security(auth.Bearer("users:read")).
+//	    Get(listUsers).
+//	    Security(auth.Bearer("users:write")).
+//	    Post(createUser)
+func (builder *PathBuilder) Security(requirements ...SecurityRequirement) *PathBuilder {
+	for _, requirement := range requirements {
+		builder.middlewares = append(builder.middlewares, requirement.Middleware())
+	}
+	builder.security = append(slices.Clip(builder.security), requirements...)
+	return builder
+}
+
+// openAPISecurity returns the OpenAPI security requirements of a route and
+// adds its schemes to schemes.
+func openAPISecurity(requirements []SecurityRequirement, schemes map[string]any) []any {
+	requirement := make(map[string]any)
+	for _, security := range requirements {
+		name, object := security.Scheme()
+		schemes[name] = object
+		scopes, _ := requirement[name].([]string)
+		for _, scope := range security.Scopes() {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+		if scopes == nil {
+			scopes = []string{}
+		}
+		requirement[name] = scopes
+	}
+	return []any{requirement}
+}
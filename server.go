@@ -0,0 +1,301 @@
+package routerx
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ServerOption configures the server built by Router.Run and Router.RunTLS.
+type ServerOption func(*serverConfig)
+
+type serverConfig struct {
+	server          *http.Server
+	listeners       []listenTarget
+	shutdownTimeout time.Duration
+	preStopDelay    time.Duration
+}
+
+// WithShutdownTimeout sets how long the runner waits for in-flight requests
+// and shutdown hooks after a termination signal. Defaults to 30 seconds.
+func WithShutdownTimeout(timeout time.Duration) ServerOption {
+	return func(config *serverConfig) {
+		config.shutdownTimeout = timeout
+	}
+}
+
+// WithPreStopDelay keeps the runner serving for delay after a termination
+// signal while the readiness probes fail, see Router.Drain. Defaults to no
+// delay.
+func WithPreStopDelay(delay time.Duration) ServerOption {
+	return func(config *serverConfig) {
+		config.preStopDelay = delay
+	}
+}
+
+// WithReadTimeout limits the time to read a whole request, including its
+// body. Defaults to 60 seconds; zero disables the limit.
+func WithReadTimeout(timeout time.Duration) ServerOption {
+	return func(config *serverConfig) {
+		config.server.ReadTimeout = timeout
+	}
+}
+
+// WithReadHeaderTimeout limits the time to read the request headers.
+// Defaults to 10 seconds.
+func WithReadHeaderTimeout(timeout time.Duration) ServerOption {
+	return func(config *serverConfig) {
+		config.server.ReadHeaderTimeout = timeout
+	}
+}
+
+// WithWriteTimeout limits the time from the end of reading the request
+// headers to the end of writing the response. It also cuts off streamed
+// responses, so it is unset by default.
+func WithWriteTimeout(timeout time.Duration) ServerOption {
+	return func(config *serverConfig) {
+		config.server.WriteTimeout = timeout
+	}
+}
+
+// WithIdleTimeout sets how long idle keep-alive connections stay open.
+// Defaults to 2 minutes.
+func WithIdleTimeout(timeout time.Duration) ServerOption {
+	return func(config *serverConfig) {
+		config.server.IdleTimeout = timeout
+	}
+}
+
+// WithMaxHeaderBytes limits the size of the request headers. Defaults to
+// http.DefaultMaxHeaderBytes, 1 MB.
+func WithMaxHeaderBytes(maxBytes int) ServerOption {
+	return func(config *serverConfig) {
+		config.server.MaxHeaderBytes = maxBytes
+	}
+}
+
+// WithBaseContext sets the function returning the base context of the
+// requests on a listener, e.g. to carry values set at startup or to cancel
+// requests with the application context.
+func WithBaseContext(baseContext func(listener net.Listener) context.Context) ServerOption {
+	return func(config *serverConfig) {
+		config.server.BaseContext = baseContext
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of RunTLS and of the listeners
+// without their own, e.g. to restrict the protocol versions or to get
+// certificates from GetCertificate, in which case the certificate and key
+// files may be empty.
+func WithTLSConfig(tlsConfig *tls.Config) ServerOption {
+	return func(config *serverConfig) {
+		config.server.TLSConfig = tlsConfig
+	}
+}
+
+// WithErrorLog sets the logger of errors accepting connections and of
+// unexpected behavior from handlers. Defaults to the log package's standard
+// logger.
+func WithErrorLog(logger *log.Logger) ServerOption {
+	return func(config *serverConfig) {
+		config.server.ErrorLog = logger
+	}
+}
+
+// WithH2C lets the server speak HTTP/2 without TLS, as load balancers and
+// service meshes that terminate TLS do towards their backends, in addition
+// to HTTP/1. Clients must use HTTP/2 with prior knowledge; the HTTP/1
+// Upgrade: h2c handshake is not supported.
+//
+// Example:
+//
+//	router.Run(":8080", routerx.WithH2C())
+func WithH2C() ServerOption {
+	return func(config *serverConfig) {
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+		config.server.Protocols = &protocols
+	}
+}
+
+// OnShutdown registers a hook that Router.Run and Router.RunTLS call once
+// the server drained, e.g. to close database pools. Hooks run in reverse
+// registration order, like deferred calls, and share the remaining shutdown
+// timeout through ctx. OnShutdown returns the Router to support chaining.
+//
+// Example:
+//
+//	router.OnShutdown(func(ctx context.Context) error {
+//	    return db.Close()
+//	})
+func (router *Router) OnShutdown(hook func(ctx context.Context) error) *Router {
+	router.shutdownHooks = append(router.shutdownHooks, hook)
+	return router
+}
+
+// Run serves the router on addr until the process receives SIGINT or
+// SIGTERM, and then shuts down gracefully: the readiness probes start
+// failing, in-flight requests are drained within the shutdown timeout, and
+// the OnShutdown hooks run. The server limits the time to read request
+// headers to 10 seconds and whole requests to 60 seconds, and closes idle
+// connections after 2 minutes; responses are not limited, so that streams
+// keep working, which is what the Timeout middleware is for. Options such
+// as WithReadTimeout and WithMaxHeaderBytes adjust the server.
+//
+// addr is a TCP address such as ":8080" or a listen target as accepted by
+// WithListener, such as "unix:/run/app.sock". WithListener adds targets
+// served at the same time.
+//
+// Run returns nil after a graceful shutdown, and otherwise the error of
+// listening, of draining, or of the hooks.
+//
+// Example:
+//
+//	if err := router.Run(":8080", routerx.WithShutdownTimeout(15*time.Second)); err != nil {
+//	    log.Fatal(err)
+//	}
+func (router *Router) Run(addr string, options ...ServerOption) error {
+	return router.run(listenTarget{target: addr}, options)
+}
+
+// RunTLS is like Run but serves HTTPS on addr with the certificate and key
+// in the given PEM files, which may be empty when WithTLSConfig provides the
+// certificates.
+func (router *Router) RunTLS(addr string, certFile string, keyFile string, options ...ServerOption) error {
+	return router.run(listenTarget{target: addr, certFile: certFile, keyFile: keyFile, tls: true}, options)
+}
+
+// listenTarget is an address the runner listens on.
+type listenTarget struct {
+	target    string
+	tlsConfig *tls.Config
+	tls       bool
+	certFile  string
+	keyFile   string
+}
+
+// WithListener serves the router on target too, with TLS when tlsConfig is
+// given. Targets are "unix:" followed by the path of a Unix domain socket,
+// "tcp:", "tcp4:", or "tcp6:" followed by an address, or a plain TCP
+// address. A stale socket file left by a previous process is replaced.
+//
+// Example:
+//
+//	router.Run(":8080",
+//	    routerx.WithListener("unix:/run/orders/http.sock"),
+//	    routerx.WithListener("tcp::8443", &tls.Config{Certificates: certificates}),
+//	)
+func WithListener(target string, tlsConfig ...*tls.Config) ServerOption {
+	return func(config *serverConfig) {
+		listener := listenTarget{target: target}
+		if len(tlsConfig) > 0 && tlsConfig[0] != nil {
+			listener.tlsConfig = tlsConfig[0]
+			listener.tls = true
+		}
+		config.listeners = append(config.listeners, listener)
+	}
+}
+
+// listen opens the listener of a target.
+func (target listenTarget) listen(ctx context.Context, server *http.Server) (net.Listener, error) {
+	network, address := "tcp", target.target
+	if prefix, rest, found := strings.Cut(target.target, ":"); found {
+		switch prefix {
+		case "unix", "tcp", "tcp4", "tcp6":
+			network, address = prefix, rest
+		}
+	}
+	if network == "unix" {
+		if info, err := os.Stat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(address)
+		}
+	}
+	var listenConfig net.ListenConfig
+	listener, err := listenConfig.Listen(ctx, network, address)
+	if err != nil || !target.tls {
+		return listener, err
+	}
+
+	tlsConfig := target.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = server.TLSConfig
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig = tlsConfig.Clone()
+	if target.certFile != "" || target.keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(target.certFile, target.keyFile)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, certificate)
+	}
+	if len(tlsConfig.NextProtos) == 0 {
+		tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+	}
+	return tls.NewListener(listener, tlsConfig), nil
+}
+
+func (router *Router) run(main listenTarget, options []ServerOption) error {
+	config := &serverConfig{
+		server: &http.Server{
+			Handler:           router,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+		},
+		shutdownTimeout: 30 * time.Second,
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var listeners []net.Listener
+	for _, target := range append([]listenTarget{main}, config.listeners...) {
+		listener, err := target.listen(signals, config.server)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return err
+		}
+		listeners = append(listeners, listener)
+	}
+	served := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func() {
+			served <- config.server.Serve(listener)
+		}()
+	}
+	select {
+	case err := <-served:
+		config.server.Close()
+		return err
+	case <-signals.Done():
+	}
+	// A second signal terminates the process right away.
+	stop()
+
+	router.SetReady(false)
+	<-router.timeSource().After(config.preStopDelay)
+	ctx, cancel := context.WithTimeout(context.Background(), config.shutdownTimeout)
+	defer cancel()
+	errs := []error{config.server.Shutdown(ctx)}
+	for index := len(router.shutdownHooks) - 1; index >= 0; index-- {
+		errs = append(errs, router.shutdownHooks[index](ctx))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,496 @@
+package routerx
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionStore keeps server-side session data by session ID.
+// Implementations must be safe for concurrent use.
+type SessionStore interface {
+	// Load returns the data saved for id, or nil data when there is none
+	// or it expired.
+	Load(ctx context.Context, id string) ([]byte, error)
+
+	// Save stores data for id, expiring after ttl.
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+
+	// Delete removes the data of id.
+	Delete(ctx context.Context, id string) error
+}
+
+// SessionConfig configures the Sessions middleware.
+type SessionConfig struct {
+	// Keys sign or encrypt the session cookie. The first key protects new
+	// cookies and all keys are accepted, so keys are rotated by prepending
+	// a new one and dropping the oldest once its cookies have expired.
+	// Keys must be 32 random bytes.
+	Keys [][]byte
+
+	// Encrypt encrypts the cookie with AES-256-GCM instead of only signing
+	// it with HMAC-SHA256, so clients cannot read the session data.
+	Encrypt bool
+
+	// Store keeps the session data on the server, leaving only the session
+	// ID in the cookie. Without a store the data travels in the cookie,
+	// which limits it to about 4 KB.
+	Store SessionStore
+
+	// CookieName defaults to "routerx_session".
+	CookieName string
+
+	// Path defaults to "/".
+	Path   string
+	Domain string
+
+	// Secure restricts the cookie to HTTPS.
+	Secure bool
+
+	// SameSite defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+
+	// MaxAge is how long a session lives after its last change. Defaults to
+	// 24 hours.
+	MaxAge time.Duration
+}
+
+// sessionFlashesKey holds the flash messages among the session values.
+const sessionFlashesKey = "_flashes"
+
+type sessionContextKey struct{}
+
+// Session is the session of a request, obtained with GetSession. Values are
+// stored as JSON, so read them back with Get into a variable of the type
+// they were set with. Changes are saved automatically before the response
+// headers are written.
+type Session struct {
+	config  *SessionConfig
+	request *http.Request
+
+	mutex     sync.Mutex
+	loaded    bool
+	id        string
+	values    map[string]json.RawMessage
+	changed   bool
+	destroyed bool
+	oldID     string
+}
+
+// Sessions returns a Middleware giving every request a Session, see
+// GetSession. Sessions are loaded on first use and saved, with a refreshed
+// cookie, when they changed.
+//
+// Example:
+//
+//	router := routerx.New().Use(routerx.Sessions(routerx.SessionConfig{
+//	    Keys:    [][]byte{sessionKey},
+//	    Encrypt: true,
+//	    Secure:  true,
+//	}))
+//	router.Post("/login", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    session := routerx.GetSession(request)
+//	    session.Renew()
+//	    session.Set("user_id", user.ID)
+//	    session.Flash("Welcome back!")
+//	    http.Redirect(responseWriter, request, "/", http.StatusSeeOther)
+//	})
+func Sessions(config SessionConfig) Middleware {
+	if len(config.Keys) == 0 {
+		panic("routerx: Sessions needs at least one key")
+	}
+	for _, key := range config.Keys {
+		if len(key) != 32 {
+			panic("routerx: session keys must be 32 bytes")
+		}
+	}
+	if config.CookieName == "" {
+		config.CookieName = "routerx_session"
+	}
+	if config.Path == "" {
+		config.Path = "/"
+	}
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteLaxMode
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = 24 * time.Hour
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			session := &Session{config: &config}
+			request = request.WithContext(context.WithValue(request.Context(), sessionContextKey{}, session))
+			session.request = request
+			writer := &sessionWriter{ResponseWriter: responseWriter, session: session}
+			next.ServeHTTP(writer, request)
+			writer.commit()
+		})
+	}
+}
+
+// GetSession returns the session of the request. It panics outside the
+// Sessions middleware.
+func GetSession(request *http.Request) *Session {
+	session, ok := request.Context().Value(sessionContextKey{}).(*Session)
+	if !ok {
+		panic("routerx: GetSession called without the Sessions middleware")
+	}
+	return session
+}
+
+// load reads the session from the cookie, and the store, on first use. The
+// caller holds the mutex.
+func (session *Session) load() {
+	if session.loaded {
+		return
+	}
+	session.loaded = true
+	session.values = make(map[string]json.RawMessage)
+	cookie, err := session.request.Cookie(session.config.CookieName)
+	if err != nil {
+		return
+	}
+	payload, ok := session.config.open(cookie.Value, GetClock(session.request).Now())
+	if !ok {
+		return
+	}
+	if session.config.Store == nil {
+		_ = json.Unmarshal(payload, &session.values)
+		return
+	}
+	if json.Unmarshal(payload, &session.id) != nil {
+		return
+	}
+	data, err := session.config.Store.Load(session.request.Context(), session.id)
+	if err != nil {
+		log.Printf("routerx: load session: %v", err)
+	}
+	if data != nil {
+		_ = json.Unmarshal(data, &session.values)
+	}
+}
+
+// ID returns the session ID when the session is kept in a store, or "".
+func (session *Session) ID() string {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	session.load()
+	return session.id
+}
+
+// Get decodes the value of key into destination and reports whether it was
+// set.
+func (session *Session) Get(key string, destination any) bool {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	session.load()
+	value, found := session.values[key]
+	return found && json.Unmarshal(value, destination) == nil
+}
+
+// GetString returns the string value of key, or "".
+func (session *Session) GetString(key string) string {
+	var value string
+	session.Get(key, &value)
+	return value
+}
+
+// Set stores value, which must be encodable as JSON, under key.
+func (session *Session) Set(key string, value any) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		panic("routerx: session value for " + key + ": " + err.Error())
+	}
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	session.load()
+	session.values[key] = encoded
+	session.changed = true
+}
+
+// Delete removes key.
+func (session *Session) Delete(key string) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	session.load()
+	if _, found := session.values[key]; found {
+		delete(session.values, key)
+		session.changed = true
+	}
+}
+
+// Flash adds a message shown once, typically after a redirect.
+func (session *Session) Flash(message string) {
+	flashes := session.peekFlashes()
+	session.Set(sessionFlashesKey, append(flashes, message))
+}
+
+// Flashes returns the flash messages and removes them from the session.
+func (session *Session) Flashes() []string {
+	flashes := session.peekFlashes()
+	session.Delete(sessionFlashesKey)
+	return flashes
+}
+
+func (session *Session) peekFlashes() []string {
+	var flashes []string
+	session.Get(sessionFlashesKey, &flashes)
+	return flashes
+}
+
+// Renew keeps the values under a new session ID, which prevents session
+// fixation when called as the privilege level changes, e.g. on login.
+func (session *Session) Renew() {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	session.load()
+	if session.id != "" && session.oldID == "" {
+		session.oldID = session.id
+	}
+	session.id = ""
+	session.changed = true
+}
+
+// Destroy removes all values and the session cookie, e.g. on logout.
+func (session *Session) Destroy() {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	session.load()
+	session.values = make(map[string]json.RawMessage)
+	session.destroyed = true
+	session.changed = true
+}
+
+// save writes the changed session to the store and the cookie header.
+func (session *Session) save(header http.Header) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if !session.changed {
+		return
+	}
+	session.changed = false
+	config := session.config
+	ctx := session.request.Context()
+	cookie := &http.Cookie{
+		Name:     config.CookieName,
+		Path:     config.Path,
+		Domain:   config.Domain,
+		Secure:   config.Secure,
+		HttpOnly: true,
+		SameSite: config.SameSite,
+	}
+	if config.Store != nil && session.oldID != "" {
+		if err := config.Store.Delete(ctx, session.oldID); err != nil {
+			log.Printf("routerx: delete session: %v", err)
+		}
+		session.oldID = ""
+	}
+	if session.destroyed {
+		if config.Store != nil && session.id != "" {
+			if err := config.Store.Delete(ctx, session.id); err != nil {
+				log.Printf("routerx: delete session: %v", err)
+			}
+		}
+		cookie.MaxAge = -1
+		header.Add("Set-Cookie", cookie.String())
+		return
+	}
+
+	data, err := json.Marshal(session.values)
+	if err != nil {
+		log.Printf("routerx: encode session: %v", err)
+		return
+	}
+	payload := data
+	if config.Store != nil {
+		if session.id == "" {
+			session.id = randomText(GetRandom(session.request))
+		}
+		if err := config.Store.Save(ctx, session.id, data, config.MaxAge); err != nil {
+			log.Printf("routerx: save session: %v", err)
+			return
+		}
+		payload, _ = json.Marshal(session.id)
+	}
+	cookie.Value = config.seal(payload, GetClock(session.request).Now())
+	cookie.MaxAge = int(config.MaxAge / time.Second)
+	if len(cookie.Value) > 4000 {
+		log.Printf("routerx: session cookie of %d bytes exceeds browser limits; use a SessionStore", len(cookie.Value))
+	}
+	header.Add("Set-Cookie", cookie.String())
+}
+
+// seal signs or encrypts payload with the first key, binding it to the
+// cookie name and an expiry time. The nonce always comes from crypto/rand,
+// even with WithRandom, since reusing one breaks the encryption.
+func (config *SessionConfig) seal(payload []byte, now time.Time) string {
+	expires := now.Add(config.MaxAge).Unix()
+	envelope, _ := json.Marshal(sessionEnvelope{Data: payload, Expires: expires})
+	key := config.Keys[0]
+	if config.Encrypt {
+		aead := sessionAEAD(key)
+		nonce := make([]byte, aead.NonceSize())
+		_, _ = rand.Read(nonce)
+		sealed := aead.Seal(nonce, nonce, envelope, []byte(config.CookieName))
+		return base64.RawURLEncoding.EncodeToString(sealed)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(envelope)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sessionMAC(key, config.CookieName, encoded))
+}
+
+// open verifies a cookie value with every key and returns its payload
+// unless it is invalid or expired.
+func (config *SessionConfig) open(value string, now time.Time) ([]byte, bool) {
+	var envelope []byte
+	for _, key := range config.Keys {
+		if config.Encrypt {
+			sealed, err := base64.RawURLEncoding.DecodeString(value)
+			aead := sessionAEAD(key)
+			if err != nil || len(sealed) < aead.NonceSize() {
+				return nil, false
+			}
+			opened, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(config.CookieName))
+			if err == nil {
+				envelope = opened
+				break
+			}
+			continue
+		}
+		encoded, signature, found := strings.Cut(value, ".")
+		mac, err := base64.RawURLEncoding.DecodeString(signature)
+		if !found || err != nil {
+			return nil, false
+		}
+		if hmac.Equal(mac, sessionMAC(key, config.CookieName, encoded)) {
+			envelope, _ = base64.RawURLEncoding.DecodeString(encoded)
+			break
+		}
+	}
+	var decoded sessionEnvelope
+	if envelope == nil || json.Unmarshal(envelope, &decoded) != nil || now.Unix() > decoded.Expires {
+		return nil, false
+	}
+	return decoded.Data, true
+}
+
+type sessionEnvelope struct {
+	Data    json.RawMessage `json:"d"`
+	Expires int64           `json:"e"`
+}
+
+func sessionAEAD(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
+
+func sessionMAC(key []byte, name string, encoded string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + encoded))
+	return mac.Sum(nil)
+}
+
+// sessionWriter saves the session before the response headers are written.
+type sessionWriter struct {
+	http.ResponseWriter
+	session   *Session
+	committed bool
+}
+
+func (writer *sessionWriter) commit() {
+	if !writer.committed {
+		writer.committed = true
+		writer.session.save(writer.Header())
+	}
+}
+
+func (writer *sessionWriter) WriteHeader(statusCode int) {
+	if statusCode >= 200 || statusCode == http.StatusSwitchingProtocols {
+		writer.commit()
+	}
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (writer *sessionWriter) Write(data []byte) (int, error) {
+	writer.commit()
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *sessionWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// MemorySessionStore is a SessionStore keeping sessions in memory, for
+// single instance deployments and tests. Expired sessions are dropped
+// periodically.
+type MemorySessionStore struct {
+	mutex     sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemorySessionStore creates an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+// Load returns the data of id.
+func (store *MemorySessionStore) Load(ctx context.Context, id string) ([]byte, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	session, found := store.sessions[id]
+	if !found || clockFrom(ctx).Now().After(session.expires) {
+		return nil, nil
+	}
+	return session.data, nil
+}
+
+// Save stores data for id.
+func (store *MemorySessionStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	if id == "" {
+		return errors.New("routerx: empty session ID")
+	}
+	now := clockFrom(ctx).Now()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if now.Sub(store.lastSweep) > time.Minute {
+		for key, session := range store.sessions {
+			if now.After(session.expires) {
+				delete(store.sessions, key)
+			}
+		}
+		store.lastSweep = now
+	}
+	store.sessions[id] = memorySession{data: data, expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete removes the data of id.
+func (store *MemorySessionStore) Delete(ctx context.Context, id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.sessions, id)
+	return nil
+}
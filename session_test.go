@@ -0,0 +1,145 @@
+package routerx
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sessionRouter returns a router storing the "user" session value on POST
+// /login and answering it on GET /me.
+func sessionRouter(config SessionConfig, clock Clock) *Router {
+	router := New(WithClock(clock))
+	router.Use(Sessions(config))
+	router.Post("/login", func(responseWriter http.ResponseWriter, request *http.Request) {
+		GetSession(request).Set("user", "42")
+	})
+	router.Get("/me", func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Write([]byte(GetSession(request).GetString("user")))
+	})
+	return router
+}
+
+// sessionCookie logs in and returns the session cookie value.
+func sessionCookie(t *testing.T, router *Router) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/login", nil))
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == "routerx_session" {
+			return cookie.Value
+		}
+	}
+	t.Fatal("no session cookie set")
+	return ""
+}
+
+// sessionUser returns the "user" session value read with the cookie value.
+func sessionUser(router *Router, value string) string {
+	request := httptest.NewRequest(http.MethodGet, "/me", nil)
+	request.AddCookie(&http.Cookie{Name: "routerx_session", Value: value})
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder.Body.String()
+}
+
+func TestSessionCookies(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	tamper := func(value string) string {
+		// The first character belongs to the payload or the nonce; the last
+		// one may only carry padding bits that decoding ignores.
+		replacement := byte('A')
+		if value[0] == 'A' {
+			replacement = 'B'
+		}
+		return string(replacement) + value[1:]
+	}
+
+	tests := []struct {
+		name     string
+		issue    [][]byte
+		read     [][]byte
+		advance  time.Duration
+		modify   func(value string) string
+		wantUser string
+	}{
+		{
+			name:     "valid",
+			issue:    [][]byte{newKey},
+			read:     [][]byte{newKey},
+			wantUser: "42",
+		},
+		{
+			name:     "tampered",
+			issue:    [][]byte{newKey},
+			read:     [][]byte{newKey},
+			modify:   tamper,
+			wantUser: "",
+		},
+		{
+			name:     "truncated",
+			issue:    [][]byte{newKey},
+			read:     [][]byte{newKey},
+			modify:   func(value string) string { return value[:len(value)/2] },
+			wantUser: "",
+		},
+		{
+			name:     "before expiry",
+			issue:    [][]byte{newKey},
+			read:     [][]byte{newKey},
+			advance:  23 * time.Hour,
+			wantUser: "42",
+		},
+		{
+			name:     "expired",
+			issue:    [][]byte{newKey},
+			read:     [][]byte{newKey},
+			advance:  24*time.Hour + time.Second,
+			wantUser: "",
+		},
+		{
+			name:     "old key still accepted",
+			issue:    [][]byte{oldKey},
+			read:     [][]byte{newKey, oldKey},
+			wantUser: "42",
+		},
+		{
+			name:     "old key dropped",
+			issue:    [][]byte{oldKey},
+			read:     [][]byte{newKey},
+			wantUser: "",
+		},
+	}
+	for _, encrypt := range []bool{false, true} {
+		for _, test := range tests {
+			name := test.name
+			if encrypt {
+				name += " encrypted"
+			}
+			t.Run(name, func(t *testing.T) {
+				clock := NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+				value := sessionCookie(t, sessionRouter(SessionConfig{Keys: test.issue, Encrypt: encrypt}, clock))
+				if test.modify != nil {
+					value = test.modify(value)
+				}
+				clock.Advance(test.advance)
+				user := sessionUser(sessionRouter(SessionConfig{Keys: test.read, Encrypt: encrypt}, clock), value)
+				if user != test.wantUser {
+					t.Errorf("user = %q, want %q", user, test.wantUser)
+				}
+			})
+		}
+	}
+}
+
+func TestSessionCookieEncryptionMismatch(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	clock := NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	signed := sessionCookie(t, sessionRouter(SessionConfig{Keys: [][]byte{key}}, clock))
+	if user := sessionUser(sessionRouter(SessionConfig{Keys: [][]byte{key}, Encrypt: true}, clock), signed); user != "" {
+		t.Errorf("signed cookie accepted as encrypted: user = %q", user)
+	}
+}
@@ -0,0 +1,235 @@
+package routerx
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Strict turns the registration warnings of the router, such as overlapping
+// patterns whose precedence is decided by ServeMux specificity, into panics
+// that name the offending call site. Strict mode also panics when Use is
+// called on a router or group after routes were registered on it or groups
+// and path builders were derived from it, since the added middlewares would
+// silently not apply to those. Enable it in tests or during development
+// to catch routing mistakes early. Strict returns the Router to support
+// chaining.
+//
+// Example:
+//
+//	router := routerx.New().Strict()
+func (router *Router) Strict() *Router {
+	router.strict = true
+	return router
+}
+
+// warn reports a registration problem: it panics in strict mode and logs
+// otherwise.
+func (router *Router) warn(format string, arguments ...any) {
+	message := "routerx: " + fmt.Sprintf(format, arguments...)
+	router.problems = append(router.problems, message)
+	if router.strict {
+		panic(message)
+	}
+	log.Print("warning: " + message)
+}
+
+// seal records the first call site after which middlewares added to the
+// router with Use no longer reach every route, because a route was registered
+// or a group or path builder copied the middleware chain.
+func (router *Router) seal() {
+	if router.sealedAt == "" {
+		router.sealedAt = callSite()
+	}
+}
+
+// seal records the first call site after which middlewares added to the group
+// with Use no longer reach every route of the group.
+func (group *RouteGroup) seal() {
+	if group.sealedAt == "" {
+		group.sealedAt = callSite()
+	}
+}
+
+// checkUse panics in strict mode when Use is called on a router or group
+// that was sealed at sealedAt, since the middlewares would silently skip the
+// routes registered before. Outside strict mode this is the documented
+// behavior of Use and is only listed by Router.Diagnose.
+func (router *Router) checkUse(owner string, sealedAt string) {
+	if sealedAt == "" {
+		return
+	}
+	format := "Use called on the %s at %s after routes were registered or groups created at %s; the middlewares do not apply to them, call Use first"
+	if router.strict {
+		router.warn(format, owner, callSite(), sealedAt)
+		return
+	}
+	router.problems = append(router.problems, "routerx: "+fmt.Sprintf(format, owner, callSite(), sealedAt))
+}
+
+// checkOverlaps warns when newRoute and an existing route shadow each other:
+// the more general one, matching a whole subtree ({name...} or a trailing
+// slash) or any method, covers the other, which differs from it only by
+// wildcards, as with "/users/{id}" and "/users/{id...}". ServeMux serves
+// the requests matching both with the more specific pattern regardless of
+// registration order, so the general route never sees them, which surprises
+// users expecting the later or the more general route to win. Routes
+// beneath a literal segment, such as "/users/new" under "/users/", and
+// routes under the root catch-all are the usual way to mount handlers and
+// are not reported.
+func (router *Router) checkOverlaps(newRoute *route) {
+	if newRoute.preflight {
+		return
+	}
+	newPattern := parsePatternSegments(newRoute.path)
+	for _, existing := range router.routes {
+		if existing.preflight || existing.method == newRoute.method && routeShapeOf(existing.path) == routeShapeOf(newRoute.path) {
+			continue
+		}
+		existingPattern := parsePatternSegments(existing.path)
+		if !methodsOverlap(newRoute.method, existing.method) || !newPattern.overlaps(existingPattern) {
+			continue
+		}
+		// A method registered on the same path as an Any route is the
+		// documented way to override it for that method.
+		if routeShapeOf(existing.path) == routeShapeOf(newRoute.path) {
+			continue
+		}
+
+		newCovers := methodCovers(newRoute.method, existing.method) && newPattern.covers(existingPattern)
+		existingCovers := methodCovers(existing.method, newRoute.method) && existingPattern.covers(newPattern)
+		var winner string
+		switch {
+		case newCovers && !existingCovers && newPattern.shadows(existingPattern):
+			winner = existing.pattern
+		case existingCovers && !newCovers && existingPattern.shadows(newPattern):
+			winner = newRoute.pattern
+		default:
+			// Either the routes do not shadow each other, or neither is
+			// more specific and ServeMux rejects them when registered.
+			continue
+		}
+		router.warn("pattern %q (%s) overlaps %q (%s); requests matching both are served by %q because it is more specific",
+			newRoute.pattern, newRoute.site, existing.pattern, existing.site, winner)
+	}
+}
+
+// patternSegments is the path of a ServeMux pattern split into segments.
+// Wildcard segments are marked in wildcards, and rest reports whether the
+// pattern matches any remainder, as with "{name...}" or a trailing slash.
+type patternSegments struct {
+	segments  []string
+	wildcards []bool
+	rest      bool
+}
+
+func parsePatternSegments(muxPath string) patternSegments {
+	var parsed patternSegments
+	parts := strings.Split(strings.TrimPrefix(muxPath, "/"), "/")
+	for index, part := range parts {
+		last := index == len(parts)-1
+		switch {
+		case last && (part == "" || strings.HasSuffix(part, "...}")):
+			parsed.rest = true
+		case part == "{$}":
+			parsed.segments = append(parsed.segments, "")
+			parsed.wildcards = append(parsed.wildcards, false)
+		default:
+			parsed.segments = append(parsed.segments, part)
+			parsed.wildcards = append(parsed.wildcards, strings.HasPrefix(part, "{"))
+		}
+	}
+	return parsed
+}
+
+// covers reports whether every path matched by other is matched by pattern.
+func (pattern patternSegments) covers(other patternSegments) bool {
+	for index := range other.segments {
+		if index >= len(pattern.segments) {
+			return pattern.rest
+		}
+		if !pattern.wildcards[index] && (other.wildcards[index] || other.segments[index] != pattern.segments[index]) {
+			return false
+		}
+	}
+	if len(pattern.segments) != len(other.segments) {
+		return false
+	}
+	return pattern.rest || !other.rest
+}
+
+// shadows reports whether pattern, which covers other, differs from it only
+// by wildcards, so that other takes a whole level of the paths of pattern
+// rather than a named part of it. The root catch-all shadows nothing.
+func (pattern patternSegments) shadows(other patternSegments) bool {
+	if len(pattern.segments) == 0 {
+		return false
+	}
+	for index := range other.segments {
+		if other.wildcards[index] {
+			continue
+		}
+		if index >= len(pattern.segments) || pattern.wildcards[index] {
+			return false
+		}
+	}
+	return true
+}
+
+// overlaps reports whether some path is matched by both patterns.
+func (pattern patternSegments) overlaps(other patternSegments) bool {
+	shared := min(len(pattern.segments), len(other.segments))
+	for index := 0; index < shared; index++ {
+		if !pattern.wildcards[index] && !other.wildcards[index] && pattern.segments[index] != other.segments[index] {
+			return false
+		}
+	}
+	switch {
+	case len(pattern.segments) < len(other.segments):
+		return pattern.rest
+	case len(pattern.segments) > len(other.segments):
+		return other.rest
+	}
+	return pattern.rest == other.rest
+}
+
+// methodsOverlap reports whether some request method is matched by both
+// route methods, "" matching every method and GET also matching HEAD.
+func methodsOverlap(first string, second string) bool {
+	return methodCovers(first, second) || methodCovers(second, first)
+}
+
+// methodCovers reports whether every method matched by other is matched by
+// method.
+func methodCovers(method string, other string) bool {
+	return method == "" || method == other || (method == http.MethodGet && other == http.MethodHead)
+}
+
+// callSite returns the file and line of the first caller outside routerx.
+func callSite() string {
+	programCounters := make([]uintptr, 16)
+	count := runtime.Callers(2, programCounters)
+	frames := runtime.CallersFrames(programCounters[:count])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/Mark-Bazylev/routerx.") {
+			return shortFile(frame.File) + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// shortFile trims a source file path to its directory and file name.
+func shortFile(file string) string {
+	if index := strings.LastIndexByte(file, '/'); index >= 0 {
+		if parent := strings.LastIndexByte(file[:index], '/'); parent >= 0 {
+			return file[parent+1:]
+		}
+	}
+	return file
+}
@@ -0,0 +1,37 @@
+package routerx
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCheckOverlaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    string
+		second   string
+		wantWarn bool
+	}{
+		{"root catch-all", "/", "/users", false},
+		{"root catch-all wildcard", "/{path...}", "/{id}", false},
+		{"literal under a rest wildcard", "/users/{rest...}", "/users/new", false},
+		{"unrelated paths", "/users/{id}", "/orders/{id}", false},
+		{"wildcard under a rest wildcard", "/users/{id...}", "/users/{id}", true},
+		{"rest wildcard over a wildcard", "/users/{id}", "/users/{id...}", true},
+		{"nested wildcard under a rest wildcard", "/users/{id}/{rest...}", "/users/{id}/{file}", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := New().Strict()
+			warned := false
+			func() {
+				defer func() { warned = recover() != nil }()
+				router.Get(test.first, func(http.ResponseWriter, *http.Request) {})
+				router.Get(test.second, func(http.ResponseWriter, *http.Request) {})
+			}()
+			if warned != test.wantWarn {
+				t.Errorf("registering %q and %q warned = %v, want %v", test.first, test.second, warned, test.wantWarn)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package routerx
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SmokeTestConfig configures Router.GenerateSmokeTests.
+type SmokeTestConfig struct {
+	// Package is the package clause of the generated test file.
+	Package string
+
+	// Router is the Go expression building the application router in the
+	// generated test, e.g. "NewRouter()" or "app.NewRouter(testConfig)".
+	Router string
+
+	// Imports lists the import paths the Router expression needs.
+	Imports []string
+
+	// Samples are the values used for path parameters by name. Parameters
+	// without a sample get "1", or a value satisfying their regular
+	// expression constraint; routes whose constraint accepts none of the
+	// built-in candidates are skipped.
+	Samples map[string]string
+
+	// Headers are sent with every request, e.g. a test API key.
+	Headers map[string]string
+
+	// TagHeaders are sent with the requests to routes documented with a tag
+	// in RouteDoc.Tags, e.g. the Authorization header of an admin test
+	// account for the routes tagged "admin".
+	TagHeaders map[string]map[string]string
+}
+
+// smokeSampleCandidates are tried in order for constrained parameters
+// without a configured sample.
+var smokeSampleCandidates = []string{
+	"1", "a", "example", "2024-01-01", "00000000-0000-0000-0000-000000000000", "A1", "a-1",
+}
+
+// GenerateSmokeTests writes a Go test file that requests every registered
+// GET route with sample path parameters and fails for responses with a 5xx
+// status or panics. It gives applications adopting routerx an instant
+// baseline: a handler that crashes without specific input is caught before
+// any hand-written test exists. Like GenerateRoutes, it is meant to run from
+// a small program invoked by go:generate.
+//
+// Example:
+//
+//	// cmd/gensmoke/main.go
+//	func main() {
+//	    file, _ := os.Create("api/smoke_gen_test.go")
+//	    defer file.Close()
+//	    err := api.NewRouter().GenerateSmokeTests(file, routerx.SmokeTestConfig{
+//	        Package:    "api",
+//	        Router:     "NewRouter()",
+//	        Samples:    map[string]string{"id": "42"},
+//	        TagHeaders: map[string]map[string]string{"admin": {"Authorization": "Bearer admin-test-token"}},
+//	    })
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+func (router *Router) GenerateSmokeTests(writer io.Writer, config SmokeTestConfig) error {
+	if config.Package == "" || config.Router == "" {
+		return fmt.Errorf("routerx: smoke tests need a package and a router expression")
+	}
+
+	var cases, skipped bytes.Buffer
+	for _, registered := range router.routes {
+		if registered.method != "GET" || registered.preflight {
+			continue
+		}
+		path, missing := smokeTestPath(registered, config.Samples)
+		if missing != "" {
+			fmt.Fprintf(&skipped, "// Skipped %s: no sample for %q.\n", registered.pattern, missing)
+			continue
+		}
+		headers := maps.Clone(config.Headers)
+		if registered.doc != nil {
+			for _, tag := range registered.doc.Tags {
+				if headers == nil {
+					headers = make(map[string]string)
+				}
+				maps.Copy(headers, config.TagHeaders[tag])
+			}
+		}
+		fmt.Fprintf(&cases, "{%s, %s, %s},\n", strconv.Quote(registered.pattern), strconv.Quote(path), smokeHeadersLiteral(headers))
+	}
+
+	var source bytes.Buffer
+	fmt.Fprintf(&source, "// Code generated by routerx; DO NOT EDIT.\n\npackage %s\n\n", config.Package)
+	source.WriteString("import (\n\"net/http/httptest\"\n\"testing\"\n")
+	for _, path := range config.Imports {
+		source.WriteString(strconv.Quote(path) + "\n")
+	}
+	source.WriteString(")\n\n")
+	source.Write(skipped.Bytes())
+	if skipped.Len() > 0 {
+		source.WriteString("\n")
+	}
+	source.WriteString("// TestRouteSmoke requests every GET route and fails on 5xx responses.\n")
+	fmt.Fprintf(&source, "func TestRouteSmoke(t *testing.T) {\nrouter := %s\n", config.Router)
+	source.WriteString("cases := []struct {\npattern string\npath string\nheaders map[string]string\n}{\n")
+	source.Write(cases.Bytes())
+	source.WriteString(`}
+for _, smoke := range cases {
+t.Run(smoke.pattern, func(t *testing.T) {
+request := httptest.NewRequest("GET", smoke.path, nil)
+for name, value := range smoke.headers {
+request.Header.Set(name, value)
+}
+recorder := httptest.NewRecorder()
+router.ServeHTTP(recorder, request)
+if recorder.Code >= 500 {
+t.Errorf("GET %s answered %d: %s", smoke.path, recorder.Code, recorder.Body.String())
+}
+})
+}
+}
+`)
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return fmt.Errorf("routerx: format generated smoke tests: %w", err)
+	}
+	_, err = writer.Write(formatted)
+	return err
+}
+
+// smokeTestPath fills the wildcards of a route with sample values, returning
+// the name of the first parameter without a usable sample instead.
+func smokeTestPath(registered *route, samples map[string]string) (string, string) {
+	var path strings.Builder
+	for index := 0; index < len(registered.path); index++ {
+		if registered.path[index] != '{' {
+			path.WriteByte(registered.path[index])
+			continue
+		}
+		end := strings.IndexByte(registered.path[index:], '}') + index
+		wildcard := registered.path[index+1 : end]
+		index = end
+		if wildcard == "$" {
+			continue
+		}
+		name := strings.TrimSuffix(wildcard, "...")
+		sample, found := samples[name]
+		if !found {
+			constraint := registered.constraints[name]
+			for _, candidate := range smokeSampleCandidates {
+				if constraint == nil || constraint.MatchString(candidate) {
+					sample, found = candidate, true
+					break
+				}
+			}
+		}
+		if !found {
+			return "", name
+		}
+		path.WriteString(url.PathEscape(sample))
+	}
+	return path.String(), ""
+}
+
+func smokeHeadersLiteral(headers map[string]string) string {
+	if len(headers) == 0 {
+		return "nil"
+	}
+	var literal strings.Builder
+	literal.WriteString("map[string]string{")
+	for index, name := range slices.Sorted(maps.Keys(headers)) {
+		if index > 0 {
+			literal.WriteString(", ")
+		}
+		literal.WriteString(strconv.Quote(name) + ": " + strconv.Quote(headers[name]))
+	}
+	literal.WriteString("}")
+	return literal.String()
+}
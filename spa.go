@@ -0,0 +1,97 @@
+package routerx
+
+import (
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// spaFallback serves a single-page application for requests that match no
+// route.
+type spaFallback struct {
+	prefix string
+	fsys   fs.FS
+	files  http.Handler
+	index  http.Handler
+}
+
+// SPA serves a single-page application from fsys under prefix. GET and HEAD
+// requests that match no route are answered with the file of the same name
+// when it exists, and with the index file otherwise, so client-side routes
+// such as "/settings/profile" load the application. SPA never shadows
+// registered routes because it only runs where routing found nothing, and
+// unmatched paths whose first segment below prefix belongs to a registered
+// route, such as "/api/v1/unknown" next to an "/api" group, still answer
+// 404. Requests that do not accept HTML also get a 404 instead of the index
+// file. The router's middleware chain at the time of the call is applied.
+//
+// Example:
+//
+//	//go:embed dist
+//	var dist embed.FS
+//
+//	router.Group("/api").Get("/users", listUsers)
+//	distFS, _ := fs.Sub(dist, "dist")
+//	router.SPA("/", distFS, "index.html")
+func (router *Router) SPA(prefix string, fsys fs.FS, index string) {
+	router.seal()
+	prefix = cleanPath(prefix)
+	files := http.StripPrefix(strings.TrimSuffix(prefix, "/"), http.FileServerFS(noListingFS{fsys}))
+	indexHandler := http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		// The index must be revalidated so that clients pick up new builds.
+		responseWriter.Header().Set("Cache-Control", "no-cache")
+		http.ServeFileFS(responseWriter, request, fsys, index)
+	})
+	router.spas = append(router.spas, &spaFallback{
+		prefix: prefix,
+		fsys:   fsys,
+		files:  applyMiddlewares(files, router.middlewares),
+		index:  applyMiddlewares(indexHandler, router.middlewares),
+	})
+}
+
+// serve answers the request and returns true when it belongs to the
+// application.
+func (spa *spaFallback) serve(router *Router, responseWriter http.ResponseWriter, request *http.Request) bool {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		return false
+	}
+	requestPath := request.URL.Path
+	if spa.prefix != "/" && requestPath != spa.prefix && !strings.HasPrefix(requestPath, spa.prefix+"/") {
+		return false
+	}
+
+	name := strings.Trim(strings.TrimPrefix(requestPath, strings.TrimSuffix(spa.prefix, "/")), "/")
+	if name != "" && fs.ValidPath(name) {
+		if info, err := fs.Stat(spa.fsys, name); err == nil && !info.IsDir() {
+			spa.files.ServeHTTP(responseWriter, request)
+			return true
+		}
+	}
+
+	if segment := firstSegment(name); segment != "" && router.ownsPrefix(strings.TrimSuffix(spa.prefix, "/")+"/"+segment) {
+		return false
+	}
+	if accept := request.Header.Get("Accept"); accept != "" && !strings.Contains(accept, "text/html") && !strings.Contains(accept, "*/*") {
+		return false
+	}
+	spa.index.ServeHTTP(responseWriter, request)
+	return true
+}
+
+// ownsPrefix reports whether a registered route lies at or below prefix.
+func (router *Router) ownsPrefix(prefix string) bool {
+	for _, registered := range router.routes {
+		if registered.path == prefix || strings.HasPrefix(registered.path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// firstSegment returns the first segment of a relative path, e.g. "api" for
+// "api/v1/users".
+func firstSegment(path string) string {
+	segment, _, _ := strings.Cut(path, "/")
+	return segment
+}
@@ -0,0 +1,146 @@
+package routerx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+)
+
+// SpoolConfig configures the Spool middleware.
+type SpoolConfig struct {
+	// MemoryThreshold is the body size kept in memory. Larger bodies are
+	// written to a temporary file. Defaults to 1 MiB.
+	MemoryThreshold int64
+
+	// MaxBytes rejects bodies larger than this with 413. Zero means no limit.
+	MaxBytes int64
+
+	// Directory holds the temporary files. Defaults to os.TempDir().
+	Directory string
+}
+
+// Spool returns a Middleware that reads the whole request body before the
+// handler runs, keeping small bodies in memory and spooling bodies above
+// config.MemoryThreshold to a temporary file that is removed when the request
+// completes. The handler receives a body that implements io.ReadSeeker, see
+// SpooledBody, and an accurate request.ContentLength. This keeps memory flat
+// for occasional huge uploads while letting handlers read a body more than
+// once.
+//
+// Example:
+//
+//	router.Path("/imports").
+//	    Use(routerx.Spool(routerx.SpoolConfig{MemoryThreshold: 4 << 20, MaxBytes: 2 << 30})).
+//	    Post(func(responseWriter http.ResponseWriter, request *http.Request) {
+//	        body, _ := routerx.SpooledBody(request)
+//	        format := sniffFormat(body)
+//	        body.Seek(0, io.SeekStart)
+//	        importFile(body, format)
+//	    })
+func Spool(config SpoolConfig) Middleware {
+	if config.MemoryThreshold <= 0 {
+		config.MemoryThreshold = 1 << 20
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			if request.Body == nil || request.Body == http.NoBody {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			body, size, err := spoolBody(request.Body, config)
+			if errors.Is(err, errSpoolTooLarge) {
+				writeError(responseWriter, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			if err != nil {
+				writeError(responseWriter, http.StatusBadRequest, "failed to read request body")
+				return
+			}
+			defer body.Close()
+
+			request.Body = body
+			request.ContentLength = size
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
+
+var errSpoolTooLarge = errors.New("routerx: request body too large")
+
+// spoolBody drains source into memory or, beyond the threshold, a temporary
+// file.
+func spoolBody(source io.ReadCloser, config SpoolConfig) (io.ReadSeekCloser, int64, error) {
+	defer source.Close()
+
+	limit := int64(-1)
+	if config.MaxBytes > 0 {
+		limit = config.MaxBytes
+	}
+	var memory bytes.Buffer
+	size, err := io.Copy(&memory, io.LimitReader(source, config.MemoryThreshold+1))
+	if err != nil {
+		return nil, 0, err
+	}
+	if limit >= 0 && size > limit {
+		return nil, 0, errSpoolTooLarge
+	}
+	if size <= config.MemoryThreshold {
+		return nopSeekCloser{bytes.NewReader(memory.Bytes())}, size, nil
+	}
+
+	file, err := os.CreateTemp(config.Directory, "routerx-spool-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	spooled := &spooledFile{File: file}
+	if _, err := file.Write(memory.Bytes()); err != nil {
+		spooled.Close()
+		return nil, 0, err
+	}
+	remaining := io.Reader(source)
+	if limit >= 0 {
+		remaining = io.LimitReader(source, limit-size+1)
+	}
+	copied, err := io.Copy(file, remaining)
+	size += copied
+	if err == nil && limit >= 0 && size > limit {
+		err = errSpoolTooLarge
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		spooled.Close()
+		return nil, 0, err
+	}
+	return spooled, size, nil
+}
+
+// SpooledBody returns the request body as an io.ReadSeeker when it was
+// prepared by the Spool middleware.
+func SpooledBody(request *http.Request) (io.ReadSeeker, bool) {
+	switch body := request.Body.(type) {
+	case nopSeekCloser, *spooledFile:
+		return body.(io.ReadSeeker), true
+	}
+	return nil, false
+}
+
+type nopSeekCloser struct {
+	*bytes.Reader
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+// spooledFile is a temporary file that is deleted when closed.
+type spooledFile struct {
+	*os.File
+}
+
+func (file *spooledFile) Close() error {
+	closeErr := file.File.Close()
+	removeErr := os.Remove(file.Name())
+	return errors.Join(closeErr, removeErr)
+}
@@ -0,0 +1,247 @@
+package routerx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEConfig configures SSE and PathBuilder.SSE.
+type SSEConfig struct {
+	// Heartbeat is the interval of the comment lines sent while no event
+	// is, which keep proxies and load balancers from closing idle streams
+	// and reveal disconnected clients. Defaults to 15 seconds; a negative
+	// value disables heartbeats.
+	Heartbeat time.Duration
+
+	// Retry, when set, tells the client how long to wait before
+	// reconnecting after the stream ends.
+	Retry time.Duration
+}
+
+// SSEEvent is a server-sent event with all its fields, see
+// EventStream.SendEvent.
+type SSEEvent struct {
+	// ID is stored by the client and sent back in the Last-Event-ID header
+	// when it reconnects, see EventStream.LastEventID.
+	ID string
+
+	// Event is the event type; clients listen to it with
+	// addEventListener. Empty means "message".
+	Event string
+
+	// Data is sent as is when it is a string or []byte and encoded as JSON
+	// otherwise.
+	Data any
+}
+
+// EventStream writes server-sent events to a response, see SSE. Its methods
+// may be called from several goroutines.
+type EventStream struct {
+	responseWriter http.ResponseWriter
+	controller     *http.ResponseController
+	ctx            context.Context
+	cancel         context.CancelFunc
+	lastEventID    string
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+// errEventStreamClosed is returned by sends after the stream was closed.
+var errEventStreamClosed = errors.New("routerx: event stream closed")
+
+// SSE starts a server-sent events response: it sends the event-stream
+// headers, disables proxy buffering and the server's write timeout for the
+// response, and returns the EventStream to send events with. Every event is
+// flushed immediately, and heartbeats are sent in between. Done is closed
+// when the client disconnects. Call Close before the handler returns. SSE
+// fails if the response cannot be flushed.
+//
+// Example:
+//
+//	router.Get("/orders/{id}/events", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    stream, err := routerx.SSE(responseWriter, request)
+//	    if err != nil {
+//	        routerx.HandleError(responseWriter, request, err)
+//	        return
+//	    }
+//	    defer stream.Close()
+//	    updates := orders.Subscribe(request.PathValue("id"))
+//	    for {
+//	        select {
+//	        case update := <-updates:
+//	            stream.Send("status", update)
+//	        case <-stream.Done():
+//	            return
+//	        }
+//	    }
+//	})
+func SSE(responseWriter http.ResponseWriter, request *http.Request, configs ...SSEConfig) (*EventStream, error) {
+	var config SSEConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.Heartbeat == 0 {
+		config.Heartbeat = 15 * time.Second
+	}
+
+	controller := http.NewResponseController(responseWriter)
+	header := responseWriter.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	header.Del("Content-Length")
+	responseWriter.WriteHeader(http.StatusOK)
+	if config.Retry > 0 {
+		fmt.Fprintf(responseWriter, "retry: %d\n\n", config.Retry.Milliseconds())
+	}
+	if err := controller.Flush(); err != nil {
+		return nil, fmt.Errorf("routerx: event stream: %w", err)
+	}
+	// Streams outlive the server's WriteTimeout by design.
+	_ = controller.SetWriteDeadline(time.Time{})
+
+	ctx, cancel := context.WithCancel(request.Context())
+	stream := &EventStream{
+		responseWriter: responseWriter,
+		controller:     controller,
+		ctx:            ctx,
+		cancel:         cancel,
+		lastEventID:    request.Header.Get("Last-Event-ID"),
+	}
+	if config.Heartbeat > 0 {
+		go stream.heartbeat(config.Heartbeat)
+	}
+	return stream, nil
+}
+
+// SSE registers a GET route at the builder's path that streams server-sent
+// events with handler. The stream is closed when handler returns.
+//
+// Example:
+//
+//	router.Path("/notifications").
+//	    Use(requireLogin).
+//	    SSE(func(stream *routerx.EventStream, request *http.Request) {
+//	        for notification := range notifier.Subscribe(stream.Done()) {
+//	            stream.SendEvent(routerx.SSEEvent{ID: notification.ID, Event: "notification", Data: notification})
+//	        }
+//	    })
+func (builder *PathBuilder) SSE(handler func(stream *EventStream, request *http.Request), configs ...SSEConfig) *PathBuilder {
+	builder.register("GET", http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		stream, err := SSE(responseWriter, request, configs...)
+		if err != nil {
+			HandleError(responseWriter, request, err)
+			return
+		}
+		defer stream.Close()
+		handler(stream, request)
+	}))
+	return builder
+}
+
+// Send sends an event of type event, or "message" when empty, with data,
+// see SSEEvent.Data.
+func (stream *EventStream) Send(event string, data any) error {
+	return stream.SendEvent(SSEEvent{Event: event, Data: data})
+}
+
+// SendEvent sends event and flushes it to the client.
+func (stream *EventStream) SendEvent(event SSEEvent) error {
+	var data string
+	switch value := event.Data.(type) {
+	case string:
+		data = value
+	case []byte:
+		data = string(value)
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		data = string(encoded)
+	}
+
+	var builder strings.Builder
+	if event.ID != "" {
+		builder.WriteString("id: " + sseField(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		builder.WriteString("event: " + sseField(event.Event) + "\n")
+	}
+	// Clients end a line at CRLF, CR, or LF, so a bare CR must start a new
+	// data line too.
+	data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+	for line := range strings.SplitSeq(data, "\n") {
+		builder.WriteString("data: " + line + "\n")
+	}
+	builder.WriteString("\n")
+	return stream.write(builder.String())
+}
+
+// Comment sends a comment line, which clients ignore.
+func (stream *EventStream) Comment(text string) error {
+	return stream.write(": " + sseField(text) + "\n\n")
+}
+
+// LastEventID returns the Last-Event-ID header of a reconnecting client,
+// the ID of the last event it received, to resume the stream from.
+func (stream *EventStream) LastEventID() string {
+	return stream.lastEventID
+}
+
+// Done returns a channel closed when the client disconnects or the stream
+// is closed.
+func (stream *EventStream) Done() <-chan struct{} {
+	return stream.ctx.Done()
+}
+
+// Close stops the heartbeats; later sends fail. It does not end the
+// response, which ends when the handler returns.
+func (stream *EventStream) Close() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.closed = true
+	stream.cancel()
+}
+
+func (stream *EventStream) write(text string) error {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.closed {
+		return errEventStreamClosed
+	}
+	if err := stream.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := stream.responseWriter.Write([]byte(text)); err != nil {
+		return err
+	}
+	return stream.controller.Flush()
+}
+
+func (stream *EventStream) heartbeat(interval time.Duration) {
+	clock := clockFrom(stream.ctx)
+	for {
+		select {
+		case now := <-clock.After(interval):
+			if stream.write(": heartbeat "+strconv.FormatInt(now.Unix(), 10)+"\n\n") != nil {
+				return
+			}
+		case <-stream.ctx.Done():
+			return
+		}
+	}
+}
+
+// sseField removes line breaks, which would end a field early.
+func sseField(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -0,0 +1,109 @@
+package routerx
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StaticConfig configures Router.Static and RouteGroup.Static.
+type StaticConfig struct {
+	// Listing serves a listing for directories without an index.html.
+	// Listings are disabled by default and such directories answer 404.
+	Listing bool
+
+	// MaxAge sets the Cache-Control max-age of the served files. Zero
+	// leaves Cache-Control unset.
+	MaxAge time.Duration
+}
+
+// Static serves the files of fsys under prefix for GET and HEAD requests. It
+// works with os.DirFS as well as embed.FS, sets Content-Type from the file
+// extension or contents, answers conditional and range requests, and serves
+// index.html for directories. Paths that try to escape fsys, such as
+// "/assets/../secret", are rejected. The router's middleware chain is
+// applied.
+//
+// Example:
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	assets, _ := fs.Sub(public, "public")
+//	router.Static("/assets", assets, routerx.StaticConfig{MaxAge: 24 * time.Hour})
+func (router *Router) Static(prefix string, fsys fs.FS, configs ...StaticConfig) {
+	prefix = cleanPath(prefix)
+	router.handle("GET", staticPattern(prefix), staticHandler(prefix, fsys, configs), router.middlewares)
+}
+
+// Static serves the files of fsys under the group's prefix joined with prefix,
+// applying the group's middleware chain. See Router.Static.
+//
+// Example:
+//
+//	docs := router.Group("/docs").Use(RequireLogin)
+//	docs.Static("/", os.DirFS("./site"))
+func (group *RouteGroup) Static(prefix string, fsys fs.FS, configs ...StaticConfig) {
+	group.handle("GET", staticPattern(cleanPath(prefix)), staticHandler(joinPath(group.prefix, prefix), fsys, configs))
+}
+
+// staticPattern returns the route path matching everything below prefix.
+func staticPattern(prefix string) string {
+	return strings.TrimSuffix(prefix, "/") + "/{path...}"
+}
+
+func staticHandler(prefix string, fsys fs.FS, configs []StaticConfig) http.HandlerFunc {
+	var config StaticConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if !config.Listing {
+		fsys = noListingFS{fsys}
+	}
+	fileServer := http.StripPrefix(strings.TrimSuffix(prefix, "/"), http.FileServerFS(fsys))
+	cacheControl := ""
+	if config.MaxAge > 0 {
+		cacheControl = "public, max-age=" + strconv.Itoa(int(config.MaxAge.Seconds()))
+	}
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		if cacheControl != "" {
+			responseWriter.Header().Set("Cache-Control", cacheControl)
+		}
+		fileServer.ServeHTTP(responseWriter, request)
+	}
+}
+
+// noListingFS hides directories that have no index.html, so that
+// http.FileServer answers 404 instead of listing their contents.
+type noListingFS struct {
+	fs.FS
+}
+
+func (fsys noListingFS) Open(name string) (fs.File, error) {
+	file, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !info.IsDir() {
+		return file, nil
+	}
+	index, err := fsys.FS.Open(path.Join(name, "index.html"))
+	if err != nil {
+		file.Close()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fs.ErrNotExist
+		}
+		return nil, err
+	}
+	index.Close()
+	return file, nil
+}
@@ -0,0 +1,125 @@
+package routerx
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// RouteSuggestion is a registered path close to a path that matched no
+// route.
+type RouteSuggestion struct {
+	Path    string   `json:"path"`
+	Methods []string `json:"methods"`
+}
+
+// SuggestionsHandler returns a NotFound handler answering 404 with a JSON
+// body that suggests the registered paths closest to the requested one, by
+// edit distance with wildcards matching any segment, and the methods they
+// accept. It helps during client integration but discloses the route
+// table, so only install it outside production.
+//
+// Example:
+//
+//	if os.Getenv("APP_ENV") != "production" {
+//	    router.NotFound(router.SuggestionsHandler())
+//	}
+//
+// GET /api/v1/user/42 then answers:
+//
+//	{"error":"route not found","suggestions":[{"path":"/api/v1/users/{id}","methods":["GET","DELETE"]}]}
+func (router *Router) SuggestionsHandler() http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusNotFound, map[string]any{
+			"error":       "route not found",
+			"suggestions": router.suggestRoutes(request.URL.Path, 3),
+		})
+	}
+}
+
+// suggestRoutes returns up to limit registered paths close to path, closest
+// first.
+func (router *Router) suggestRoutes(path string, limit int) []RouteSuggestion {
+	type candidate struct {
+		suggestion RouteSuggestion
+		distance   int
+	}
+	threshold := max(2, len(path)/4)
+	var candidates []*candidate
+	byPath := make(map[string]*candidate)
+	for _, registered := range router.routes {
+		if registered.preflight || registered.undocumented {
+			continue
+		}
+		existing := byPath[registered.path]
+		if existing == nil {
+			distance := editDistance(path, instantiatePath(registered.path, path))
+			if distance > threshold {
+				continue
+			}
+			existing = &candidate{suggestion: RouteSuggestion{Path: registered.path, Methods: []string{}}, distance: distance}
+			byPath[registered.path] = existing
+			candidates = append(candidates, existing)
+		}
+		method := registered.method
+		if method == "" {
+			method = "*"
+		}
+		if !slices.Contains(existing.suggestion.Methods, method) {
+			existing.suggestion.Methods = append(existing.suggestion.Methods, method)
+		}
+	}
+	slices.SortStableFunc(candidates, func(first *candidate, second *candidate) int {
+		return cmp.Compare(first.distance, second.distance)
+	})
+	suggestions := []RouteSuggestion{}
+	for _, found := range candidates[:min(limit, len(candidates))] {
+		suggestions = append(suggestions, found.suggestion)
+	}
+	return suggestions
+}
+
+// instantiatePath fills the wildcards of a ServeMux path with the segments
+// of path at the same positions, so that "/users/{id}" compared to
+// "/user/42" only differs in the literal segment.
+func instantiatePath(muxPath string, path string) string {
+	patternSegments := strings.Split(strings.TrimPrefix(muxPath, "/"), "/")
+	pathSegments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for index, segment := range patternSegments {
+		if !strings.HasPrefix(segment, "{") {
+			continue
+		}
+		switch {
+		case segment == "{$}":
+			patternSegments[index] = ""
+		case strings.HasSuffix(segment, "...}"):
+			patternSegments = append(patternSegments[:index], pathSegments[min(index, len(pathSegments)):]...)
+			return "/" + strings.Join(patternSegments, "/")
+		case index < len(pathSegments):
+			patternSegments[index] = pathSegments[index]
+		}
+	}
+	return "/" + strings.Join(patternSegments, "/")
+}
+
+// editDistance returns the Levenshtein distance between first and second.
+func editDistance(first string, second string) int {
+	previous := make([]int, len(second)+1)
+	current := make([]int, len(second)+1)
+	for index := range previous {
+		previous[index] = index
+	}
+	for firstIndex := 1; firstIndex <= len(first); firstIndex++ {
+		current[0] = firstIndex
+		for secondIndex := 1; secondIndex <= len(second); secondIndex++ {
+			substitution := previous[secondIndex-1]
+			if first[firstIndex-1] != second[secondIndex-1] {
+				substitution++
+			}
+			current[secondIndex] = min(previous[secondIndex]+1, current[secondIndex-1]+1, substitution)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(second)]
+}
@@ -0,0 +1,176 @@
+package routerx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutConfig configures the Timeout middleware.
+type TimeoutConfig struct {
+	// StatusCode answers requests that exceed the timeout. Defaults to 503
+	// Service Unavailable; use 504 Gateway Timeout for handlers that mostly
+	// wait on upstream services.
+	StatusCode int
+}
+
+type timeoutContextKey struct{}
+
+// Timeout returns a Middleware that gives every request a deadline of
+// duration on its context and answers it with 503 Service Unavailable, or
+// the configured status, when the handler has not started the response by
+// then. Handlers should pass the request context to database and upstream
+// calls so they stop working once the deadline passes; writes after the
+// deadline fail with http.ErrHandlerTimeout. Routes registered on a group or
+// PathBuilder with a Timeout override use that duration instead.
+//
+// Example:
+//
+//	router := routerx.New().Use(routerx.Timeout(5 * time.Second))
+//	router.Path("/reports").Timeout(time.Minute).Post(generateReport)
+func Timeout(duration time.Duration, configs ...TimeoutConfig) Middleware {
+	var config TimeoutConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.StatusCode == 0 {
+		config.StatusCode = http.StatusServiceUnavailable
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			limit := duration
+			if override, ok := request.Context().Value(timeoutContextKey{}).(time.Duration); ok {
+				limit = override
+			}
+			if limit <= 0 {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			ctx, cancel := context.WithTimeout(request.Context(), limit)
+			defer cancel()
+
+			writer := &timeoutWriter{ResponseWriter: responseWriter, header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						panicked <- recovered
+					}
+				}()
+				next.ServeHTTP(writer, request.WithContext(ctx))
+				close(done)
+			}()
+			select {
+			case recovered := <-panicked:
+				panic(recovered)
+			case <-done:
+			case <-ctx.Done():
+				writer.expire(config.StatusCode, fmt.Sprintf("request exceeded the %s timeout", limit))
+			}
+		})
+	}
+}
+
+// Timeout overrides the duration of the Timeout middleware for the routes
+// registered on the group and its nested groups after calling Timeout, e.g.
+// for known slow endpoints. A negative duration disables the timeout.
+//
+// Example:
+//
+//	exports := router.Group("/exports").Timeout(2 * time.Minute)
+func (group *RouteGroup) Timeout(duration time.Duration) *RouteGroup {
+	group.timeout = duration
+	return group
+}
+
+// Timeout overrides the duration of the Timeout middleware for the methods
+// registered on the builder after calling Timeout. A negative duration
+// disables the timeout.
+//
+// Example:
+//
+//	router.Path("/reports").Timeout(time.Minute).Post(generateReport)
+func (builder *PathBuilder) Timeout(duration time.Duration) *PathBuilder {
+	builder.timeout = duration
+	return builder
+}
+
+// timeoutWriter passes a response through until the deadline passes. The
+// handler writes its headers into a separate map, so that the timeout
+// response can be written while the handler is still running.
+type timeoutWriter struct {
+	http.ResponseWriter
+	mutex       sync.Mutex
+	header      http.Header
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (writer *timeoutWriter) Header() http.Header {
+	return writer.header
+}
+
+func (writer *timeoutWriter) WriteHeader(statusCode int) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	writer.writeHeader(statusCode)
+}
+
+func (writer *timeoutWriter) writeHeader(statusCode int) {
+	if writer.timedOut || writer.wroteHeader {
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		writer.copyHeader()
+		writer.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	writer.wroteHeader = true
+	writer.copyHeader()
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (writer *timeoutWriter) copyHeader() {
+	header := writer.ResponseWriter.Header()
+	for key, values := range writer.header {
+		header[key] = values
+	}
+}
+
+func (writer *timeoutWriter) Write(data []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	if writer.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	writer.writeHeader(http.StatusOK)
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *timeoutWriter) Flush() {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	if writer.timedOut {
+		return
+	}
+	writer.writeHeader(http.StatusOK)
+	_ = http.NewResponseController(writer.ResponseWriter).Flush()
+}
+
+func (writer *timeoutWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// expire answers the request with the timeout response unless the handler
+// already started its own, and refuses all further writes.
+func (writer *timeoutWriter) expire(statusCode int, message string) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	if !writer.wroteHeader {
+		writeError(writer.ResponseWriter, statusCode, message)
+	}
+	writer.timedOut = true
+}
@@ -0,0 +1,242 @@
+// Package tracing traces routerx requests with OpenTelemetry. It lives in its
+// own module so that routerx itself keeps no dependencies.
+//
+//	router := routerx.New().Use(tracing.Middleware(tracerProvider))
+//	router.ErrorHandler(tracing.ErrorHandler(routerx.DefaultErrorHandler))
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/Mark-Bazylev/routerx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer of this package.
+const instrumentationName = "github.com/Mark-Bazylev/routerx/tracing"
+
+// Config configures Middleware.
+type Config struct {
+	// Propagator extracts the incoming trace context. Defaults to the
+	// global propagator, see otel.SetTextMapPropagator.
+	Propagator propagation.TextMapPropagator
+
+	// Filter excludes requests from tracing when it returns false, e.g.
+	// health checks.
+	Filter func(request *http.Request) bool
+
+	// PathValues lists the path wildcards recorded on the span as
+	// "http.route.param.<name>", e.g. "tenant" for "/tenants/{tenant}".
+	// Only list values that are neither secret nor unbounded identifiers
+	// you would not search by.
+	PathValues []string
+
+	// Headers lists the request headers recorded on the span as
+	// "http.request.header.<name>", following the OpenTelemetry semantic
+	// conventions.
+	Headers []string
+
+	// Baggage lists the members of the incoming W3C baggage recorded on the
+	// span as "baggage.<key>", e.g. a tenant or experiment set upstream.
+	Baggage []string
+}
+
+// Middleware returns a routerx.Middleware that starts a server span for every
+// request with a tracer from provider, continuing the trace propagated by the
+// client. Spans are named after the matched route pattern, such as
+// "GET /users/{id}", never the raw URL, and record the request method, route,
+// path, and user agent, the response status code, and an error status for
+// 5xx responses. Use it on the router so that the route pattern is known.
+//
+// Example:
+//
+//	router := routerx.New().Use(tracing.Middleware(otel.GetTracerProvider()))
+func Middleware(provider trace.TracerProvider, configs ...Config) routerx.Middleware {
+	var config Config
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	tracer := provider.Tracer(instrumentationName)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			if config.Filter != nil && !config.Filter(request) {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			propagator := config.Propagator
+			if propagator == nil {
+				propagator = otel.GetTextMapPropagator()
+			}
+			ctx := propagator.Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+
+			route := routePath(request.Pattern)
+			attributes := []attribute.KeyValue{
+				attribute.String("http.request.method", request.Method),
+				attribute.String("url.path", request.URL.Path),
+				attribute.String("url.scheme", scheme(request)),
+				attribute.String("server.address", request.Host),
+			}
+			if route != "" {
+				attributes = append(attributes, attribute.String("http.route", route))
+			}
+			if userAgent := request.UserAgent(); userAgent != "" {
+				attributes = append(attributes, attribute.String("user_agent.original", userAgent))
+			}
+			attributes = append(attributes, config.allowlisted(ctx, request)...)
+			ctx, span := tracer.Start(ctx, spanName(request.Method, route),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attributes...))
+			defer span.End()
+
+			writer := &statusWriter{ResponseWriter: responseWriter}
+			next.ServeHTTP(writer, request.WithContext(ctx))
+
+			status := writer.status()
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
+
+// allowlisted returns the attributes for the path values, headers, and
+// baggage members listed in the config.
+func (config *Config) allowlisted(ctx context.Context, request *http.Request) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+	for _, name := range config.PathValues {
+		if value := request.PathValue(name); value != "" {
+			attributes = append(attributes, attribute.String("http.route.param."+name, value))
+		}
+	}
+	for _, name := range config.Headers {
+		if values := request.Header.Values(name); len(values) > 0 {
+			attributes = append(attributes, attribute.StringSlice("http.request.header."+strings.ToLower(name), values))
+		}
+	}
+	if len(config.Baggage) > 0 {
+		members := baggage.FromContext(ctx)
+		for _, key := range config.Baggage {
+			if member := members.Member(key); member.Key() != "" {
+				attributes = append(attributes, attribute.String("baggage."+key, member.Value()))
+			}
+		}
+	}
+	return attributes
+}
+
+// Attributes returns a routerx.Middleware that adds attributes to the span
+// of every request it handles, to annotate routes or groups with metadata
+// such as the owning team, tier, or feature so that traces can be searched
+// by them. It requires Middleware on the router.
+//
+// Example:
+//
+//	checkout := router.Group("/checkout").Use(tracing.Attributes(
+//	    attribute.String("service.owner", "payments"),
+//	    attribute.String("service.tier", "critical"),
+//	))
+func Attributes(attributes ...attribute.KeyValue) routerx.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			trace.SpanFromContext(request.Context()).SetAttributes(attributes...)
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
+
+// ErrorHandler returns a routerx.ErrorHandlerFunc that records errors
+// returned by handlers on the request span before passing them to next.
+//
+// Example:
+//
+//	router.ErrorHandler(tracing.ErrorHandler(routerx.ProblemErrorHandler))
+func ErrorHandler(next routerx.ErrorHandlerFunc) routerx.ErrorHandlerFunc {
+	if next == nil {
+		next = routerx.DefaultErrorHandler
+	}
+	return func(responseWriter http.ResponseWriter, request *http.Request, err error) {
+		span := trace.SpanFromContext(request.Context())
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		next(responseWriter, request, err)
+	}
+}
+
+// TraceID returns the trace ID of the request's span when it is sampled, or
+// "". Use it as routerx.MetricsConfig.TraceID to attach exemplars to the
+// latency histogram, with the metrics middleware after Middleware.
+//
+// Example:
+//
+//	metrics := routerx.NewMetrics(routerx.MetricsConfig{TraceID: tracing.TraceID})
+//	router := routerx.New().Use(tracing.Middleware(tracerProvider), metrics.Middleware())
+func TraceID(request *http.Request) string {
+	spanContext := trace.SpanContextFromContext(request.Context())
+	if !spanContext.IsSampled() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// routePath returns the path of a ServeMux pattern, without its method.
+func routePath(pattern string) string {
+	if _, path, found := strings.Cut(pattern, " "); found {
+		return path
+	}
+	return pattern
+}
+
+// spanName follows the OpenTelemetry convention "{method} {route}", falling
+// back to the method alone for unmatched requests.
+func spanName(method string, route string) string {
+	if route == "" {
+		return method
+	}
+	return method + " " + route
+}
+
+func scheme(request *http.Request) string {
+	if request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (writer *statusWriter) WriteHeader(statusCode int) {
+	if writer.statusCode == 0 {
+		writer.statusCode = statusCode
+	}
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (writer *statusWriter) Write(data []byte) (int, error) {
+	if writer.statusCode == 0 {
+		writer.statusCode = http.StatusOK
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *statusWriter) status() int {
+	if writer.statusCode == 0 {
+		return http.StatusOK
+	}
+	return writer.statusCode
+}
+
+func (writer *statusWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
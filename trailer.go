@@ -0,0 +1,133 @@
+package routerx
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TrailerConfig declares the HTTP trailers sent by a TrailerWriter. Empty
+// names disable the corresponding built-in trailer.
+type TrailerConfig struct {
+	// Trailers lists additional trailer names that the handler sets with
+	// TrailerWriter.SetTrailer.
+	Trailers []string
+
+	// ChecksumTrailer receives the SHA-256 digest of the body in the
+	// structured field format of RFC 9530, e.g. "Content-Digest".
+	ChecksumTrailer string
+
+	// CountTrailer receives the number of records written with WriteRecord.
+	CountTrailer string
+
+	// StatusTrailer receives "ok" or the error passed to Close, so clients
+	// can tell a complete stream from one that failed after the headers
+	// were sent.
+	StatusTrailer string
+}
+
+// TrailerWriter streams a chunked response body and sends HTTP trailers after
+// it, such as a checksum, a record count, or the final status of the stream.
+// Trailers are only delivered to clients that read them, e.g. Go's
+// http.Response.Trailer or gRPC-style consumers.
+//
+// Example:
+//
+//	router.Get("/export", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    stream := routerx.NewTrailerWriter(responseWriter, routerx.TrailerConfig{
+//	        ChecksumTrailer: "Content-Digest",
+//	        CountTrailer:    "X-Record-Count",
+//	        StatusTrailer:   "X-Stream-Status",
+//	    })
+//	    responseWriter.Header().Set("Content-Type", "application/x-ndjson")
+//	    var err error
+//	    for record := range records(request.Context()) {
+//	        if err = stream.WriteRecord(record); err != nil {
+//	            break
+//	        }
+//	    }
+//	    stream.Close(err)
+//	})
+type TrailerWriter struct {
+	responseWriter http.ResponseWriter
+	config         TrailerConfig
+	checksum       hash.Hash
+	records        int
+}
+
+// NewTrailerWriter declares the configured trailers on responseWriter. It
+// must be called before anything is written to the response.
+func NewTrailerWriter(responseWriter http.ResponseWriter, config TrailerConfig) *TrailerWriter {
+	names := append([]string(nil), config.Trailers...)
+	for _, name := range []string{config.ChecksumTrailer, config.CountTrailer, config.StatusTrailer} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		responseWriter.Header().Set("Trailer", strings.Join(names, ", "))
+	}
+	writer := &TrailerWriter{responseWriter: responseWriter, config: config}
+	if config.ChecksumTrailer != "" {
+		writer.checksum = sha256.New()
+	}
+	return writer
+}
+
+// Write writes data to the body and flushes it to the client.
+func (writer *TrailerWriter) Write(data []byte) (int, error) {
+	written, err := writer.responseWriter.Write(data)
+	if writer.checksum != nil {
+		writer.checksum.Write(data[:written])
+	}
+	if err == nil {
+		if flushErr := http.NewResponseController(writer.responseWriter).Flush(); !errors.Is(flushErr, http.ErrNotSupported) {
+			err = flushErr
+		}
+	}
+	return written, err
+}
+
+// WriteRecord writes record as one line of newline-delimited JSON and counts
+// it for the CountTrailer.
+func (writer *TrailerWriter) WriteRecord(record any) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	writer.records++
+	return nil
+}
+
+// SetTrailer sets the value of a trailer declared in TrailerConfig.Trailers.
+// Values set before the body is complete are sent with the trailers.
+func (writer *TrailerWriter) SetTrailer(name string, value string) {
+	writer.responseWriter.Header().Set(name, value)
+}
+
+// Close sets the built-in trailers. err is the reason the stream ended early,
+// or nil when it completed. The trailers are sent when the handler returns.
+func (writer *TrailerWriter) Close(err error) {
+	header := writer.responseWriter.Header()
+	if writer.checksum != nil {
+		header.Set(writer.config.ChecksumTrailer, "sha-256=:"+base64.StdEncoding.EncodeToString(writer.checksum.Sum(nil))+":")
+	}
+	if writer.config.CountTrailer != "" {
+		header.Set(writer.config.CountTrailer, strconv.Itoa(writer.records))
+	}
+	if writer.config.StatusTrailer != "" {
+		status := "ok"
+		if err != nil {
+			status = "error: " + strings.ReplaceAll(err.Error(), "\n", " ")
+		}
+		header.Set(writer.config.StatusTrailer, status)
+	}
+}
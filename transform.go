@@ -0,0 +1,126 @@
+package routerx
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// TransformConfig declares how the Transform middleware rewrites JSON
+// response bodies. Field paths use dots to address nested objects, e.g.
+// "user.first_name"; arrays are traversed transparently, so "items.id"
+// addresses the id of every element of items. The steps run in the order
+// Remove, Rename, Case, Envelope.
+type TransformConfig struct {
+	// Remove lists field paths that are deleted from the response.
+	Remove []string
+
+	// Rename maps field paths to their new key, e.g.
+	// {"user.first_name": "given_name"}. Only the last key of the path
+	// changes.
+	Rename map[string]string
+
+	// Case converts every key of the response to the given convention.
+	Case KeyCase
+
+	// Envelope, when set, wraps the transformed body in an object under this
+	// key, e.g. "data" turns [1,2] into {"data":[1,2]}.
+	Envelope string
+}
+
+// Transform returns a Middleware that rewrites JSON responses according to
+// config. It buffers the response of the next handler and leaves non-JSON
+// responses and invalid JSON untouched, which makes it a convenient way to
+// adapt legacy backend responses to a new API contract at the edge.
+//
+// Example:
+//
+//	legacy := router.Group("/v2").Use(routerx.Transform(routerx.TransformConfig{
+//	    Remove: []string{"internal_id"},
+//	    Rename: map[string]string{"user.fname": "first_name"},
+//	    Case:   routerx.CamelCase,
+//	}))
+func Transform(config TransformConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			buffered := newBufferedResponseWriter()
+			next.ServeHTTP(buffered, request)
+
+			body := buffered.body.Bytes()
+			if !isJSONContentType(buffered.header.Get("Content-Type")) || len(bytes.TrimSpace(body)) == 0 {
+				buffered.flushTo(responseWriter, body)
+				return
+			}
+			var document any
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+			if err := decoder.Decode(&document); err != nil {
+				buffered.flushTo(responseWriter, body)
+				return
+			}
+
+			document = config.apply(document)
+			transformed, err := json.Marshal(document)
+			if err != nil {
+				buffered.flushTo(responseWriter, body)
+				return
+			}
+			buffered.flushTo(responseWriter, append(transformed, '\n'))
+		})
+	}
+}
+
+// apply runs every configured transformation on a decoded JSON document.
+func (config TransformConfig) apply(document any) any {
+	for _, path := range config.Remove {
+		walkFieldPath(document, strings.Split(path, "."), func(object map[string]any, key string) {
+			delete(object, key)
+		})
+	}
+	for path, newKey := range config.Rename {
+		walkFieldPath(document, strings.Split(path, "."), func(object map[string]any, key string) {
+			if value, found := object[key]; found {
+				delete(object, key)
+				object[newKey] = value
+			}
+		})
+	}
+	if config.Case != KeepCase {
+		document = convertKeys(document, config.Case.convert)
+	}
+	if config.Envelope != "" {
+		document = map[string]any{config.Envelope: document}
+	}
+	return document
+}
+
+// walkFieldPath calls visit with the object holding the last key of path for
+// every place the path addresses inside value.
+func walkFieldPath(value any, path []string, visit func(object map[string]any, key string)) {
+	switch typed := value.(type) {
+	case []any:
+		for _, element := range typed {
+			walkFieldPath(element, path, visit)
+		}
+	case map[string]any:
+		if len(path) == 1 {
+			visit(typed, path[0])
+			return
+		}
+		if child, found := typed[path[0]]; found {
+			walkFieldPath(child, path[1:], visit)
+		}
+	}
+}
+
+// isJSONContentType reports whether contentType denotes a JSON document,
+// including structured syntax suffixes such as application/problem+json.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,95 @@
+package routerx
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// routeUsage holds the counters of a route.
+type routeUsage struct {
+	hits         atomic.Uint64
+	clientErrors atomic.Uint64
+	serverErrors atomic.Uint64
+	lastHit      atomic.Int64
+}
+
+// record counts a request answered with status.
+func (usage *routeUsage) record(status int, now time.Time) {
+	usage.hits.Add(1)
+	usage.lastHit.Store(now.UnixNano())
+	switch {
+	case status >= http.StatusInternalServerError:
+		usage.serverErrors.Add(1)
+	case status >= http.StatusBadRequest:
+		usage.clientErrors.Add(1)
+	}
+}
+
+// TrackUsage counts the requests served by every route, their 4xx and 5xx
+// responses, and the time of the last request, for Router.Usage and
+// Router.UsageHandler. The counters live in memory and start at zero with
+// the process, so they show which routes are still called and which are
+// dead before removing them. TrackUsage returns the Router to support
+// chaining.
+//
+// Example:
+//
+//	router := routerx.New().TrackUsage()
+//	admin.Get("/routes/usage", router.UsageHandler())
+func (router *Router) TrackUsage() *Router {
+	router.trackUsage = true
+	return router
+}
+
+// RouteUsage is the usage of a route since the process started.
+type RouteUsage struct {
+	RouteInfo
+
+	Hits         uint64 `json:"hits"`
+	ClientErrors uint64 `json:"client_errors"`
+	ServerErrors uint64 `json:"server_errors"`
+
+	// ErrorRate is the fraction of requests answered with a 5xx status.
+	ErrorRate float64 `json:"error_rate"`
+
+	// LastHit is the time of the last request, or nil if the route was
+	// never called.
+	LastHit *time.Time `json:"last_hit"`
+}
+
+// Usage returns the usage of every registered route in registration order,
+// including the routes never called. It is empty unless TrackUsage is
+// enabled.
+func (router *Router) Usage() []RouteUsage {
+	if !router.trackUsage {
+		return nil
+	}
+	usages := make([]RouteUsage, 0, len(router.routes))
+	for _, registered := range router.routes {
+		if registered.preflight {
+			continue
+		}
+		usage := RouteUsage{
+			RouteInfo:    RouteInfo{Method: registered.method, Path: registered.path, Name: registered.name},
+			Hits:         registered.usage.hits.Load(),
+			ClientErrors: registered.usage.clientErrors.Load(),
+			ServerErrors: registered.usage.serverErrors.Load(),
+		}
+		if usage.Hits > 0 {
+			usage.ErrorRate = float64(usage.ServerErrors) / float64(usage.Hits)
+			lastHit := time.Unix(0, registered.usage.lastHit.Load())
+			usage.LastHit = &lastHit
+		}
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+// UsageHandler returns a handler serving Usage as JSON. Mount it on an
+// administrative route.
+func (router *Router) UsageHandler() http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusOK, router.Usage())
+	}
+}
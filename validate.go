@@ -0,0 +1,285 @@
+package routerx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FieldError describes one invalid field of a request.
+type FieldError struct {
+	// Field is the name the client used for the field: the json, path,
+	// query, or form tag name, or the Go field name. Nested fields are
+	// joined with dots.
+	Field string `json:"field"`
+
+	Message string `json:"message"`
+}
+
+// ValidationError lists the invalid fields of a bound request. Its status
+// code is 422 Unprocessable Entity, and DefaultErrorHandler answers it with
+// {"error": "validation failed", "fields": [{"field": ..., "message": ...}]}.
+type ValidationError struct {
+	Fields []FieldError
+
+	// Err is the error returned by the validator, if any.
+	Err error
+}
+
+func (err *ValidationError) Error() string {
+	messages := make([]string, 0, len(err.Fields))
+	for _, field := range err.Fields {
+		if field.Field == "" {
+			messages = append(messages, field.Message)
+		} else {
+			messages = append(messages, field.Field+": "+field.Message)
+		}
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// StatusCode returns 422 Unprocessable Entity.
+func (err *ValidationError) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// Unwrap returns the error returned by the validator.
+func (err *ValidationError) Unwrap() error {
+	return err.Err
+}
+
+// ValidatorFunc validates a bound request value. It may return a
+// *ValidationError to report field errors; other errors are reported as a
+// single error without a field.
+type ValidatorFunc func(value any) error
+
+var (
+	validatorMutex sync.RWMutex
+	validator      ValidatorFunc = ValidateTags
+)
+
+// SetValidator replaces the validator run by Bind and Validate, which
+// defaults to ValidateTags. Passing nil disables tag validation; Validate()
+// methods are still called.
+//
+// Example:
+//
+//	validate := validator.New(validator.WithRequiredStructEnabled())
+//	routerx.SetValidator(func(value any) error {
+//	    var fieldErrors validator.ValidationErrors
+//	    if err := validate.Struct(value); !errors.As(err, &fieldErrors) {
+//	        return err
+//	    }
+//	    result := &routerx.ValidationError{}
+//	    for _, fieldError := range fieldErrors {
+//	        result.Fields = append(result.Fields, routerx.FieldError{Field: fieldError.Field(), Message: fieldError.Tag()})
+//	    }
+//	    return result
+//	})
+func SetValidator(validate ValidatorFunc) {
+	validatorMutex.Lock()
+	defer validatorMutex.Unlock()
+	validator = validate
+}
+
+// Validate runs the configured validator on value and then, when value
+// implements Validate() error, its own validation. Failures are returned as
+// a *ValidationError. Bind calls Validate after binding, so handlers using
+// Bind or Handle receive only valid requests.
+//
+// Example:
+//
+//	func (request CreateUserRequest) Validate() error {
+//	    if request.Password == request.Name {
+//	        return &routerx.ValidationError{Fields: []routerx.FieldError{{Field: "password", Message: "must differ from name"}}}
+//	    }
+//	    return nil
+//	}
+func Validate(value any) error {
+	validatorMutex.RLock()
+	validate := validator
+	validatorMutex.RUnlock()
+	if validate != nil {
+		if err := validate(value); err != nil {
+			return asValidationError(err)
+		}
+	}
+	if validatable, ok := value.(interface{ Validate() error }); ok {
+		if err := validatable.Validate(); err != nil {
+			return asValidationError(err)
+		}
+	}
+	return nil
+}
+
+func asValidationError(err error) *ValidationError {
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		return validationError
+	}
+	return &ValidationError{Fields: []FieldError{{Message: err.Error()}}, Err: err}
+}
+
+// ValidateTags is the built-in validator. It checks the rules listed in
+// `validate` struct tags, separated by commas, and descends into nested
+// structs:
+//
+//   - required: the value is not the zero value
+//   - min=n, max=n: numbers are at least or at most n; strings have at least
+//     or at most n characters, slices and maps n elements
+//   - len=n: strings have exactly n characters, slices and maps n elements
+//   - oneof=a b c: the value is one of the space-separated options
+//   - email: the string is an email address
+//   - omitempty: the other rules are skipped when the value is the zero
+//     value, e.g. for optional fields
+//
+// As with go-playground/validator, the rules apply to zero values too, so
+// `validate:"min=1"` rejects 0 and `validate:"email"` rejects "". Optional
+// fields list omitempty first.
+//
+// Example:
+//
+//	type CreateUserRequest struct {
+//	    Name  string `json:"name" validate:"required,max=64"`
+//	    Email string `json:"email" validate:"required,email"`
+//	    Role  string `json:"role" validate:"omitempty,oneof=admin member"`
+//	}
+func ValidateTags(value any) error {
+	target := reflect.ValueOf(value)
+	for target.Kind() == reflect.Pointer {
+		if target.IsNil() {
+			return nil
+		}
+		target = target.Elem()
+	}
+	if target.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []FieldError
+	validateStruct(target, "", &fields)
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func validateStruct(target reflect.Value, prefix string, fields *[]FieldError) {
+	for index := 0; index < target.NumField(); index++ {
+		field := target.Type().Field(index)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + fieldName(field)
+		value := target.Field(index)
+		if rules := field.Tag.Get("validate"); rules != "" && rules != "-" && (!value.IsZero() || !hasOmitEmpty(rules)) {
+			for _, rule := range strings.Split(rules, ",") {
+				if message := checkRule(value, strings.TrimSpace(rule)); message != "" {
+					*fields = append(*fields, FieldError{Field: name, Message: message})
+					break
+				}
+			}
+		}
+		for value.Kind() == reflect.Pointer && !value.IsNil() {
+			value = value.Elem()
+		}
+		if value.Kind() == reflect.Struct && !implementsTextUnmarshaler(value) {
+			validateStruct(value, name+".", fields)
+		}
+	}
+}
+
+// fieldName returns the name clients use for field.
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "path", "query", "form"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// hasOmitEmpty reports whether the validate rules list omitempty.
+func hasOmitEmpty(rules string) bool {
+	return slices.ContainsFunc(strings.Split(rules, ","), func(rule string) bool {
+		return strings.TrimSpace(rule) == "omitempty"
+	})
+}
+
+// checkRule returns the message for value violating rule, or "". Nil
+// pointers are checked as the zero value they point to.
+func checkRule(value reflect.Value, rule string) string {
+	name, argument, _ := strings.Cut(rule, "=")
+	switch name {
+	case "required":
+		if value.IsZero() {
+			return "is required"
+		}
+		return ""
+	case "omitempty":
+		return ""
+	}
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			value = reflect.Zero(value.Type().Elem())
+			continue
+		}
+		value = value.Elem()
+	}
+	switch name {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(argument, 64)
+		if err != nil {
+			return "has an invalid " + name + " rule"
+		}
+		size, unit := ruleSize(value)
+		switch {
+		case name == "min" && size < limit && unit != "":
+			return fmt.Sprintf("must have at least %s %s", argument, unit)
+		case name == "min" && size < limit:
+			return "must be at least " + argument
+		case name == "max" && size > limit && unit != "":
+			return fmt.Sprintf("must have at most %s %s", argument, unit)
+		case name == "max" && size > limit:
+			return "must be at most " + argument
+		case name == "len" && size != limit:
+			return fmt.Sprintf("must have exactly %s %s", argument, unit)
+		}
+	case "oneof":
+		options := strings.Fields(argument)
+		if !slices.Contains(options, fmt.Sprint(value.Interface())) {
+			return "must be one of " + strings.Join(options, ", ")
+		}
+	case "email":
+		address, err := mail.ParseAddress(value.String())
+		if value.Kind() != reflect.String || err != nil || address.Address != value.String() {
+			return "must be an email address"
+		}
+	default:
+		return "has an unknown rule " + strconv.Quote(name)
+	}
+	return ""
+}
+
+// ruleSize returns the number compared by min, max, and len rules and, for
+// lengths, what is counted.
+func ruleSize(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(len([]rune(value.String()))), "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), "elements"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+	return 0, ""
+}
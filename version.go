@@ -0,0 +1,112 @@
+package routerx
+
+import (
+	"maps"
+	"net/http"
+	"runtime/debug"
+)
+
+// VersionInfo configures Router.Version.
+type VersionInfo struct {
+	// Path of the version endpoint. Defaults to "/version".
+	Path string
+
+	// BuildzPath, when set, adds an endpoint with the complete build
+	// information: the build settings and every dependency module. It
+	// tells attackers which vulnerable versions to look for, so keep it
+	// internal.
+	BuildzPath string
+
+	// Version overrides the version of the main module, which is
+	// "(devel)" for binaries built from a checkout, e.g. with a version
+	// set by -ldflags "-X main.version=...".
+	Version string
+
+	// Fields are added to the version document, e.g. the environment or
+	// region of the deployment.
+	Fields map[string]any
+}
+
+// Version registers an endpoint reporting what is deployed: the version of
+// the main module, the VCS revision and commit time, whether the working
+// tree was modified, and the Go version, read with debug.ReadBuildInfo,
+// plus the configured fields. The endpoints run the router's middlewares and
+// are left out of the OpenAPI document.
+//
+// Example:
+//
+//	router.Version(routerx.VersionInfo{
+//	    BuildzPath: "/buildz",
+//	    Version:    version,
+//	    Fields:     map[string]any{"environment": os.Getenv("ENVIRONMENT")},
+//	})
+//	// GET /version → {"version":"v1.4.2","revision":"8f3c2e1…","build_time":"2024-05-02T09:12:44Z",…}
+func (router *Router) Version(info VersionInfo) {
+	if info.Path == "" {
+		info.Path = "/version"
+	}
+	build, _ := debug.ReadBuildInfo()
+	document := versionDocument(build, info)
+	router.register("GET", cleanPath(info.Path), http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusOK, document)
+	}), router.middlewares, nil).undocumented = true
+
+	if info.BuildzPath == "" {
+		return
+	}
+	buildz := buildzDocument(build)
+	router.register("GET", cleanPath(info.BuildzPath), http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusOK, buildz)
+	}), router.middlewares, nil).undocumented = true
+}
+
+// versionDocument returns the body of the version endpoint.
+func versionDocument(build *debug.BuildInfo, info VersionInfo) map[string]any {
+	document := make(map[string]any, len(info.Fields)+6)
+	maps.Copy(document, info.Fields)
+	if build != nil {
+		document["module"] = build.Main.Path
+		document["version"] = build.Main.Version
+		document["go_version"] = build.GoVersion
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				document["revision"] = setting.Value
+			case "vcs.time":
+				document["build_time"] = setting.Value
+			case "vcs.modified":
+				document["modified"] = setting.Value == "true"
+			}
+		}
+	}
+	if info.Version != "" {
+		document["version"] = info.Version
+	}
+	return document
+}
+
+// buildzDocument returns the body of the buildz endpoint.
+func buildzDocument(build *debug.BuildInfo) map[string]any {
+	if build == nil {
+		return map[string]any{}
+	}
+	settings := make(map[string]string, len(build.Settings))
+	for _, setting := range build.Settings {
+		settings[setting.Key] = setting.Value
+	}
+	dependencies := make([]map[string]string, 0, len(build.Deps))
+	for _, module := range build.Deps {
+		dependency := map[string]string{"path": module.Path, "version": module.Version, "sum": module.Sum}
+		if module.Replace != nil {
+			dependency["replace"] = module.Replace.Path + "@" + module.Replace.Version
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return map[string]any{
+		"go_version":   build.GoVersion,
+		"path":         build.Path,
+		"main":         map[string]string{"path": build.Main.Path, "version": build.Main.Version, "sum": build.Main.Sum},
+		"settings":     settings,
+		"dependencies": dependencies,
+	}
+}
@@ -0,0 +1,474 @@
+package routerx
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocketMessageType is the type of a WebSocket data message.
+type WebSocketMessageType int
+
+const (
+	WebSocketText   WebSocketMessageType = 1
+	WebSocketBinary WebSocketMessageType = 2
+)
+
+// WebSocket close codes, see RFC 6455 section 7.4.1.
+const (
+	WebSocketCloseNormal          = 1000
+	WebSocketCloseGoingAway       = 1001
+	WebSocketCloseProtocolError   = 1002
+	WebSocketCloseUnsupportedData = 1003
+	WebSocketCloseNoStatus        = 1005
+	WebSocketCloseInvalidPayload  = 1007
+	WebSocketClosePolicyViolation = 1008
+	WebSocketCloseMessageTooBig   = 1009
+	WebSocketCloseInternalError   = 1011
+)
+
+const (
+	webSocketContinuation = 0x0
+	webSocketClose        = 0x8
+	webSocketPing         = 0x9
+	webSocketPong         = 0xa
+)
+
+// webSocketGUID is appended to the client key to compute the accept key,
+// see RFC 6455 section 1.3.
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocketConfig configures Upgrade and PathBuilder.WebSocket.
+type WebSocketConfig struct {
+	// Subprotocols lists the subprotocols the server speaks, in order of
+	// preference. The first one the client offers is selected.
+	Subprotocols []string
+
+	// CheckOrigin decides whether a browser page of another origin may
+	// connect. Defaults to accepting requests without an Origin header and
+	// requests whose Origin host equals the Host header, which protects
+	// cookie-authenticated endpoints from cross-site WebSocket hijacking.
+	CheckOrigin func(request *http.Request) bool
+
+	// MaxMessageSize is the largest message accepted from the client, in
+	// bytes; larger messages close the connection with 1009. Defaults to
+	// 1 MiB.
+	MaxMessageSize int64
+}
+
+// WebSocketCloseError is returned by WebSocketConn.ReadMessage when the peer
+// closed the connection.
+type WebSocketCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (err *WebSocketCloseError) Error() string {
+	if err.Reason == "" {
+		return fmt.Sprintf("websocket closed with code %d", err.Code)
+	}
+	return fmt.Sprintf("websocket closed with code %d: %s", err.Code, err.Reason)
+}
+
+// WebSocketConn is a server-side WebSocket connection created by Upgrade.
+// Reads must happen on one goroutine at a time; writes may happen
+// concurrently with each other and with reads.
+type WebSocketConn struct {
+	conn           net.Conn
+	reader         *bufio.Reader
+	subprotocol    string
+	maxMessageSize int64
+
+	writeMutex sync.Mutex
+	closeOnce  sync.Once
+	closeSent  bool
+}
+
+// Upgrade performs the RFC 6455 opening handshake and returns the
+// WebSocket connection taking over the request's connection. Because the
+// handshake answers 101 Switching Protocols through responseWriter, the
+// middlewares of the route see it like any other response: authentication
+// runs before it and access logs record it. A request that is not a valid
+// WebSocket handshake is answered through the router's error handler, see
+// HandleError, and Upgrade returns the error.
+//
+// Example:
+//
+//	router.Get("/echo", func(responseWriter http.ResponseWriter, request *http.Request) {
+//	    conn, err := routerx.Upgrade(responseWriter, request)
+//	    if err != nil {
+//	        return
+//	    }
+//	    defer conn.Close(routerx.WebSocketCloseNormal, "")
+//	    for {
+//	        messageType, data, err := conn.ReadMessage()
+//	        if err != nil {
+//	            return
+//	        }
+//	        conn.WriteMessage(messageType, data)
+//	    }
+//	})
+func Upgrade(responseWriter http.ResponseWriter, request *http.Request, configs ...WebSocketConfig) (*WebSocketConn, error) {
+	var config WebSocketConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.CheckOrigin == nil {
+		config.CheckOrigin = sameOrigin
+	}
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = 1 << 20
+	}
+
+	fail := func(status int, message string) (*WebSocketConn, error) {
+		err := NewHTTPError(status, message)
+		HandleError(responseWriter, request, err)
+		return nil, err
+	}
+	if request.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, "websocket handshake must use GET")
+	}
+	if !headerContainsToken(request.Header, "Connection", "upgrade") || !headerContainsToken(request.Header, "Upgrade", "websocket") {
+		responseWriter.Header().Set("Upgrade", "websocket")
+		return fail(http.StatusUpgradeRequired, "websocket upgrade required")
+	}
+	if request.Header.Get("Sec-WebSocket-Version") != "13" {
+		responseWriter.Header().Set("Sec-WebSocket-Version", "13")
+		return fail(http.StatusBadRequest, "unsupported websocket version")
+	}
+	key := request.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	if !config.CheckOrigin(request) {
+		return fail(http.StatusForbidden, "websocket origin not allowed")
+	}
+
+	subprotocol := ""
+	offered := headerTokens(request.Header, "Sec-WebSocket-Protocol")
+	for _, supported := range config.Subprotocols {
+		if containsFold(offered, supported) {
+			subprotocol = supported
+			break
+		}
+	}
+
+	header := responseWriter.Header()
+	header.Set("Upgrade", "websocket")
+	header.Set("Connection", "Upgrade")
+	header.Set("Sec-WebSocket-Accept", webSocketAccept(key))
+	if subprotocol != "" {
+		header.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+	responseWriter.WriteHeader(http.StatusSwitchingProtocols)
+	conn, buffered, err := http.NewResponseController(responseWriter).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("routerx: websocket hijack: %w", err)
+	}
+	// Clear the deadlines of the server's timeouts, which would otherwise
+	// cut long-lived connections.
+	_ = conn.SetDeadline(time.Time{})
+	if err := buffered.Writer.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &WebSocketConn{
+		conn:           conn,
+		reader:         buffered.Reader,
+		subprotocol:    subprotocol,
+		maxMessageSize: config.MaxMessageSize,
+	}, nil
+}
+
+// WebSocket registers a GET route at the builder's path that upgrades
+// requests to WebSocket connections and serves them with handler. The
+// builder's middlewares run before the handshake. The connection is closed
+// normally when handler returns, unless handler closed it already.
+//
+// Example:
+//
+//	router.Path("/chat").
+//	    Use(requireLogin).
+//	    WebSocket(func(conn *routerx.WebSocketConn, request *http.Request) {
+//	        for {
+//	            var message ChatMessage
+//	            if err := conn.ReadJSON(&message); err != nil {
+//	                return
+//	            }
+//	            hub.Broadcast(message)
+//	        }
+//	    }, routerx.WebSocketConfig{Subprotocols: []string{"chat.v1"}})
+func (builder *PathBuilder) WebSocket(handler func(conn *WebSocketConn, request *http.Request), configs ...WebSocketConfig) *PathBuilder {
+	builder.register("GET", http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		conn, err := Upgrade(responseWriter, request, configs...)
+		if err != nil {
+			return
+		}
+		defer conn.Close(WebSocketCloseNormal, "")
+		handler(conn, request)
+	}))
+	return builder
+}
+
+// Subprotocol returns the negotiated subprotocol, or "".
+func (conn *WebSocketConn) Subprotocol() string {
+	return conn.subprotocol
+}
+
+// RemoteAddr returns the network address of the client.
+func (conn *WebSocketConn) RemoteAddr() net.Addr {
+	return conn.conn.RemoteAddr()
+}
+
+// SetReadDeadline sets the deadline of the pending and future reads.
+func (conn *WebSocketConn) SetReadDeadline(deadline time.Time) error {
+	return conn.conn.SetReadDeadline(deadline)
+}
+
+// SetWriteDeadline sets the deadline of the pending and future writes.
+func (conn *WebSocketConn) SetWriteDeadline(deadline time.Time) error {
+	return conn.conn.SetWriteDeadline(deadline)
+}
+
+// ReadMessage returns the next data message, reassembled from its
+// fragments. Pings are answered while reading. When the client closes the
+// connection, the close is acknowledged and ReadMessage returns a
+// *WebSocketCloseError; protocol violations close the connection with the
+// matching close code.
+func (conn *WebSocketConn) ReadMessage() (WebSocketMessageType, []byte, error) {
+	var messageType WebSocketMessageType
+	var message []byte
+	for {
+		final, opcode, payload, err := conn.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case webSocketPing:
+			if err := conn.writeFrame(webSocketPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case webSocketPong:
+			continue
+		case webSocketClose:
+			closeErr := &WebSocketCloseError{Code: WebSocketCloseNoStatus}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			reply := closeErr.Code
+			if reply == WebSocketCloseNoStatus {
+				reply = WebSocketCloseNormal
+			}
+			conn.Close(reply, "")
+			return 0, nil, closeErr
+		case webSocketContinuation:
+			if messageType == 0 {
+				return 0, nil, conn.fail(WebSocketCloseProtocolError, "unexpected continuation frame")
+			}
+		case int(WebSocketText), int(WebSocketBinary):
+			if messageType != 0 {
+				return 0, nil, conn.fail(WebSocketCloseProtocolError, "expected continuation frame")
+			}
+			messageType = WebSocketMessageType(opcode)
+		default:
+			return 0, nil, conn.fail(WebSocketCloseProtocolError, "unknown opcode")
+		}
+		if int64(len(message)+len(payload)) > conn.maxMessageSize {
+			return 0, nil, conn.fail(WebSocketCloseMessageTooBig, "message too big")
+		}
+		message = append(message, payload...)
+		if !final {
+			continue
+		}
+		if messageType == WebSocketText && !utf8.Valid(message) {
+			return 0, nil, conn.fail(WebSocketCloseInvalidPayload, "invalid UTF-8")
+		}
+		return messageType, message, nil
+	}
+}
+
+// readFrame reads a frame and unmasks its payload.
+func (conn *WebSocketConn) readFrame() (bool, int, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(conn.reader, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	final := head[0]&0x80 != 0
+	opcode := int(head[0] & 0x0f)
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, conn.fail(WebSocketCloseProtocolError, "reserved bits set")
+	}
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, conn.fail(WebSocketCloseProtocolError, "client frames must be masked")
+	}
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(conn.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(conn.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(extended[:]) & (1<<63 - 1))
+	}
+	if opcode >= webSocketClose && (length > 125 || !final) {
+		return false, 0, nil, conn.fail(WebSocketCloseProtocolError, "invalid control frame")
+	}
+	if length > conn.maxMessageSize {
+		return false, 0, nil, conn.fail(WebSocketCloseMessageTooBig, "message too big")
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(conn.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for index := range payload {
+		payload[index] ^= mask[index%4]
+	}
+	return final, opcode, payload, nil
+}
+
+// WriteMessage sends data as a single message of messageType.
+func (conn *WebSocketConn) WriteMessage(messageType WebSocketMessageType, data []byte) error {
+	return conn.writeFrame(int(messageType), data)
+}
+
+// ReadJSON reads the next message and decodes it as JSON into value.
+func (conn *WebSocketConn) ReadJSON(value any) error {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// WriteJSON sends value encoded as JSON in a text message.
+func (conn *WebSocketConn) WriteJSON(value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(WebSocketText, data)
+}
+
+// Ping sends a ping; the client answers with a pong, which ReadMessage
+// consumes. Pinging periodically keeps proxies from closing idle
+// connections.
+func (conn *WebSocketConn) Ping(data []byte) error {
+	return conn.writeFrame(webSocketPing, data)
+}
+
+// Close sends a close frame with code and reason and closes the
+// connection. Closing an already closed connection does nothing.
+func (conn *WebSocketConn) Close(code int, reason string) error {
+	var err error
+	conn.closeOnce.Do(func() {
+		payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+		payload = append(payload, reason...)
+		if len(payload) > 125 {
+			payload = payload[:125]
+		}
+		_ = conn.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		err = conn.writeFrame(webSocketClose, payload)
+		conn.writeMutex.Lock()
+		conn.closeSent = true
+		conn.writeMutex.Unlock()
+		if closeErr := conn.conn.Close(); err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+// fail closes the connection after a protocol violation and returns the
+// error describing it.
+func (conn *WebSocketConn) fail(code int, reason string) error {
+	conn.Close(code, reason)
+	return &WebSocketCloseError{Code: code, Reason: reason}
+}
+
+// errWebSocketClosed is returned by writes after the connection was closed.
+var errWebSocketClosed = errors.New("routerx: websocket connection closed")
+
+// writeFrame sends a final, unmasked frame.
+func (conn *WebSocketConn) writeFrame(opcode int, payload []byte) error {
+	conn.writeMutex.Lock()
+	defer conn.writeMutex.Unlock()
+	if conn.closeSent {
+		return errWebSocketClosed
+	}
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|byte(opcode))
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	frame = append(frame, payload...)
+	_, err := conn.conn.Write(frame)
+	return err
+}
+
+// webSocketAccept computes the Sec-WebSocket-Accept header for key.
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// sameOrigin reports whether request has no Origin header or one whose host
+// equals the Host header.
+func sameOrigin(request *http.Request) bool {
+	origin := request.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, request.Host)
+}
+
+// headerTokens returns the comma-separated tokens of every value of the
+// header name.
+func headerTokens(header http.Header, name string) []string {
+	var tokens []string
+	for _, value := range header.Values(name) {
+		for token := range strings.SplitSeq(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+// headerContainsToken reports whether the header name lists token, ignoring
+// case.
+func headerContainsToken(header http.Header, name string, token string) bool {
+	return containsFold(headerTokens(header, name), token)
+}
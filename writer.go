@@ -0,0 +1,91 @@
+package routerx
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// bufferedResponseWriter captures the status code, headers, and body written
+// by a handler so that a middleware can inspect or rewrite the response
+// before it reaches the client.
+type bufferedResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: make(http.Header)}
+}
+
+func (writer *bufferedResponseWriter) Header() http.Header {
+	return writer.header
+}
+
+func (writer *bufferedResponseWriter) WriteHeader(statusCode int) {
+	// Informational responses cannot be replayed from the buffer.
+	if writer.statusCode == 0 && !(statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols) {
+		writer.statusCode = statusCode
+	}
+}
+
+func (writer *bufferedResponseWriter) Write(data []byte) (int, error) {
+	writer.WriteHeader(http.StatusOK)
+	return writer.body.Write(data)
+}
+
+// status returns the captured status code, defaulting to 200 like net/http.
+func (writer *bufferedResponseWriter) status() int {
+	if writer.statusCode == 0 {
+		return http.StatusOK
+	}
+	return writer.statusCode
+}
+
+// flushTo copies the captured response to responseWriter, replacing the body
+// with body.
+func (writer *bufferedResponseWriter) flushTo(responseWriter http.ResponseWriter, body []byte) {
+	header := responseWriter.Header()
+	for key, values := range writer.header {
+		header[key] = values
+	}
+	header.Del("Content-Length")
+	responseWriter.WriteHeader(writer.status())
+	_, _ = responseWriter.Write(body)
+}
+
+// statusWriter passes a response through while recording its status code and
+// body size, for middlewares that observe responses without changing them.
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+	written    int64
+}
+
+func (writer *statusWriter) WriteHeader(statusCode int) {
+	if writer.statusCode == 0 && !(statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols) {
+		writer.statusCode = statusCode
+	}
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (writer *statusWriter) Write(data []byte) (int, error) {
+	if writer.statusCode == 0 {
+		writer.statusCode = http.StatusOK
+	}
+	count, err := writer.ResponseWriter.Write(data)
+	writer.written += int64(count)
+	return count, err
+}
+
+// status returns the recorded status code, defaulting to 200 like net/http.
+func (writer *statusWriter) status() int {
+	if writer.statusCode == 0 {
+		return http.StatusOK
+	}
+	return writer.statusCode
+}
+
+func (writer *statusWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}